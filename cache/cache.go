@@ -0,0 +1,363 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+// Trust is the credibility of cached data, RFC 2181 5.4.1, data with
+// lower trust won't replace valid data with higher trust
+type Trust int
+
+const (
+	TrustAdditional Trust = iota
+	TrustAuthority
+	TrustAnswer
+	TrustAuthAuthority
+	TrustAuthAnswer
+)
+
+const (
+	DefaultMaxTTL         = 7 * 24 * time.Hour
+	DefaultMaxNegativeTTL = 3 * time.Hour
+	// RFC 8767 4, ttl of stale answer
+	StaleTTL = 30 * time.Second
+
+	// memory used by entry besides the wire data
+	entryOverhead = 128
+	// nxdomain is shared by all types of the name
+	nxdomainType g53.RRType = 0
+)
+
+type entryKey struct {
+	name  string
+	typ   g53.RRType
+	class g53.RRClass
+}
+
+type entry struct {
+	key    entryKey
+	rrset  *g53.RRset
+	rcode  g53.Rcode
+	soa    *g53.RRset
+	trust  Trust
+	expire time.Time
+	size   int
+}
+
+// Result is a copy of the cached data with ttl decreased by the time it
+// stays in cache, rrset is nil for negative answer
+type Result struct {
+	RRset *g53.RRset
+	Rcode g53.Rcode
+	SOA   *g53.RRset
+	Trust Trust
+	Stale bool
+}
+
+func (r *Result) IsNegative() bool {
+	return r.RRset == nil
+}
+
+// Cache is a lru cache with memory limit, the size of each entry is
+// estimated with its wire length
+type Cache struct {
+	MaxTTL         time.Duration
+	MaxNegativeTTL time.Duration
+	// how long expired entry is kept for serve-stale, 0 means disabled
+	MaxStale time.Duration
+
+	lock    sync.Mutex
+	maxSize int
+	size    int
+	entries map[entryKey]*list.Element
+	lru     *list.List
+	now     func() time.Time
+}
+
+func New(maxSize int) *Cache {
+	return &Cache{
+		MaxTTL:         DefaultMaxTTL,
+		MaxNegativeTTL: DefaultMaxNegativeTTL,
+		maxSize:        maxSize,
+		entries:        make(map[entryKey]*list.Element),
+		lru:            list.New(),
+		now:            time.Now,
+	}
+}
+
+func makeKey(name *g53.Name, typ g53.RRType, class g53.RRClass) entryKey {
+	return entryKey{
+		name:  strings.ToLower(name.String(false)),
+		typ:   typ,
+		class: class,
+	}
+}
+
+func rrsetSize(rrset *g53.RRset) int {
+	if rrset == nil {
+		return 0
+	}
+	buf := util.NewOutputBuffer(512)
+	rrset.ToWire(buf)
+	return int(buf.Len())
+}
+
+func minDuration(d1, d2 time.Duration) time.Duration {
+	if d1 < d2 {
+		return d1
+	}
+	return d2
+}
+
+// Add insert rrset with its ttl, it returns false if rrset isn't cached
+// because ttl is zero or there is valid data with higher trust
+func (c *Cache) Add(rrset *g53.RRset, trust Trust) bool {
+	if rrset.Ttl == 0 || len(rrset.Rdatas) == 0 {
+		return false
+	}
+
+	ttl := minDuration(time.Duration(rrset.Ttl)*time.Second, c.MaxTTL)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := makeKey(rrset.Name, rrset.Type, rrset.Class)
+	if c.add(&entry{
+		key:    key,
+		rrset:  rrset.Clone(),
+		rcode:  g53.R_NOERROR,
+		trust:  trust,
+		expire: c.now().Add(ttl),
+		size:   entryOverhead + rrsetSize(rrset),
+	}) == false {
+		return false
+	}
+
+	// name exists, nxdomain isn't correct any more
+	nxKey := key
+	nxKey.typ = nxdomainType
+	if elem, ok := c.entries[nxKey]; ok && elem.Value.(*entry).trust <= trust {
+		c.remove(elem)
+	}
+	return true
+}
+
+// AddNegative cache nxdomain or nodata(rcode is NOERROR), ttl is the minimum
+// of soa ttl and soa minimum field, RFC 2308 5, response without soa
+// isn't cached
+func (c *Cache) AddNegative(name *g53.Name, typ g53.RRType, class g53.RRClass, rcode g53.Rcode, soa *g53.RRset, trust Trust) bool {
+	if rcode != g53.R_NXDOMAIN && rcode != g53.R_NOERROR {
+		return false
+	}
+	if soa == nil || soa.Type != g53.RR_SOA || len(soa.Rdatas) != 1 {
+		return false
+	}
+
+	ttl := uint32(soa.Ttl)
+	if minimum := soa.Rdatas[0].(*g53.SOA).Minimum; minimum < ttl {
+		ttl = minimum
+	}
+	if ttl == 0 {
+		return false
+	}
+
+	if rcode == g53.R_NXDOMAIN {
+		typ = nxdomainType
+	}
+
+	soa = soa.Clone()
+	soa.Ttl = g53.RRTTL(ttl)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.add(&entry{
+		key:    makeKey(name, typ, class),
+		rcode:  rcode,
+		soa:    soa,
+		trust:  trust,
+		expire: c.now().Add(minDuration(time.Duration(ttl)*time.Second, c.MaxNegativeTTL)),
+		size:   entryOverhead + rrsetSize(soa),
+	})
+}
+
+// AddMessage cache the rrsets in response, the trust of each rrset is
+// decided by its section and the aa flag, if the question isn't answered
+// the negative answer is cached
+func (c *Cache) AddMessage(msg *g53.Message) {
+	aa := msg.Header.GetFlag(g53.FLAG_AA)
+	answerTrust, authTrust := TrustAnswer, TrustAuthority
+	if aa {
+		answerTrust, authTrust = TrustAuthAnswer, TrustAuthAuthority
+	}
+
+	for _, rrset := range msg.Sections[g53.AnswerSection] {
+		c.Add(rrset, answerTrust)
+	}
+
+	var soa *g53.RRset
+	for _, rrset := range msg.Sections[g53.AuthSection] {
+		if rrset.Type == g53.RR_SOA {
+			soa = rrset
+		} else {
+			c.Add(rrset, authTrust)
+		}
+	}
+
+	for _, rrset := range msg.Sections[g53.AdditionalSection] {
+		if rrset.Type != g53.RR_OPT && rrset.Type != g53.RR_TSIG {
+			c.Add(rrset, TrustAdditional)
+		}
+	}
+
+	q := msg.Question
+	if q == nil || soa == nil {
+		return
+	}
+	// RFC 2308 2.1, negative answer is for the last name of cname chain
+	name, answered := followCNAME(msg.Sections[g53.AnswerSection], q.Name, q.Type)
+	if answered == false &&
+		(msg.Header.Rcode == g53.R_NXDOMAIN || msg.Header.Rcode == g53.R_NOERROR) {
+		c.AddNegative(name, q.Type, q.Class, msg.Header.Rcode, soa, authTrust)
+	}
+}
+
+// followCNAME returns the last name of the cname chain start from name in
+// answers, and whether rrset of typ exists for it
+func followCNAME(answers g53.Section, name *g53.Name, typ g53.RRType) (*g53.Name, bool) {
+	// each cname is followed at most once to avoid loop
+	for i := 0; i <= len(answers); i++ {
+		var cname *g53.RRset
+		for _, rrset := range answers {
+			if rrset.Name.Equals(name) == false {
+				continue
+			}
+			if rrset.Type == typ {
+				return name, true
+			} else if rrset.Type == g53.RR_CNAME && len(rrset.Rdatas) > 0 {
+				cname = rrset
+			}
+		}
+		if cname == nil {
+			break
+		}
+		name = cname.Rdatas[0].(*g53.CName).Name
+	}
+	return name, false
+}
+
+func (c *Cache) add(e *entry) bool {
+	if elem, ok := c.entries[e.key]; ok {
+		old := elem.Value.(*entry)
+		if old.trust > e.trust && c.now().Before(old.expire) {
+			return false
+		}
+		c.remove(elem)
+	}
+
+	if e.size > c.maxSize {
+		return false
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += e.size
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	return true
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*entry)
+	delete(c.entries, e.key)
+	c.size -= e.size
+}
+
+// Get return the valid data of name and type, nxdomain of name is returned
+// if any
+func (c *Cache) Get(name *g53.Name, typ g53.RRType, class g53.RRClass) (*Result, bool) {
+	return c.get(name, typ, class, false)
+}
+
+// GetStale is same as Get except expired data within MaxStale is returned
+// with ttl StaleTTL, it should only be used when upstream isn't reachable,
+// RFC 8767
+func (c *Cache) GetStale(name *g53.Name, typ g53.RRType, class g53.RRClass) (*Result, bool) {
+	return c.get(name, typ, class, true)
+}
+
+func (c *Cache) get(name *g53.Name, typ g53.RRType, class g53.RRClass, allowStale bool) (*Result, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := makeKey(name, typ, class)
+	for _, t := range []g53.RRType{typ, nxdomainType} {
+		key.typ = t
+		elem, ok := c.entries[key]
+		if ok == false {
+			continue
+		}
+
+		e := elem.Value.(*entry)
+		now := c.now()
+		if now.Before(e.expire) {
+			c.lru.MoveToFront(elem)
+			return e.result(e.expire.Sub(now), false), true
+		}
+
+		if now.Before(e.expire.Add(c.MaxStale)) == false {
+			c.remove(elem)
+			continue
+		}
+		if allowStale {
+			c.lru.MoveToFront(elem)
+			return e.result(StaleTTL, true), true
+		}
+		// stale data of the type means the name exists, older nxdomain
+		// shouldn't be returned
+		break
+	}
+	return nil, false
+}
+
+func (e *entry) result(remain time.Duration, stale bool) *Result {
+	ttl := g53.RRTTL((remain + time.Second - 1) / time.Second)
+	result := &Result{
+		Rcode: e.rcode,
+		Trust: e.trust,
+		Stale: stale,
+	}
+	if e.rrset != nil {
+		result.RRset = e.rrset.Clone()
+		result.RRset.Ttl = ttl
+	}
+	if e.soa != nil {
+		result.SOA = e.soa.Clone()
+		result.SOA.Ttl = ttl
+	}
+	return result
+}
+
+func (c *Cache) Remove(name *g53.Name, typ g53.RRType, class g53.RRClass) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.entries[makeKey(name, typ, class)]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len return the count of entries including the expired ones not cleaned
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// Size return the estimated memory used by cache
+func (c *Cache) Size() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) forward(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestCache(maxSize int) (*Cache, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	c := New(maxSize)
+	c.now = clock.Now
+	return c, clock
+}
+
+func rrsetFromString(s string) *g53.RRset {
+	rrset, err := g53.RRsetFromString(s)
+	if err != nil {
+		panic("invalid rrset " + s)
+	}
+	return rrset
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, clock := newTestCache(1 << 20)
+	www := rrsetFromString("www.example.com. 300 IN A 192.0.2.1")
+	ut.Assert(t, c.Add(www, TrustAnswer), "add should succeed")
+	ut.Assert(t, c.Add(rrsetFromString("ftp.example.com. 0 IN A 192.0.2.2"), TrustAnswer) == false,
+		"zero ttl shouldn't be cached")
+
+	clock.forward(100 * time.Second)
+	result, ok := c.Get(g53.NameFromStringUnsafe("WWW.example.com."), g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok, "rrset should be cached")
+	ut.Equal(t, result.RRset.Ttl, g53.RRTTL(200))
+	ut.Assert(t, result.RRset.Equals(www), "rrset should be same")
+	ut.Equal(t, www.Ttl, g53.RRTTL(300))
+
+	_, ok = c.Get(www.Name, g53.RR_AAAA, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "aaaa isn't cached")
+
+	clock.forward(200 * time.Second)
+	_, ok = c.Get(www.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "rrset should expire")
+	ut.Equal(t, c.Len(), 0)
+}
+
+func TestCacheNegative(t *testing.T) {
+	c, clock := newTestCache(1 << 20)
+	soa := rrsetFromString("example.com. 3600 IN SOA ns1.example.com. root.example.com. 1 3600 600 86400 300")
+	nx := g53.NameFromStringUnsafe("nx.example.com.")
+	ut.Assert(t, c.AddNegative(nx, g53.RR_A, g53.CLASS_IN, g53.R_NXDOMAIN, soa, TrustAuthAuthority), "add nxdomain failed")
+	ut.Assert(t, c.AddNegative(nx, g53.RR_A, g53.CLASS_IN, g53.R_NXDOMAIN, nil, TrustAuthAuthority) == false,
+		"negative answer without soa shouldn't be cached")
+
+	result, ok := c.Get(nx, g53.RR_MX, g53.CLASS_IN)
+	ut.Assert(t, ok && result.IsNegative(), "nxdomain should cover all types")
+	ut.Equal(t, result.Rcode, g53.R_NXDOMAIN)
+	ut.Equal(t, result.SOA.Ttl, g53.RRTTL(300))
+
+	www := g53.NameFromStringUnsafe("www.example.com.")
+	c.AddNegative(www, g53.RR_AAAA, g53.CLASS_IN, g53.R_NOERROR, soa, TrustAuthAuthority)
+	result, ok = c.Get(www, g53.RR_AAAA, g53.CLASS_IN)
+	ut.Assert(t, ok && result.IsNegative(), "nodata should be cached")
+	ut.Equal(t, result.Rcode, g53.R_NOERROR)
+	_, ok = c.Get(www, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "nodata only cover the type")
+
+	// name is created later
+	c.Add(rrsetFromString("nx.example.com. 300 IN A 192.0.2.1"), TrustAuthAnswer)
+	_, ok = c.Get(nx, g53.RR_MX, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "nxdomain should be removed")
+
+	clock.forward(301 * time.Second)
+	_, ok = c.Get(www, g53.RR_AAAA, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "negative answer should expire with soa minimum")
+}
+
+func TestCacheAddMessage(t *testing.T) {
+	c, _ := newTestCache(1 << 20)
+	msg := g53.MakeQuery(g53.NameFromStringUnsafe("nx.example.com."), g53.RR_A, 512, false).MakeResponse()
+	msg.Header.SetFlag(g53.FLAG_AA, true)
+	msg.Header.Rcode = g53.R_NXDOMAIN
+	msg.AddRRset(g53.AuthSection, rrsetFromString("example.com. 3600 IN SOA ns1.example.com. root.example.com. 1 3600 600 86400 300"))
+	c.AddMessage(msg)
+	result, ok := c.Get(msg.Question.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok, "nxdomain should be cached")
+	ut.Equal(t, result.Trust, TrustAuthAuthority)
+	ut.Equal(t, result.Rcode, g53.R_NXDOMAIN)
+}
+
+func TestCacheAddMessageWithCNAME(t *testing.T) {
+	c, _ := newTestCache(1 << 20)
+	soa := rrsetFromString("example.com. 3600 IN SOA ns1.example.com. root.example.com. 1 3600 600 86400 300")
+	www := g53.NameFromStringUnsafe("www.example.com.")
+	gone := g53.NameFromStringUnsafe("gone.example.com.")
+	msg := g53.MakeQuery(www, g53.RR_A, 512, false).MakeResponse()
+	msg.Header.SetFlag(g53.FLAG_AA, true)
+	msg.Header.Rcode = g53.R_NXDOMAIN
+	msg.AddRRset(g53.AnswerSection, rrsetFromString("www.example.com. 300 IN CNAME gone.example.com."))
+	msg.AddRRset(g53.AuthSection, soa)
+	c.AddMessage(msg)
+
+	_, ok := c.Get(www, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "owner of cname exists")
+	result, ok := c.Get(www, g53.RR_CNAME, g53.CLASS_IN)
+	ut.Assert(t, ok && result.IsNegative() == false, "cname should be cached")
+	result, ok = c.Get(gone, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok && result.IsNegative(), "nxdomain should be cached for cname target")
+	ut.Equal(t, result.Rcode, g53.R_NXDOMAIN)
+
+	// nodata of the target
+	msg = g53.MakeQuery(g53.NameFromStringUnsafe("ftp.example.com."), g53.RR_AAAA, 512, false).MakeResponse()
+	msg.Header.SetFlag(g53.FLAG_AA, true)
+	msg.AddRRset(g53.AnswerSection, rrsetFromString("ftp.example.com. 300 IN CNAME host.example.com."))
+	msg.AddRRset(g53.AuthSection, soa)
+	c.AddMessage(msg)
+	_, ok = c.Get(msg.Question.Name, g53.RR_AAAA, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "cname owner shouldn't be nodata")
+	result, ok = c.Get(g53.NameFromStringUnsafe("host.example.com."), g53.RR_AAAA, g53.CLASS_IN)
+	ut.Assert(t, ok && result.IsNegative(), "nodata should be cached for cname target")
+	ut.Equal(t, result.Rcode, g53.R_NOERROR)
+}
+
+func TestCacheTrust(t *testing.T) {
+	c, clock := newTestCache(1 << 20)
+	auth := rrsetFromString("ns1.example.com. 300 IN A 192.0.2.1")
+	glue := rrsetFromString("ns1.example.com. 3600 IN A 6.6.6.6")
+	ut.Assert(t, c.Add(auth, TrustAuthAnswer), "add should succeed")
+	ut.Assert(t, c.Add(glue, TrustAdditional) == false, "glue shouldn't override authoritative data")
+	result, _ := c.Get(auth.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, result.RRset.Equals(auth), "authoritative data should be kept")
+
+	clock.forward(time.Hour)
+	ut.Assert(t, c.Add(glue, TrustAdditional), "expired data could be replaced by any data")
+	result, _ = c.Get(auth.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, result.RRset.Equals(glue), "glue should be cached")
+	ut.Assert(t, c.Add(auth, TrustAuthAnswer), "authoritative data should override glue")
+}
+
+func TestCacheServeStale(t *testing.T) {
+	c, clock := newTestCache(1 << 20)
+	c.MaxStale = time.Hour
+	www := rrsetFromString("www.example.com. 300 IN A 192.0.2.1")
+	c.Add(www, TrustAnswer)
+
+	clock.forward(10 * time.Minute)
+	_, ok := c.Get(www.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "expired data shouldn't be returned by Get")
+	result, ok := c.GetStale(www.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok && result.Stale, "stale data should be returned")
+	ut.Equal(t, result.RRset.Ttl, g53.RRTTL(StaleTTL/time.Second))
+
+	clock.forward(time.Hour)
+	_, ok = c.GetStale(www.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "data is too stale")
+	ut.Equal(t, c.Len(), 0)
+}
+
+func TestCacheStaleShadowNXDomain(t *testing.T) {
+	c, clock := newTestCache(1 << 20)
+	c.MaxStale = time.Hour
+	soa := rrsetFromString("example.com. 3600 IN SOA ns1.example.com. root.example.com. 1 3600 600 86400 300")
+	www := g53.NameFromStringUnsafe("www.example.com.")
+	c.AddNegative(www, g53.RR_A, g53.CLASS_IN, g53.R_NXDOMAIN, soa, TrustAuthAuthority)
+	c.Add(rrsetFromString("www.example.com. 60 IN A 192.0.2.1"), TrustAnswer)
+
+	clock.forward(2 * time.Minute)
+	_, ok := c.Get(www, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "nxdomain shouldn't be returned for name with stale data")
+	result, ok := c.GetStale(www, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok && result.Stale && result.IsNegative() == false, "stale data should be returned")
+	result, ok = c.Get(www, g53.RR_MX, g53.CLASS_IN)
+	ut.Assert(t, ok && result.IsNegative(), "nxdomain still covers other types")
+}
+
+func TestCacheLRU(t *testing.T) {
+	a := rrsetFromString("a.example.com. 300 IN A 192.0.2.1")
+	b := rrsetFromString("b.example.com. 300 IN A 192.0.2.2")
+	d := rrsetFromString("d.example.com. 300 IN A 192.0.2.3")
+	entrySize := entryOverhead + rrsetSize(a)
+	c, _ := newTestCache(entrySize * 2)
+
+	c.Add(a, TrustAnswer)
+	c.Add(b, TrustAnswer)
+	c.Get(a.Name, g53.RR_A, g53.CLASS_IN)
+	c.Add(d, TrustAnswer)
+	ut.Equal(t, c.Len(), 2)
+	ut.Equal(t, c.Size(), entrySize*2)
+	_, ok := c.Get(b.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok == false, "least recently used entry should be evicted")
+	_, ok = c.Get(a.Name, g53.RR_A, g53.CLASS_IN)
+	ut.Assert(t, ok, "recently used entry should be kept")
+}
@@ -0,0 +1,347 @@
+package dnstap
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+// content type of frame streams which carry dnstap
+const ContentType = "protobuf:dnstap.Dnstap"
+
+var (
+	ErrUnknownType = errors.New("unknown dnstap type")
+	ErrNoMessage   = errors.New("dnstap has no message")
+)
+
+type MessageType uint8
+
+const (
+	AuthQuery         MessageType = 1
+	AuthResponse      MessageType = 2
+	ResolverQuery     MessageType = 3
+	ResolverResponse  MessageType = 4
+	ClientQuery       MessageType = 5
+	ClientResponse    MessageType = 6
+	ForwarderQuery    MessageType = 7
+	ForwarderResponse MessageType = 8
+	StubQuery         MessageType = 9
+	StubResponse      MessageType = 10
+	ToolQuery         MessageType = 11
+	ToolResponse      MessageType = 12
+	UpdateQuery       MessageType = 13
+	UpdateResponse    MessageType = 14
+)
+
+var messageTypeNames = []string{
+	"",
+	"AUTH_QUERY",
+	"AUTH_RESPONSE",
+	"RESOLVER_QUERY",
+	"RESOLVER_RESPONSE",
+	"CLIENT_QUERY",
+	"CLIENT_RESPONSE",
+	"FORWARDER_QUERY",
+	"FORWARDER_RESPONSE",
+	"STUB_QUERY",
+	"STUB_RESPONSE",
+	"TOOL_QUERY",
+	"TOOL_RESPONSE",
+	"UPDATE_QUERY",
+	"UPDATE_RESPONSE",
+}
+
+func (t MessageType) String() string {
+	if int(t) < len(messageTypeNames) && t != 0 {
+		return messageTypeNames[t]
+	}
+	return "UNKNOWN"
+}
+
+// query types are odd, response types are even
+func (t MessageType) IsQuery() bool {
+	return t%2 == 1
+}
+
+type SocketFamily uint8
+
+const (
+	FamilyINET  SocketFamily = 1
+	FamilyINET6 SocketFamily = 2
+)
+
+type SocketProtocol uint8
+
+const (
+	ProtocolUDP SocketProtocol = 1
+	ProtocolTCP SocketProtocol = 2
+	ProtocolDOT SocketProtocol = 3
+	ProtocolDOH SocketProtocol = 4
+)
+
+// Message is the dnstap message, query address is the initiator of the
+// query, for client messages it's the client, for resolver messages it's
+// the resolver itself
+type Message struct {
+	Type            MessageType
+	SocketFamily    SocketFamily
+	SocketProtocol  SocketProtocol
+	QueryAddress    net.IP
+	ResponseAddress net.IP
+	QueryPort       uint16
+	ResponsePort    uint16
+	QueryTime       time.Time
+	QueryMessage    []byte
+	// zone of resolver query, which is the bailiwick of the server
+	QueryZone       *g53.Name
+	ResponseTime    time.Time
+	ResponseMessage []byte
+}
+
+// Dnstap is the top level dnstap frame, only message type is supported
+type Dnstap struct {
+	Identity []byte
+	Version  []byte
+	Extra    []byte
+	Message  *Message
+}
+
+const dnstapTypeMessage = 1
+
+func renderMessage(msg *g53.Message) []byte {
+	render := g53.NewMsgRender()
+	msg.Rend(render)
+	data := render.Data()
+	return append(make([]byte, 0, len(data)), data...)
+}
+
+func (m *Message) setAddresses(queryAddr, responseAddr net.Addr) {
+	m.QueryAddress, m.QueryPort, m.SocketProtocol = splitAddr(queryAddr)
+	m.ResponseAddress, m.ResponsePort, _ = splitAddr(responseAddr)
+	if m.SocketProtocol == 0 {
+		_, _, m.SocketProtocol = splitAddr(responseAddr)
+	}
+
+	ip := m.QueryAddress
+	if ip == nil {
+		ip = m.ResponseAddress
+	}
+	if ip != nil {
+		if ip.To4() != nil {
+			m.SocketFamily = FamilyINET
+		} else {
+			m.SocketFamily = FamilyINET6
+		}
+	}
+}
+
+func splitAddr(addr net.Addr) (net.IP, uint16, SocketProtocol) {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP, uint16(addr.Port), ProtocolUDP
+	case *net.TCPAddr:
+		return addr.IP, uint16(addr.Port), ProtocolTCP
+	default:
+		return nil, 0, 0
+	}
+}
+
+// NewQueryMessage creates message with the wire format of query, socket
+// protocol is decided by the type of address
+func NewQueryMessage(typ MessageType, query *g53.Message, queryAddr, responseAddr net.Addr, queryTime time.Time) *Message {
+	m := &Message{
+		Type:         typ,
+		QueryTime:    queryTime,
+		QueryMessage: renderMessage(query),
+	}
+	m.setAddresses(queryAddr, responseAddr)
+	return m
+}
+
+// NewResponseMessage creates message with the wire format of response,
+// queryTime is optional
+func NewResponseMessage(typ MessageType, response *g53.Message, queryAddr, responseAddr net.Addr, queryTime, responseTime time.Time) *Message {
+	m := &Message{
+		Type:            typ,
+		QueryTime:       queryTime,
+		ResponseTime:    responseTime,
+		ResponseMessage: renderMessage(response),
+	}
+	m.setAddresses(queryAddr, responseAddr)
+	return m
+}
+
+func parseMessage(data []byte) (*g53.Message, error) {
+	if len(data) == 0 {
+		return nil, ErrNoMessage
+	}
+	return g53.MessageFromWire(util.NewInputBuffer(data))
+}
+
+// Query parses the query message
+func (m *Message) Query() (*g53.Message, error) {
+	return parseMessage(m.QueryMessage)
+}
+
+// Response parses the response message
+func (m *Message) Response() (*g53.Message, error) {
+	return parseMessage(m.ResponseMessage)
+}
+
+func (m *Message) marshal() []byte {
+	var e protoEncoder
+	e.varint(1, uint64(m.Type))
+	if m.SocketFamily != 0 {
+		e.varint(2, uint64(m.SocketFamily))
+	}
+	if m.SocketProtocol != 0 {
+		e.varint(3, uint64(m.SocketProtocol))
+	}
+	if ip := compactIP(m.QueryAddress); ip != nil {
+		e.bytes(4, ip)
+	}
+	if ip := compactIP(m.ResponseAddress); ip != nil {
+		e.bytes(5, ip)
+	}
+	if m.QueryAddress != nil {
+		e.varint(6, uint64(m.QueryPort))
+	}
+	if m.ResponseAddress != nil {
+		e.varint(7, uint64(m.ResponsePort))
+	}
+	if m.QueryTime.IsZero() == false {
+		e.varint(8, uint64(m.QueryTime.Unix()))
+		e.fixed32(9, uint32(m.QueryTime.Nanosecond()))
+	}
+	if m.QueryMessage != nil {
+		e.bytes(10, m.QueryMessage)
+	}
+	if m.QueryZone != nil {
+		buf := util.NewOutputBuffer(m.QueryZone.Length())
+		m.QueryZone.ToWire(buf)
+		e.bytes(11, buf.Data())
+	}
+	if m.ResponseTime.IsZero() == false {
+		e.varint(12, uint64(m.ResponseTime.Unix()))
+		e.fixed32(13, uint32(m.ResponseTime.Nanosecond()))
+	}
+	if m.ResponseMessage != nil {
+		e.bytes(14, m.ResponseMessage)
+	}
+	return e.buf
+}
+
+func compactIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip.To16()
+}
+
+func unmarshalMessage(data []byte) (*Message, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Message{}
+	var querySec, queryNsec, responseSec, responseNsec uint64
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.Type = MessageType(f.varint)
+		case 2:
+			m.SocketFamily = SocketFamily(f.varint)
+		case 3:
+			m.SocketProtocol = SocketProtocol(f.varint)
+		case 4:
+			m.QueryAddress = net.IP(f.data)
+		case 5:
+			m.ResponseAddress = net.IP(f.data)
+		case 6:
+			m.QueryPort = uint16(f.varint)
+		case 7:
+			m.ResponsePort = uint16(f.varint)
+		case 8:
+			querySec = f.varint
+		case 9:
+			queryNsec = f.varint
+		case 10:
+			m.QueryMessage = f.data
+		case 11:
+			m.QueryZone, err = g53.NameFromWire(util.NewInputBuffer(f.data), false)
+			if err != nil {
+				return nil, err
+			}
+		case 12:
+			responseSec = f.varint
+		case 13:
+			responseNsec = f.varint
+		case 14:
+			m.ResponseMessage = f.data
+		}
+	}
+
+	if m.Type == 0 {
+		return nil, ErrUnknownType
+	}
+	if querySec != 0 || queryNsec != 0 {
+		m.QueryTime = time.Unix(int64(querySec), int64(queryNsec))
+	}
+	if responseSec != 0 || responseNsec != 0 {
+		m.ResponseTime = time.Unix(int64(responseSec), int64(responseNsec))
+	}
+	return m, nil
+}
+
+func (d *Dnstap) Marshal() []byte {
+	var e protoEncoder
+	if d.Identity != nil {
+		e.bytes(1, d.Identity)
+	}
+	if d.Version != nil {
+		e.bytes(2, d.Version)
+	}
+	if d.Extra != nil {
+		e.bytes(3, d.Extra)
+	}
+	if d.Message != nil {
+		e.bytes(14, d.Message.marshal())
+	}
+	e.varint(15, dnstapTypeMessage)
+	return e.buf
+}
+
+func Unmarshal(data []byte) (*Dnstap, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dnstap{}
+	var typ uint64
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			d.Identity = f.data
+		case 2:
+			d.Version = f.data
+		case 3:
+			d.Extra = f.data
+		case 14:
+			if d.Message, err = unmarshalMessage(f.data); err != nil {
+				return nil, err
+			}
+		case 15:
+			typ = f.varint
+		}
+	}
+
+	if typ != dnstapTypeMessage {
+		return nil, ErrUnknownType
+	}
+	return d, nil
+}
@@ -0,0 +1,274 @@
+package dnstap
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+func makeExchange() (*g53.Message, *g53.Message) {
+	query := g53.MakeQuery(g53.NameFromStringUnsafe("www.example.com."), g53.RR_A, 1232, false)
+	response := query.MakeResponse()
+	response.Edns = query.Edns
+	rrset, _ := g53.RRsetFromString("www.example.com. 300 IN A 192.0.2.1")
+	response.AddRRset(g53.AnswerSection, rrset)
+	response.RecalculateSectionRRCount()
+	return query, response
+}
+
+func TestMarshal(t *testing.T) {
+	d := &Dnstap{Message: &Message{Type: ClientQuery}}
+	ut.Equal(t, d.Marshal(), []byte{0x72, 0x02, 0x08, 0x05, 0x78, 0x01})
+
+	query, response := makeExchange()
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 53000}
+	server := &net.UDPAddr{IP: net.ParseIP("192.0.2.53"), Port: 53}
+	queryTime := time.Unix(1700000000, 123456789)
+	responseTime := queryTime.Add(time.Millisecond)
+
+	d = &Dnstap{
+		Identity: []byte("ns1"),
+		Version:  []byte("g53"),
+		Message:  NewResponseMessage(ClientResponse, response, client, server, queryTime, responseTime),
+	}
+	d2, err := Unmarshal(d.Marshal())
+	ut.Equal(t, err, nil)
+	ut.Equal(t, string(d2.Identity), "ns1")
+	ut.Equal(t, string(d2.Version), "g53")
+	m := d2.Message
+	ut.Equal(t, m.Type, ClientResponse)
+	ut.Equal(t, m.SocketFamily, FamilyINET)
+	ut.Equal(t, m.SocketProtocol, ProtocolUDP)
+	ut.Assert(t, m.QueryAddress.Equal(client.IP), "query address should be client")
+	ut.Assert(t, m.ResponseAddress.Equal(server.IP), "response address should be server")
+	ut.Equal(t, m.QueryPort, uint16(53000))
+	ut.Equal(t, m.ResponsePort, uint16(53))
+	ut.Assert(t, m.QueryTime.Equal(queryTime), "query time isn't correct")
+	ut.Assert(t, m.ResponseTime.Equal(responseTime), "response time isn't correct")
+
+	_, err = m.Query()
+	ut.Equal(t, err, ErrNoMessage)
+	parsed, err := m.Response()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, parsed.String(), response.String())
+
+	zone := g53.NameFromStringUnsafe("example.com.")
+	resolverQuery := NewQueryMessage(ResolverQuery, query, &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 40000}, &net.TCPAddr{IP: net.ParseIP("2001:db8::53"), Port: 53}, queryTime)
+	resolverQuery.QueryZone = zone
+	d2, err = Unmarshal((&Dnstap{Message: resolverQuery}).Marshal())
+	ut.Equal(t, err, nil)
+	ut.Equal(t, d2.Message.SocketFamily, FamilyINET6)
+	ut.Equal(t, d2.Message.SocketProtocol, ProtocolTCP)
+	ut.Equal(t, d2.Message.QueryZone.String(false), "example.com.")
+	ut.Equal(t, d2.Message.Type.IsQuery(), true)
+
+	_, err = Unmarshal([]byte{0x72, 0x10, 0x08})
+	ut.Equal(t, err, ErrInvalidProtobuf)
+	_, err = Unmarshal([]byte{0x78, 0x02})
+	ut.Equal(t, err, ErrUnknownType)
+}
+
+func TestFileWriterAndReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dnstap.fstrm")
+	w, err := NewFileWriter(path)
+	ut.Equal(t, err, nil)
+
+	query, response := makeExchange()
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 53000}
+	server := &net.UDPAddr{IP: net.ParseIP("192.0.2.53"), Port: 53}
+	now := time.Now()
+	logger := NewLogger(w, "ns1", "")
+	ut.Equal(t, logger.ClientQuery(query, client, server, now), nil)
+	ut.Equal(t, logger.ClientResponse(response, client, server, now, now), nil)
+	ut.Equal(t, logger.Close(), nil)
+
+	ds, err := ReadFile(path)
+	ut.Equal(t, err, nil)
+	ut.Equal(t, len(ds), 2)
+	ut.Equal(t, ds[0].Message.Type, ClientQuery)
+	ut.Assert(t, ds[0].Version == nil, "empty version shouldn't be set")
+	parsed, err := ds[0].Message.Query()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, parsed.Question.String(), query.Question.String())
+	ut.Equal(t, ds[1].Message.Type, ClientResponse)
+
+	//unfinished file is read until the last complete frame
+	data, _ := os.ReadFile(path)
+	ut.Equal(t, os.WriteFile(path, data[:len(data)-8], 0644), nil)
+	ds, err = ReadFile(path)
+	ut.Equal(t, err, nil)
+	ut.Equal(t, len(ds), 2)
+
+	var buf bytes.Buffer
+	writeControl(&buf, &controlFrame{typ: controlStart, contentTypes: []string{"protobuf:other"}})
+	_, err = NewReader(&buf)
+	ut.Equal(t, err, ErrContentTypeMismatch)
+}
+
+func TestUnixWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "dnstap")
+	ut.Equal(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dnstap.sock")
+	ln, err := net.Listen("unix", path)
+	ut.Equal(t, err, nil)
+	defer ln.Close()
+
+	received := make(chan []*Dnstap, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		var ds []*Dnstap
+		defer func() { received <- ds }()
+		ready, err := readControl(conn, controlReady)
+		if err != nil || ready.matchContentType(ContentType) == false {
+			return
+		}
+		writeControl(conn, &controlFrame{typ: controlAccept, contentTypes: []string{ContentType}})
+		r, err := NewReader(conn)
+		if err != nil {
+			return
+		}
+		for {
+			d, err := r.Read()
+			if err == io.EOF {
+				writeControl(conn, &controlFrame{typ: controlFinish})
+				return
+			} else if err != nil {
+				return
+			}
+			ds = append(ds, d)
+		}
+	}()
+
+	w, err := NewUnixWriter(path, time.Second)
+	ut.Equal(t, err, nil)
+	query, response := makeExchange()
+	local := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 40000}
+	server := &net.UDPAddr{IP: net.ParseIP("198.51.100.53"), Port: 53}
+	zone := g53.NameFromStringUnsafe("example.com.")
+	logger := NewLogger(w, "resolver", "1.0")
+	now := time.Now()
+	ut.Equal(t, logger.ResolverQuery(query, local, server, zone, now), nil)
+	ut.Equal(t, logger.ResolverResponse(response, local, server, zone, now, now), nil)
+	ut.Equal(t, logger.Close(), nil)
+
+	ds := <-received
+	ut.Equal(t, len(ds), 2)
+	ut.Equal(t, ds[0].Message.Type, ResolverQuery)
+	ut.Equal(t, ds[1].Message.Type, ResolverResponse)
+	ut.Equal(t, string(ds[1].Version), "1.0")
+	ut.Equal(t, ds[1].Message.QueryZone.String(false), "example.com.")
+}
+
+// acceptWriter accepts connection from UnixWriter and replies to ready
+// frame, start frame is left for caller
+func acceptWriter(t *testing.T) (string, chan net.Conn, func()) {
+	dir, err := os.MkdirTemp("", "dnstap")
+	ut.Equal(t, err, nil)
+	path := filepath.Join(dir, "dnstap.sock")
+	ln, err := net.Listen("unix", path)
+	ut.Equal(t, err, nil)
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			accepted <- nil
+			return
+		}
+		readControl(conn, controlReady)
+		writeControl(conn, &controlFrame{typ: controlAccept, contentTypes: []string{ContentType}})
+		accepted <- conn
+	}()
+	return path, accepted, func() {
+		ln.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestUnixWriterFlushInterval(t *testing.T) {
+	path, accepted, clean := acceptWriter(t)
+	defer clean()
+
+	w, err := NewUnixWriter(path, time.Second)
+	ut.Equal(t, err, nil)
+	defer w.Close()
+	conn := <-accepted
+	defer conn.Close()
+
+	query, _ := makeExchange()
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 40000}
+	server := &net.UDPAddr{IP: net.ParseIP("198.51.100.53"), Port: 53}
+	ut.Equal(t, NewLogger(w, "", "").ClientQuery(query, client, server, time.Now()), nil)
+
+	//frame is sent without Flush
+	conn.SetReadDeadline(time.Now().Add(3 * unixFlushInterval))
+	r, err := NewReader(conn)
+	ut.Equal(t, err, nil)
+	d, err := r.Read()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, d.Message.Type, ClientQuery)
+}
+
+// it only guards against blocking forever, it's much longer than the
+// write timeout so that slow test environment doesn't fail the test
+const stalledReaderGuard = 30 * time.Second
+
+func TestUnixWriterStalledReader(t *testing.T) {
+	path, accepted, clean := acceptWriter(t)
+	defer clean()
+
+	w, err := NewUnixWriter(path, 100*time.Millisecond)
+	ut.Equal(t, err, nil)
+	conn := <-accepted
+	defer conn.Close()
+
+	//reader never reads, writes shouldn't block
+	query, _ := makeExchange()
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 40000}
+	server := &net.UDPAddr{IP: net.ParseIP("198.51.100.53"), Port: 53}
+	logger := NewLogger(w, "", "")
+	written := make(chan error)
+	go func() {
+		for i := 0; i < 2*unixQueueSize; i++ {
+			if err := logger.ClientQuery(query, client, server, time.Now()); err != nil {
+				written <- err
+				return
+			}
+		}
+		written <- nil
+	}()
+	select {
+	case err := <-written:
+		ut.Equal(t, err, nil)
+	case <-time.After(stalledReaderGuard):
+		t.Fatal("write shouldn't block on stalled reader")
+	}
+	ut.Assert(t, w.Dropped() > 0, "frames beyond the queue should be dropped")
+
+	closed := make(chan error)
+	go func() {
+		closed <- w.Close()
+	}()
+	select {
+	case err := <-closed:
+		ut.Assert(t, err != nil, "close should time out")
+	case <-time.After(stalledReaderGuard):
+		t.Fatal("close shouldn't block on stalled reader")
+	}
+	ut.Equal(t, logger.ClientQuery(query, client, server, time.Now()), ErrWriterClosed)
+	ut.Equal(t, w.Flush(), ErrWriterClosed)
+}
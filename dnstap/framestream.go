@@ -0,0 +1,142 @@
+package dnstap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// frame streams protocol, each data frame is prefixed with its length in
+// 4 bytes, control frame starts with a zero length which is followed by
+// the control frame length, type and fields
+
+type controlType uint32
+
+const (
+	controlAccept controlType = 1
+	controlStart  controlType = 2
+	controlStop   controlType = 3
+	controlReady  controlType = 4
+	controlFinish controlType = 5
+)
+
+const (
+	controlFieldContentType = 1
+	maxControlFrameLen      = 512
+	// dnstap frame contains two dns messages at most
+	maxDataFrameLen = 2 * 65535 * 2
+)
+
+var (
+	ErrInvalidControlFrame = errors.New("invalid frame streams control frame")
+	ErrUnexpectedControl   = errors.New("unexpected frame streams control frame")
+	ErrContentTypeMismatch = errors.New("frame streams content type mismatch")
+	ErrFrameTooLong        = errors.New("frame is too long")
+)
+
+type controlFrame struct {
+	typ          controlType
+	contentTypes []string
+}
+
+func writeControl(w io.Writer, cf *controlFrame) error {
+	length := 4
+	for _, ct := range cf.contentTypes {
+		length += 8 + len(ct)
+	}
+
+	buf := make([]byte, 0, 8+length)
+	buf = binary.BigEndian.AppendUint32(buf, 0)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(length))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(cf.typ))
+	for _, ct := range cf.contentTypes {
+		buf = binary.BigEndian.AppendUint32(buf, controlFieldContentType)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(ct)))
+		buf = append(buf, ct...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func writeData(w io.Writer, data []byte) error {
+	buf := make([]byte, 0, 4+len(data))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	buf = append(buf, data...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame returns data frame or control frame, only one of them is
+// not nil
+func readFrame(r io.Reader) ([]byte, *controlFrame, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length != 0 {
+		if length > maxDataFrameLen {
+			return nil, nil, ErrFrameTooLong
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, nil, err
+		}
+		return data, nil, nil
+	}
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, nil, err
+	}
+	length = binary.BigEndian.Uint32(header[:])
+	if length < 4 || length > maxControlFrameLen {
+		return nil, nil, ErrInvalidControlFrame
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, nil, err
+	}
+
+	cf := &controlFrame{typ: controlType(binary.BigEndian.Uint32(data))}
+	data = data[4:]
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, nil, ErrInvalidControlFrame
+		}
+		fieldType, fieldLen := binary.BigEndian.Uint32(data), binary.BigEndian.Uint32(data[4:])
+		data = data[8:]
+		if uint32(len(data)) < fieldLen {
+			return nil, nil, ErrInvalidControlFrame
+		}
+		if fieldType == controlFieldContentType {
+			cf.contentTypes = append(cf.contentTypes, string(data[:fieldLen]))
+		}
+		data = data[fieldLen:]
+	}
+	return nil, cf, nil
+}
+
+func readControl(r io.Reader, typ controlType) (*controlFrame, error) {
+	_, cf, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	if cf == nil || cf.typ != typ {
+		return nil, ErrUnexpectedControl
+	}
+	return cf, nil
+}
+
+// content type of start frame is optional, if it's set, it should match
+func (cf *controlFrame) matchContentType(contentType string) bool {
+	if len(cf.contentTypes) == 0 {
+		return cf.typ == controlStart
+	}
+	for _, ct := range cf.contentTypes {
+		if ct == contentType {
+			return true
+		}
+	}
+	return false
+}
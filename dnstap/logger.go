@@ -0,0 +1,62 @@
+package dnstap
+
+import (
+	"net"
+	"time"
+
+	"github.com/zdnscloud/g53"
+)
+
+// Logger creates dnstap for messages exchanged by server and writes them
+// to writer
+type Logger struct {
+	Identity []byte
+	Version  []byte
+	w        Writer
+}
+
+func NewLogger(w Writer, identity, version string) *Logger {
+	l := &Logger{w: w}
+	if identity != "" {
+		l.Identity = []byte(identity)
+	}
+	if version != "" {
+		l.Version = []byte(version)
+	}
+	return l
+}
+
+func (l *Logger) Log(m *Message) error {
+	return l.w.Write(&Dnstap{
+		Identity: l.Identity,
+		Version:  l.Version,
+		Message:  m,
+	})
+}
+
+// ClientQuery logs query received from client on server address
+func (l *Logger) ClientQuery(query *g53.Message, client, server net.Addr, queryTime time.Time) error {
+	return l.Log(NewQueryMessage(ClientQuery, query, client, server, queryTime))
+}
+
+func (l *Logger) ClientResponse(response *g53.Message, client, server net.Addr, queryTime, responseTime time.Time) error {
+	return l.Log(NewResponseMessage(ClientResponse, response, client, server, queryTime, responseTime))
+}
+
+// ResolverQuery logs query sent from local address to authoritative
+// server, zone is the bailiwick of the server which could be nil
+func (l *Logger) ResolverQuery(query *g53.Message, local, server net.Addr, zone *g53.Name, queryTime time.Time) error {
+	m := NewQueryMessage(ResolverQuery, query, local, server, queryTime)
+	m.QueryZone = zone
+	return l.Log(m)
+}
+
+func (l *Logger) ResolverResponse(response *g53.Message, local, server net.Addr, zone *g53.Name, queryTime, responseTime time.Time) error {
+	m := NewResponseMessage(ResolverResponse, response, local, server, queryTime, responseTime)
+	m.QueryZone = zone
+	return l.Log(m)
+}
+
+func (l *Logger) Close() error {
+	return l.w.Close()
+}
@@ -0,0 +1,92 @@
+package dnstap
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// the dnstap schema is small and stable, so messages are encoded with
+// the protobuf wire format directly instead of generated code
+
+var ErrInvalidProtobuf = errors.New("invalid protobuf data")
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type protoEncoder struct {
+	buf []byte
+}
+
+func (e *protoEncoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field<<3|wireType))
+}
+
+func (e *protoEncoder) varint(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *protoEncoder) fixed32(field int, v uint32) {
+	e.tag(field, wireFixed32)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *protoEncoder) bytes(field int, data []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(data)))
+	e.buf = append(e.buf, data...)
+}
+
+type protoField struct {
+	num      int
+	wireType int
+	varint   uint64
+	data     []byte
+}
+
+// protoFields splits data into fields, value of fixed field is saved in
+// varint
+func protoFields(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, ErrInvalidProtobuf
+		}
+		data = data[n:]
+
+		f := protoField{num: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case wireVarint:
+			f.varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, ErrInvalidProtobuf
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, ErrInvalidProtobuf
+			}
+			f.varint, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, ErrInvalidProtobuf
+			}
+			f.varint, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, ErrInvalidProtobuf
+			}
+			f.data, data = data[n:n+int(length)], data[n+int(length):]
+		default:
+			return nil, ErrInvalidProtobuf
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
@@ -0,0 +1,72 @@
+package dnstap
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// Reader reads dnstap from unidirectional frame streams
+type Reader struct {
+	r       io.Reader
+	stopped bool
+}
+
+// NewReader reads the start frame and checks its content type
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	start, err := readControl(br, controlStart)
+	if err != nil {
+		return nil, err
+	}
+	if start.matchContentType(ContentType) == false {
+		return nil, ErrContentTypeMismatch
+	}
+	return &Reader{r: br}, nil
+}
+
+// Read returns next dnstap, io.EOF is returned after stop frame
+func (r *Reader) Read() (*Dnstap, error) {
+	if r.stopped {
+		return nil, io.EOF
+	}
+
+	data, cf, err := readFrame(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if cf != nil {
+		if cf.typ != controlStop {
+			return nil, ErrUnexpectedControl
+		}
+		r.stopped = true
+		return nil, io.EOF
+	}
+	return Unmarshal(data)
+}
+
+// ReadFile returns all the dnstap in file, a file without stop frame
+// is accepted since the writer may not be closed properly
+func ReadFile(path string) ([]*Dnstap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds []*Dnstap
+	for {
+		d, err := r.Read()
+		if err == io.EOF {
+			return ds, nil
+		} else if err != nil {
+			return ds, err
+		}
+		ds = append(ds, d)
+	}
+}
@@ -0,0 +1,265 @@
+package dnstap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrWriterClosed = errors.New("dnstap writer is closed")
+
+type Writer interface {
+	Write(d *Dnstap) error
+	Close() error
+}
+
+// StreamWriter writes unidirectional frame streams, which is used for
+// file
+type StreamWriter struct {
+	lock   sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+}
+
+// NewStreamWriter writes start frame to w, if w is a io.Closer, it's
+// closed with the writer
+func NewStreamWriter(w io.Writer) (*StreamWriter, error) {
+	sw := &StreamWriter{
+		w: bufio.NewWriter(w),
+	}
+	if closer, ok := w.(io.Closer); ok {
+		sw.closer = closer
+	}
+
+	if err := writeControl(sw.w, &controlFrame{typ: controlStart, contentTypes: []string{ContentType}}); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func NewFileWriter(path string) (*StreamWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewStreamWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (sw *StreamWriter) Write(d *Dnstap) error {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	return writeData(sw.w, d.Marshal())
+}
+
+func (sw *StreamWriter) Flush() error {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	return sw.w.Flush()
+}
+
+// Close writes stop frame and flushes the buffered frames
+func (sw *StreamWriter) Close() error {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	err := writeControl(sw.w, &controlFrame{typ: controlStop})
+	if err == nil {
+		err = sw.w.Flush()
+	}
+	if sw.closer != nil {
+		if closeErr := sw.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// frames beyond the queue are dropped instead of blocking the caller,
+// buffered frames are flushed in the interval when traffic is low
+const (
+	unixQueueSize     = 10000
+	unixFlushInterval = time.Second
+)
+
+// UnixWriter writes bidirectional frame streams to unix socket, the
+// content type is negotiated with ready and accept frames before start.
+// Frames are queued and sent by one goroutine, so a stalled reader never
+// blocks the caller of Write
+type UnixWriter struct {
+	conn    net.Conn
+	w       *bufio.Writer
+	timeout time.Duration
+
+	queue     chan []byte
+	flushes   chan chan error
+	stop      chan struct{}
+	done      chan error
+	dropped   uint64
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewUnixWriter connects to the reader, timeout is used for handshake
+// and each write to the socket
+func NewUnixWriter(path string, timeout time.Duration) (*UnixWriter, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	uw := &UnixWriter{
+		conn:    conn,
+		w:       bufio.NewWriter(conn),
+		timeout: timeout,
+		queue:   make(chan []byte, unixQueueSize),
+		flushes: make(chan chan error),
+		stop:    make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+	if err := uw.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go uw.run()
+	return uw, nil
+}
+
+func (uw *UnixWriter) handshake() error {
+	uw.conn.SetDeadline(time.Now().Add(uw.timeout))
+	defer uw.conn.SetDeadline(time.Time{})
+
+	if err := writeControl(uw.conn, &controlFrame{typ: controlReady, contentTypes: []string{ContentType}}); err != nil {
+		return err
+	}
+	accept, err := readControl(uw.conn, controlAccept)
+	if err != nil {
+		return err
+	}
+	if accept.matchContentType(ContentType) == false {
+		return ErrContentTypeMismatch
+	}
+	return writeControl(uw.conn, &controlFrame{typ: controlStart, contentTypes: []string{ContentType}})
+}
+
+// Write queues the frame, it's dropped if the queue is full
+func (uw *UnixWriter) Write(d *Dnstap) error {
+	select {
+	case <-uw.stop:
+		return ErrWriterClosed
+	default:
+	}
+
+	select {
+	case uw.queue <- d.Marshal():
+	default:
+		atomic.AddUint64(&uw.dropped, 1)
+	}
+	return nil
+}
+
+// Dropped returns the count of frames dropped since the queue is full or
+// the connection is broken
+func (uw *UnixWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&uw.dropped)
+}
+
+// Flush waits until the frames queued before it are sent
+func (uw *UnixWriter) Flush() error {
+	result := make(chan error, 1)
+	select {
+	case uw.flushes <- result:
+		return <-result
+	case <-uw.stop:
+		return ErrWriterClosed
+	}
+}
+
+// Close sends the queued frames and stop frame, then waits for the
+// finish frame from reader
+func (uw *UnixWriter) Close() error {
+	uw.closeOnce.Do(func() {
+		close(uw.stop)
+		uw.closeErr = <-uw.done
+	})
+	return uw.closeErr
+}
+
+func (uw *UnixWriter) run() {
+	ticker := time.NewTicker(unixFlushInterval)
+	defer ticker.Stop()
+
+	var err error
+	for {
+		select {
+		case data := <-uw.queue:
+			err = uw.write(data, err)
+		case <-ticker.C:
+			if err == nil && uw.w.Buffered() > 0 {
+				err = uw.flush()
+			}
+		case result := <-uw.flushes:
+			if err = uw.drain(err); err == nil {
+				err = uw.flush()
+			}
+			result <- err
+		case <-uw.stop:
+			uw.done <- uw.finish(uw.drain(err))
+			return
+		}
+	}
+}
+
+// once write fails, the stream may be broken in the middle of a frame,
+// so all the frames after it are dropped
+func (uw *UnixWriter) write(data []byte, err error) error {
+	if err != nil {
+		atomic.AddUint64(&uw.dropped, 1)
+		return err
+	}
+	uw.conn.SetWriteDeadline(time.Now().Add(uw.timeout))
+	return writeData(uw.w, data)
+}
+
+func (uw *UnixWriter) drain(err error) error {
+	for {
+		select {
+		case data := <-uw.queue:
+			err = uw.write(data, err)
+		default:
+			return err
+		}
+	}
+}
+
+func (uw *UnixWriter) flush() error {
+	uw.conn.SetWriteDeadline(time.Now().Add(uw.timeout))
+	return uw.w.Flush()
+}
+
+func (uw *UnixWriter) finish(err error) error {
+	defer uw.conn.Close()
+	if err != nil {
+		return err
+	}
+
+	uw.conn.SetDeadline(time.Now().Add(uw.timeout))
+	if err := writeControl(uw.w, &controlFrame{typ: controlStop}); err != nil {
+		return err
+	}
+	if err := uw.w.Flush(); err != nil {
+		return err
+	}
+	_, err = readControl(uw.conn, controlFinish)
+	return err
+}
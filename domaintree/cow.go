@@ -0,0 +1,439 @@
+package domaintree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zdnscloud/g53"
+)
+
+var ErrTransactionClosed = errors.New("transaction is already committed or rolled back")
+
+// CowDomainTree is a copy-on-write domain tree, modification is done in
+// transaction which only copies the nodes on the path to the modified
+// node, once committed, the new version is published atomically, readers
+// get a consistent read only snapshot without any lock
+type TypedCowDomainTree[T any] struct {
+	returnEmptyNode bool
+	snapshot        atomic.Value
+	writeLock       sync.Mutex
+	lastTxnID       uint64
+}
+
+type CowDomainTree = TypedCowDomainTree[interface{}]
+
+func NewCowDomainTree(returnEmptyNode bool) *CowDomainTree {
+	return NewTypedCowDomainTree[interface{}](returnEmptyNode)
+}
+
+func NewTypedCowDomainTree[T any](returnEmptyNode bool) *TypedCowDomainTree[T] {
+	tree := &TypedCowDomainTree[T]{
+		returnEmptyNode: returnEmptyNode,
+	}
+	tree.snapshot.Store(&TypedDomainTree[T]{
+		returnEmptyNode: returnEmptyNode,
+		readOnly:        true,
+	})
+	return tree
+}
+
+// Snapshot returns the last committed version, it and its nodes
+// shouldn't be modified
+func (t *TypedCowDomainTree[T]) Snapshot() *TypedDomainTree[T] {
+	return t.snapshot.Load().(*TypedDomainTree[T])
+}
+
+// Begin starts a transaction, only one transaction could be in progress,
+// Begin blocks until the previous one is committed or rolled back
+func (t *TypedCowDomainTree[T]) Begin() *TypedTransaction[T] {
+	t.writeLock.Lock()
+	t.lastTxnID += 1
+	snapshot := t.Snapshot()
+	return &TypedTransaction[T]{
+		tree:      t,
+		id:        t.lastTxnID,
+		root:      snapshot.root,
+		nodeCount: snapshot.nodeCount,
+	}
+}
+
+type TypedTransaction[T any] struct {
+	tree      *TypedCowDomainTree[T]
+	id        uint64
+	root      *TypedNode[T]
+	nodeCount int
+	closed    bool
+}
+
+type Transaction = TypedTransaction[interface{}]
+
+// Commit publishes the modification, the transaction can't be used
+// after commit
+func (txn *TypedTransaction[T]) Commit() error {
+	if txn.closed {
+		return ErrTransactionClosed
+	}
+
+	txn.tree.snapshot.Store(txn.view())
+	txn.close()
+	return nil
+}
+
+// Rollback discards the modification
+func (txn *TypedTransaction[T]) Rollback() error {
+	if txn.closed {
+		return ErrTransactionClosed
+	}
+
+	txn.close()
+	return nil
+}
+
+func (txn *TypedTransaction[T]) close() {
+	txn.closed = true
+	txn.root = nil
+	txn.tree.writeLock.Unlock()
+}
+
+func (txn *TypedTransaction[T]) checkOpen() {
+	if txn.closed {
+		panic("use closed transaction")
+	}
+}
+
+func (txn *TypedTransaction[T]) view() *TypedDomainTree[T] {
+	return &TypedDomainTree[T]{
+		returnEmptyNode: txn.tree.returnEmptyNode,
+		root:            txn.root,
+		nodeCount:       txn.nodeCount,
+		readOnly:        true,
+	}
+}
+
+// Tree returns the read only view which includes the uncommitted
+// modification, nodes returned by the view shouldn't be modified, use
+// Insert to get the modifiable node
+func (txn *TypedTransaction[T]) Tree() *TypedDomainTree[T] {
+	txn.checkOpen()
+	return txn.view()
+}
+
+func (txn *TypedTransaction[T]) NodeCount() int {
+	return txn.nodeCount
+}
+
+// Insert has the same semantic as DomainTree.Insert, the returned node
+// belongs to the transaction and could be modified until commit
+func (txn *TypedTransaction[T]) Insert(name *g53.Name) (*TypedNode[T], error) {
+	txn.checkOpen()
+	if name.IsRoot() {
+		return txn.insertRoot(), nil
+	}
+
+	firstCompare := true
+	root, node, err := txn.insertLevel(txn.root, name, &firstCompare)
+	txn.root = root
+	return node, err
+}
+
+func (txn *TypedTransaction[T]) insertRoot() *TypedNode[T] {
+	if txn.root != nil && txn.root.name.IsRoot() {
+		txn.root = txn.own(txn.root)
+		return txn.root
+	}
+
+	node := txn.newNode(g53.Root)
+	node.color = BLACK
+	node.down = txn.root
+	txn.root = node
+	return node
+}
+
+// insert name into the level rooted at root, new root of the level is
+// returned
+func (txn *TypedTransaction[T]) insertLevel(root *TypedNode[T], name *g53.Name, firstCompare *bool) (*TypedNode[T], *TypedNode[T], error) {
+	root, node, err := txn.insertSubTree(root, name, firstCompare)
+	if root.color == RED {
+		root = txn.mk(root, BLACK, root.left, root.right)
+	}
+	return root, node, err
+}
+
+func (txn *TypedTransaction[T]) insertSubTree(current *TypedNode[T], name *g53.Name, firstCompare *bool) (*TypedNode[T], *TypedNode[T], error) {
+	if current == nil {
+		node := txn.newNode(name)
+		return node, node, nil
+	}
+
+	comparison := name.Compare(current.name, false)
+	isFirstCompare := *firstCompare
+	*firstCompare = false
+	switch {
+	case comparison.Relation == g53.EQUAL:
+		current = txn.own(current)
+		if current.IsEmpty() {
+			return current, current, nil
+		} else {
+			return current, current, ErrAlreadyExist
+		}
+	case comparison.Relation == g53.SUBDOMAIN:
+		current = txn.own(current)
+		subName, _ := name.Subtract(current.name)
+		down, node, err := txn.insertLevel(current.down, subName, firstCompare)
+		current.down = down
+		return current, node, err
+	case comparison.CommonLabelCount > 1 || isFirstCompare:
+		commonAncestor, _ := name.Split(
+			name.LabelCount()-uint(comparison.CommonLabelCount),
+			uint(comparison.CommonLabelCount))
+		current = txn.own(current)
+		txn.nodeFission(current, commonAncestor)
+		return txn.insertSubTree(current, name, firstCompare)
+	case comparison.Order < 0:
+		left, node, err := txn.insertSubTree(current.left, name, firstCompare)
+		if current.color == BLACK {
+			return txn.balance(left, current, current.right), node, err
+		}
+		return txn.mk(current, RED, left, current.right), node, err
+	default:
+		right, node, err := txn.insertSubTree(current.right, name, firstCompare)
+		if current.color == BLACK {
+			return txn.balance(current.left, current, right), node, err
+		}
+		return txn.mk(current, RED, current.left, right), node, err
+	}
+}
+
+func (txn *TypedTransaction[T]) nodeFission(node *TypedNode[T], baseName *g53.Name) {
+	subName, _ := node.name.Subtract(baseName)
+	downNode := txn.newNode(subName)
+	downNode.color = BLACK
+	downNode.data, downNode.hasData = node.data, node.hasData
+	node.ClearData()
+	downNode.flag, node.flag = node.flag, 0
+	downNode.down = node.down
+	node.name = baseName
+	node.down = downNode
+}
+
+// Remove has the same semantic as DomainTree.Remove
+func (txn *TypedTransaction[T]) Remove(name *g53.Name) error {
+	txn.checkOpen()
+	if _, ret := txn.view().Search(name); ret != ExactMatch {
+		return fmt.Errorf("no found node with domain %s", name.String(false))
+	}
+
+	txn.root = txn.removeLevel(txn.root, name)
+	return nil
+}
+
+// name must exist in the level rooted at root, new root of the level
+// is returned
+func (txn *TypedTransaction[T]) removeLevel(root *TypedNode[T], name *g53.Name) *TypedNode[T] {
+	node := root
+	for {
+		comparison := name.Compare(node.name, false)
+		if comparison.Relation == g53.EQUAL {
+			if node.IsLeaf() {
+				return txn.deleteNode(root, node)
+			}
+			return txn.replaceNode(root, node, func(n *TypedNode[T]) { n.ClearData() })
+		} else if comparison.Relation == g53.SUBDOMAIN {
+			subName, _ := name.Subtract(node.name)
+			down := txn.removeLevel(node.down, subName)
+			if down == nil && node.IsEmpty() {
+				return txn.deleteNode(root, node)
+			}
+			return txn.replaceNode(root, node, func(n *TypedNode[T]) { n.down = down })
+		} else if comparison.Order < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+}
+
+// copy the path from current to target, and modify the copy of target
+func (txn *TypedTransaction[T]) replaceNode(current, target *TypedNode[T], modify func(*TypedNode[T])) *TypedNode[T] {
+	isTarget := current == target
+	left, right := current.left, current.right
+	current = txn.own(current)
+	if isTarget {
+		modify(current)
+	} else if target.name.Compare(current.name, false).Order < 0 {
+		current.left = txn.replaceNode(left, target, modify)
+	} else {
+		current.right = txn.replaceNode(right, target, modify)
+	}
+	return current
+}
+
+func (txn *TypedTransaction[T]) deleteNode(root, target *TypedNode[T]) *TypedNode[T] {
+	root = txn.del(root, target)
+	if isRed(root) {
+		root = txn.mk(root, BLACK, root.left, root.right)
+	}
+	txn.nodeCount -= 1
+	return root
+}
+
+func (txn *TypedTransaction[T]) own(node *TypedNode[T]) *TypedNode[T] {
+	if node.txnID == txn.id {
+		return node
+	}
+
+	copied := *node
+	copied.txnID = txn.id
+	copied.parent = nil
+	return &copied
+}
+
+func (txn *TypedTransaction[T]) newNode(name *g53.Name) *TypedNode[T] {
+	node := NewTypedNode[T](name)
+	node.txnID = txn.id
+	txn.nodeCount += 1
+	return node
+}
+
+// nodes owned by the transaction are modified in place, others are copied
+func (txn *TypedTransaction[T]) mk(node *TypedNode[T], color RBNodeColor, left, right *TypedNode[T]) *TypedNode[T] {
+	node = txn.own(node)
+	node.color = color
+	node.left = left
+	node.right = right
+	return node
+}
+
+func isRed[T any](node *TypedNode[T]) bool {
+	return node != nil && node.color == RED
+}
+
+func isBlack[T any](node *TypedNode[T]) bool {
+	return node != nil && node.color == BLACK
+}
+
+// the rebalance is from "Red-Black Trees with Types" by Stefan Kahrs,
+// which is purely functional, so it suits for copy-on-write
+func (txn *TypedTransaction[T]) balance(left, node, right *TypedNode[T]) *TypedNode[T] {
+	switch {
+	case isRed(left) && isRed(right):
+		a, b, c, d := left.left, left.right, right.left, right.right
+		return txn.mk(node, RED, txn.mk(left, BLACK, a, b), txn.mk(right, BLACK, c, d))
+	case isRed(left) && isRed(left.left):
+		ll := left.left
+		a, b, c := ll.left, ll.right, left.right
+		return txn.mk(left, RED, txn.mk(ll, BLACK, a, b), txn.mk(node, BLACK, c, right))
+	case isRed(left) && isRed(left.right):
+		lr := left.right
+		a, b, c := left.left, lr.left, lr.right
+		return txn.mk(lr, RED, txn.mk(left, BLACK, a, b), txn.mk(node, BLACK, c, right))
+	case isRed(right) && isRed(right.right):
+		rr := right.right
+		b, c, d := right.left, rr.left, rr.right
+		return txn.mk(right, RED, txn.mk(node, BLACK, left, b), txn.mk(rr, BLACK, c, d))
+	case isRed(right) && isRed(right.left):
+		rl := right.left
+		b, c, d := rl.left, rl.right, right.right
+		return txn.mk(rl, RED, txn.mk(node, BLACK, left, b), txn.mk(right, BLACK, c, d))
+	default:
+		return txn.mk(node, BLACK, left, right)
+	}
+}
+
+func (txn *TypedTransaction[T]) sub1(node *TypedNode[T]) *TypedNode[T] {
+	if isBlack(node) == false {
+		panic("red black tree invariance violation")
+	}
+	return txn.mk(node, RED, node.left, node.right)
+}
+
+func (txn *TypedTransaction[T]) balanceLeft(left, node, right *TypedNode[T]) *TypedNode[T] {
+	switch {
+	case isRed(left):
+		a, b := left.left, left.right
+		return txn.mk(node, RED, txn.mk(left, BLACK, a, b), right)
+	case isBlack(right):
+		a, b := right.left, right.right
+		return txn.balance(left, node, txn.mk(right, RED, a, b))
+	case isRed(right) && isBlack(right.left):
+		rl := right.left
+		a, b, c := rl.left, rl.right, right.right
+		return txn.mk(rl, RED, txn.mk(node, BLACK, left, a), txn.balance(b, right, txn.sub1(c)))
+	default:
+		panic("red black tree invariance violation")
+	}
+}
+
+func (txn *TypedTransaction[T]) balanceRight(left, node, right *TypedNode[T]) *TypedNode[T] {
+	switch {
+	case isRed(right):
+		b, c := right.left, right.right
+		return txn.mk(node, RED, left, txn.mk(right, BLACK, b, c))
+	case isBlack(left):
+		a, b := left.left, left.right
+		return txn.balance(txn.mk(left, RED, a, b), node, right)
+	case isRed(left) && isBlack(left.right):
+		lr := left.right
+		a, b, c := left.left, lr.left, lr.right
+		return txn.mk(lr, RED, txn.balance(txn.sub1(a), left, b), txn.mk(node, BLACK, c, right))
+	default:
+		panic("red black tree invariance violation")
+	}
+}
+
+// join the two sub trees of the deleted node
+func (txn *TypedTransaction[T]) join(left, right *TypedNode[T]) *TypedNode[T] {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case isRed(left) && isRed(right):
+		a, d := left.left, right.right
+		bc := txn.join(left.right, right.left)
+		if isRed(bc) {
+			b, c := bc.left, bc.right
+			return txn.mk(bc, RED, txn.mk(left, RED, a, b), txn.mk(right, RED, c, d))
+		}
+		return txn.mk(left, RED, a, txn.mk(right, RED, bc, d))
+	case isBlack(left) && isBlack(right):
+		a, d := left.left, right.right
+		bc := txn.join(left.right, right.left)
+		if isRed(bc) {
+			b, c := bc.left, bc.right
+			return txn.mk(bc, RED, txn.mk(left, BLACK, a, b), txn.mk(right, BLACK, c, d))
+		}
+		return txn.balanceLeft(a, left, txn.mk(right, BLACK, bc, d))
+	case isRed(right):
+		b, c := right.left, right.right
+		return txn.mk(right, RED, txn.join(left, b), c)
+	default:
+		a, b := left.left, left.right
+		return txn.mk(left, RED, a, txn.join(b, right))
+	}
+}
+
+func (txn *TypedTransaction[T]) del(current, target *TypedNode[T]) *TypedNode[T] {
+	if current == nil {
+		panic("delete node which doesn't exist")
+	}
+
+	left, right := current.left, current.right
+	if current == target {
+		return txn.join(left, right)
+	}
+
+	if target.name.Compare(current.name, false).Order < 0 {
+		if isBlack(left) {
+			return txn.balanceLeft(txn.del(left, target), current, right)
+		}
+		return txn.mk(current, RED, txn.del(left, target), right)
+	} else {
+		if isBlack(right) {
+			return txn.balanceRight(left, current, txn.del(right, target))
+		}
+		return txn.mk(current, RED, left, txn.del(right, target))
+	}
+}
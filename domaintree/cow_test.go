@@ -0,0 +1,231 @@
+package domaintree
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+func createCowDomainTree(returnEmptyNode bool) *CowDomainTree {
+	domains := []string{
+		"c", "b", "a", "x.d.e.f", "z.d.e.f", "g.h", "i.g.h", "o.w.y.d.e.f",
+		"j.z.d.e.f", "p.w.y.d.e.f", "q.w.y.d.e.f"}
+
+	tree := NewCowDomainTree(returnEmptyNode)
+	txn := tree.Begin()
+	for i, d := range domains {
+		node, _ := txn.Insert(g53.NameFromStringUnsafe(d))
+		node.SetData(i + 1)
+	}
+	txn.Commit()
+	return tree
+}
+
+func treeContent(tree *DomainTree) []string {
+	var content []string
+	nodePath := NewNodeChain()
+	for node := tree.First(nodePath); node != nil; node = tree.Next(nodePath) {
+		content = append(content, fmt.Sprintf("%s:%v", nodePath.GetAbsoluteName().String(true), node.Data()))
+	}
+	return content
+}
+
+// return black height of the level, and check the levels below
+func checkLevel(t *testing.T, node *Node) int {
+	if node == NULL_NODE {
+		return 1
+	}
+
+	if node.color == RED {
+		ut.Assert(t, isRed(node.left) == false && isRed(node.right) == false, "red node has red child")
+	}
+	if node.down != NULL_NODE {
+		ut.Equal(t, node.down.color, BLACK)
+		checkLevel(t, node.down)
+	}
+
+	leftHeight := checkLevel(t, node.left)
+	ut.Equal(t, leftHeight, checkLevel(t, node.right))
+	if node.color == BLACK {
+		return leftHeight + 1
+	}
+	return leftHeight
+}
+
+func TestCowTreeSnapshot(t *testing.T) {
+	tree := createCowDomainTree(false)
+	old := tree.Snapshot()
+	ut.Equal(t, old.NodeCount(), 14)
+	ut.Equal(t, treeContent(old), treeContent(createDomainTree(false).Clone(nil)))
+	ut.Equal(t, len(treeContent(old)), len(canonicalNames))
+
+	txn := tree.Begin()
+	node, err := txn.Insert(g53.NameFromStringUnsafe("c"))
+	ut.Equal(t, err, ErrAlreadyExist)
+	node.SetData(100)
+	node, err = txn.Insert(g53.NameFromStringUnsafe("k.e.f"))
+	ut.Equal(t, err, nil)
+	node.SetData(200)
+	ut.Equal(t, txn.Remove(g53.NameFromStringUnsafe("j.z.d.e.f")), nil)
+	ut.Assert(t, txn.Remove(g53.NameFromStringUnsafe("y.d.e.f")) != nil, "remove non-exist name should fail")
+
+	_, ret := txn.Tree().Search(g53.NameFromStringUnsafe("k.e.f"))
+	ut.Equal(t, ret, ExactMatch)
+	_, ret = tree.Snapshot().Search(g53.NameFromStringUnsafe("k.e.f"))
+	ut.Equal(t, ret, NotFound)
+	ut.Equal(t, txn.Commit(), nil)
+	ut.Equal(t, txn.Commit(), ErrTransactionClosed)
+
+	current := tree.Snapshot()
+	node, ret = current.Search(g53.NameFromStringUnsafe("c"))
+	ut.Equal(t, node.Data(), 100)
+	node, ret = current.Search(g53.NameFromStringUnsafe("k.e.f"))
+	ut.Equal(t, node.Data(), 200)
+	_, ret = current.Search(g53.NameFromStringUnsafe("j.z.d.e.f"))
+	ut.Equal(t, ret, PartialMatch)
+	ut.Equal(t, current.NodeCount(), 15)
+
+	// old snapshot isn't affected
+	ut.Equal(t, treeContent(old), treeContent(createDomainTree(false)))
+	node, _ = old.Search(g53.NameFromStringUnsafe("c"))
+	ut.Equal(t, node.Data(), 1)
+
+	txn = tree.Begin()
+	txn.Insert(g53.NameFromStringUnsafe("rollback"))
+	txn.Rollback()
+	ut.Assert(t, tree.Snapshot() == current, "rollback shouldn't change the tree")
+}
+
+func TestCowTreeReadOnlySnapshot(t *testing.T) {
+	tree := createCowDomainTree(false)
+	snapshot := tree.Snapshot()
+	ut.Assert(t, snapshot.IsReadOnly(), "snapshot should be read only")
+	defer func() {
+		ut.Assert(t, recover() != nil, "insert into snapshot should panic")
+	}()
+	snapshot.Insert(g53.NameFromStringUnsafe("a"))
+}
+
+func TestCowTreeRandomDomain(t *testing.T) {
+	tree := NewDomainTree(false)
+	cow := NewCowDomainTree(false)
+	var names []*g53.Name
+	for round := 0; round < 20; round++ {
+		txn := cow.Begin()
+		for i := 0; i < 50; i++ {
+			name := randomHierarchicalName()
+			if rand.Intn(3) == 0 && len(names) > 0 {
+				name = names[rand.Intn(len(names))]
+				err := tree.Remove(name)
+				ut.Equal(t, txn.Remove(name) == nil, err == nil)
+				continue
+			}
+
+			node, err := tree.Insert(name)
+			cowNode, cowErr := txn.Insert(name)
+			ut.Equal(t, cowErr, err)
+			if err == nil {
+				node.SetData(i)
+				cowNode.SetData(i)
+				names = append(names, name)
+			}
+		}
+		ut.Equal(t, txn.NodeCount(), tree.NodeCount())
+		ut.Equal(t, treeContent(txn.Tree()), treeContent(tree))
+		txn.Commit()
+
+		snapshot := cow.Snapshot()
+		checkLevel(t, snapshot.root)
+		ut.Equal(t, treeContent(snapshot), treeContent(tree))
+
+		var reversed []string
+		nodePath := NewNodeChain()
+		for node := snapshot.Last(nodePath); node != nil; node = snapshot.Prev(nodePath) {
+			reversed = append([]string{fmt.Sprintf("%s:%v", nodePath.GetAbsoluteName().String(true), node.Data())}, reversed...)
+		}
+		ut.Equal(t, reversed, treeContent(tree))
+	}
+}
+
+func collectNodes(node *Node, nodes map[*Node]struct{}) {
+	if node == NULL_NODE {
+		return
+	}
+	nodes[node] = struct{}{}
+	collectNodes(node.left, nodes)
+	collectNodes(node.right, nodes)
+	collectNodes(node.down, nodes)
+}
+
+func TestCowTreeCopyPath(t *testing.T) {
+	tree := NewCowDomainTree(false)
+	txn := tree.Begin()
+	for i := 0; i < 10000; i++ {
+		node, err := txn.Insert(g53.NameFromStringUnsafe(fmt.Sprintf("%d.%d.example.com", i, i%100)))
+		ut.Equal(t, err, nil)
+		node.SetData(i)
+	}
+	txn.Commit()
+
+	old := tree.Snapshot()
+	oldNodes := make(map[*Node]struct{})
+	collectNodes(old.root, oldNodes)
+
+	txn = tree.Begin()
+	txn.Insert(g53.NameFromStringUnsafe("new.50.example.com"))
+	txn.Commit()
+
+	newNodes := make(map[*Node]struct{})
+	collectNodes(tree.Snapshot().root, newNodes)
+	copied := 0
+	for node := range newNodes {
+		if _, ok := oldNodes[node]; ok == false {
+			copied += 1
+		}
+	}
+	ut.Assert(t, copied < 64, "insert should only copy nodes on the path but copied %d nodes", copied)
+}
+
+func TestCowTreeConcurrentRead(t *testing.T) {
+	tree := createCowDomainTree(false)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snapshot := tree.Snapshot()
+				node, ret := snapshot.Search(g53.NameFromStringUnsafe("a"))
+				if ret != ExactMatch || node.Data() != 3 {
+					t.Errorf("a should always exist")
+					return
+				}
+				if len(treeContent(snapshot))%2 != 1 {
+					t.Errorf("snapshot should be consistent")
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		txn := tree.Begin()
+		node, _ := txn.Insert(g53.NameFromStringUnsafe(fmt.Sprintf("%d.pair", i)))
+		node.SetData(i)
+		node, _ = txn.Insert(g53.NameFromStringUnsafe(fmt.Sprintf("%d.pair.another", i)))
+		node.SetData(i)
+		txn.Commit()
+	}
+	close(stop)
+	wg.Wait()
+}
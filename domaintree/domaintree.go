@@ -17,45 +17,68 @@ const (
 
 var ErrAlreadyExist = errors.New("name already exists")
 
-type DomainTree struct {
+// TypedDomainTree stores data of type T in its nodes, DomainTree is the
+// one which stores interface{}
+type TypedDomainTree[T any] struct {
 	returnEmptyNode bool
-	root            *Node
+	root            *TypedNode[T]
 	nodeCount       int
+	readOnly        bool
 }
 
-type NodeCallBack func(*Node, interface{}) bool
+type DomainTree = TypedDomainTree[interface{}]
+
+type TypedNodeCallBack[T any] func(*TypedNode[T], interface{}) bool
+
+type NodeCallBack = TypedNodeCallBack[interface{}]
 
 func NewDomainTree(returnEmptyNode bool) *DomainTree {
-	return &DomainTree{
+	return NewTypedDomainTree[interface{}](returnEmptyNode)
+}
+
+func NewTypedDomainTree[T any](returnEmptyNode bool) *TypedDomainTree[T] {
+	return &TypedDomainTree[T]{
 		returnEmptyNode: returnEmptyNode,
-		root:            NULL_NODE,
 	}
 }
 
-func (tree *DomainTree) NodeCount() int {
+// snapshot of CowDomainTree shares nodes with other snapshots, so it
+// can't be modified
+func (tree *TypedDomainTree[T]) IsReadOnly() bool {
+	return tree.readOnly
+}
+
+func (tree *TypedDomainTree[T]) checkWritable() {
+	if tree.readOnly {
+		panic("modify read only domain tree")
+	}
+}
+
+func (tree *TypedDomainTree[T]) NodeCount() int {
 	return tree.nodeCount
 }
 
-func (tree *DomainTree) Search(name *g53.Name) (*Node, SearchResult) {
-	nodePath := NewNodeChain()
+func (tree *TypedDomainTree[T]) Search(name *g53.Name) (*TypedNode[T], SearchResult) {
+	nodePath := NewTypedNodeChain[T]()
 	return tree.SearchExt(name, nodePath, nil, nil)
 }
 
-func (tree *DomainTree) Clean() {
+func (tree *TypedDomainTree[T]) Clean() {
+	tree.checkWritable()
 	tree.root.Clean()
-	tree.root = NULL_NODE
+	tree.root = nil
 	tree.nodeCount = 0
 }
 
-func (tree *DomainTree) SearchExt(name *g53.Name, nodePath *NodeChain, callback NodeCallBack, params interface{}) (*Node, SearchResult) {
+func (tree *TypedDomainTree[T]) SearchExt(name *g53.Name, nodePath *TypedNodeChain[T], callback TypedNodeCallBack[T], params interface{}) (*TypedNode[T], SearchResult) {
 	if nodePath.IsEmpty() == false {
 		panic("search is given a null empty chain")
 	}
 
-	var target *Node
+	var target *TypedNode[T]
 	node := tree.root
 	ret := NotFound
-	for node != NULL_NODE {
+	for node != nil {
 		nodePath.lastCompared = node
 		comparison := name.Compare(node.name, false)
 		nodePath.lastComparison = comparison
@@ -97,15 +120,15 @@ func (tree *DomainTree) SearchExt(name *g53.Name, nodePath *NodeChain, callback
 	return target, ret
 }
 
-func (tree *DomainTree) nextNode(nodePath *NodeChain) *Node {
+func (tree *TypedDomainTree[T]) nextNode(nodePath *TypedNodeChain[T]) *TypedNode[T] {
 	if nodePath.IsEmpty() {
 		panic("next node is given a empty node path")
 	}
 
 	node := nodePath.Top()
-	if node.down != NULL_NODE {
+	if node.down != nil {
 		leftMost := node.down
-		for leftMost.left != NULL_NODE {
+		for leftMost.left != nil {
 			leftMost = leftMost.left
 		}
 		nodePath.push(leftMost)
@@ -115,8 +138,8 @@ func (tree *DomainTree) nextNode(nodePath *NodeChain) *Node {
 	// node_path go to up level
 	nodePath.Pop()
 	// otherwise found the successor node in current level
-	successor := node.successor()
-	if successor != NULL_NODE {
+	successor := tree.levelSuccessor(nodePath, node)
+	if successor != nil {
 		nodePath.push(successor)
 		return successor
 	}
@@ -126,9 +149,10 @@ func (tree *DomainTree) nextNode(nodePath *NodeChain) *Node {
 	// up node doesn't have successor we gonna keep moving to up
 	// level
 	for nodePath.IsEmpty() == false {
-		upNodeSuccessor := nodePath.Top().successor()
+		upNode := nodePath.Top()
 		nodePath.Pop()
-		if upNodeSuccessor != NULL_NODE {
+		upNodeSuccessor := tree.levelSuccessor(nodePath, upNode)
+		if upNodeSuccessor != nil {
 			nodePath.push(upNodeSuccessor)
 			return upNodeSuccessor
 		}
@@ -137,18 +161,18 @@ func (tree *DomainTree) nextNode(nodePath *NodeChain) *Node {
 	return nil
 }
 
-func (tree *DomainTree) Insert(name *g53.Name) (*Node, error) {
+func (tree *TypedDomainTree[T]) Insert(name *g53.Name) (*TypedNode[T], error) {
+	tree.checkWritable()
 	if name.IsRoot() {
 		return tree.insertRoot()
 	}
 
-	parent := NULL_NODE
-	upNode := NULL_NODE
+	var parent, upNode *TypedNode[T]
 	current := tree.root
 
 	order := -1
 	firstCompare := true
-	for current != NULL_NODE {
+	for current != nil {
 		comparison := name.Compare(current.name, false)
 		if comparison.Relation == g53.EQUAL {
 			if current.IsEmpty() {
@@ -159,7 +183,7 @@ func (tree *DomainTree) Insert(name *g53.Name) (*Node, error) {
 		} else {
 			if comparison.Relation == g53.SUBDOMAIN {
 				// insert sub domain to sub tree
-				parent = NULL_NODE
+				parent = nil
 				upNode = current
 				name, _ = name.Subtract(current.name)
 				current = current.down
@@ -189,12 +213,12 @@ func (tree *DomainTree) Insert(name *g53.Name) (*Node, error) {
 	}
 
 	currentRoot := &tree.root
-	if upNode != NULL_NODE {
+	if upNode != nil {
 		currentRoot = &upNode.down
 	}
-	node := NewNode(name)
+	node := NewTypedNode[T](name)
 	node.parent = parent
-	if parent == NULL_NODE {
+	if parent == nil {
 		*currentRoot = node
 		node.color = BLACK
 	} else if order < 0 {
@@ -207,16 +231,16 @@ func (tree *DomainTree) Insert(name *g53.Name) (*Node, error) {
 	return node, nil
 }
 
-func (tree *DomainTree) insertRoot() (*Node, error) {
+func (tree *TypedDomainTree[T]) insertRoot() (*TypedNode[T], error) {
 	current := tree.root
-	if current != NULL_NODE && current.name.IsRoot() {
+	if current != nil && current.name.IsRoot() {
 		return current, nil
 	}
 
-	node := NewNode(g53.Root)
+	node := NewTypedNode[T](g53.Root)
 	node.color = BLACK
 	node.down = current
-	if current != NULL_NODE {
+	if current != nil {
 		current.parent = node
 		current.color = BLACK
 	}
@@ -225,12 +249,13 @@ func (tree *DomainTree) insertRoot() (*Node, error) {
 	return node, nil
 }
 
-func (tree *DomainTree) nodeFission(oldNode *Node, baseName *g53.Name) {
+func (tree *TypedDomainTree[T]) nodeFission(oldNode *TypedNode[T], baseName *g53.Name) {
 	oldName := oldNode.name
 	subName, _ := oldName.Subtract(baseName)
-	downNode := NewNode(subName)
+	downNode := NewTypedNode[T](subName)
 	oldNode.name = baseName
 	downNode.data, oldNode.data = oldNode.data, downNode.data
+	downNode.hasData, oldNode.hasData = oldNode.hasData, downNode.hasData
 	downNode.flag, oldNode.flag = oldNode.flag, downNode.flag
 	downNode.down = oldNode.down
 	oldNode.down = downNode
@@ -238,12 +263,12 @@ func (tree *DomainTree) nodeFission(oldNode *Node, baseName *g53.Name) {
 	tree.nodeCount += 1
 }
 
-func (tree *DomainTree) insertRebalance(root **Node, node *Node) {
-	var uncle *Node
+func (tree *TypedDomainTree[T]) insertRebalance(root **TypedNode[T], node *TypedNode[T]) {
+	var uncle *TypedNode[T]
 	for node != *root && node.parent.color == RED {
 		if node.parent == node.parent.parent.left {
 			uncle = node.parent.parent.right
-			if uncle.color == RED {
+			if isRed(uncle) {
 				node.parent.color = BLACK
 				uncle.color = BLACK
 				node.parent.parent.color = RED
@@ -259,7 +284,7 @@ func (tree *DomainTree) insertRebalance(root **Node, node *Node) {
 			}
 		} else {
 			uncle = node.parent.parent.left
-			if uncle.color == RED {
+			if isRed(uncle) {
 				node.parent.color = BLACK
 				uncle.color = BLACK
 				node.parent.parent.color = RED
@@ -279,14 +304,14 @@ func (tree *DomainTree) insertRebalance(root **Node, node *Node) {
 	(*root).color = BLACK
 }
 
-func (tree *DomainTree) leftRotate(root **Node, node *Node) *Node {
+func (tree *TypedDomainTree[T]) leftRotate(root **TypedNode[T], node *TypedNode[T]) *TypedNode[T] {
 	right := node.right
 	node.right = right.left
-	if right.left != NULL_NODE {
+	if right.left != nil {
 		right.left.parent = node
 	}
 	right.parent = node.parent
-	if node.parent != NULL_NODE {
+	if node.parent != nil {
 		if node == node.parent.left {
 			node.parent.left = right
 		} else {
@@ -301,14 +326,14 @@ func (tree *DomainTree) leftRotate(root **Node, node *Node) *Node {
 	return node
 }
 
-func (tree *DomainTree) rightRotate(root **Node, node *Node) *Node {
+func (tree *TypedDomainTree[T]) rightRotate(root **TypedNode[T], node *TypedNode[T]) *TypedNode[T] {
 	left := node.left
 	node.left = left.right
-	if left.right != NULL_NODE {
+	if left.right != nil {
 		left.right.parent = node
 	}
 	left.parent = node.parent
-	if node.parent != NULL_NODE {
+	if node.parent != nil {
 		if node == node.parent.right {
 			node.parent.right = left
 		} else {
@@ -322,20 +347,21 @@ func (tree *DomainTree) rightRotate(root **Node, node *Node) *Node {
 	return node
 }
 
-func (tree *DomainTree) Remove(name *g53.Name) error {
-	nodePath := NewNodeChain()
+func (tree *TypedDomainTree[T]) Remove(name *g53.Name) error {
+	tree.checkWritable()
+	nodePath := NewTypedNodeChain[T]()
 	node, result := tree.SearchExt(name, nodePath, nil, nil)
 	if result != ExactMatch {
 		return fmt.Errorf("no found node with domain %s", name.String(false))
 	}
 
 	if node.IsLeaf() == false {
-		node.SetData(nil)
+		node.ClearData()
 		return nil
 	}
 
 	for {
-		upperNode := NULL_NODE
+		var upperNode *TypedNode[T]
 		if nodePath.IsEmpty() == false {
 			nodePath.Pop()
 			if nodePath.IsEmpty() == false {
@@ -343,9 +369,9 @@ func (tree *DomainTree) Remove(name *g53.Name) error {
 			}
 		}
 
-		if node.left != NULL_NODE && node.right != NULL_NODE {
+		if node.left != nil && node.right != nil {
 			rightMost := node.left
-			for rightMost.right != NULL_NODE {
+			for rightMost.right != nil {
 				rightMost = rightMost.right
 			}
 
@@ -353,21 +379,21 @@ func (tree *DomainTree) Remove(name *g53.Name) error {
 		}
 
 		child := node.left
-		if node.right != NULL_NODE {
+		if node.right != nil {
 			child = node.right
 		}
 
 		tree.connectChild(node, node, child, upperNode)
-		if child != NULL_NODE {
+		if child != nil {
 			child.parent = node.parent
 		}
 
 		if node.color == BLACK {
-			if child != NULL_NODE && child.color == RED {
+			if child != nil && child.color == RED {
 				child.color = BLACK
 			} else {
 				currentRoot := &tree.root
-				if upperNode != NULL_NODE {
+				if upperNode != nil {
 					currentRoot = &upperNode.down
 				}
 				tree.removeRebalance(currentRoot, child, node.parent)
@@ -375,7 +401,7 @@ func (tree *DomainTree) Remove(name *g53.Name) error {
 		}
 
 		tree.nodeCount -= 1
-		if upperNode == NULL_NODE || upperNode.down != NULL_NODE || upperNode.IsEmpty() == false {
+		if upperNode == nil || upperNode.down != nil || upperNode.IsEmpty() == false {
 			break
 		}
 
@@ -385,7 +411,7 @@ func (tree *DomainTree) Remove(name *g53.Name) error {
 	return nil
 }
 
-func (tree *DomainTree) exchange(node, lowerNode, upperNode *Node) {
+func (tree *TypedDomainTree[T]) exchange(node, lowerNode, upperNode *TypedNode[T]) {
 	node.left, lowerNode.left = lowerNode.left, node.left
 	if lowerNode.left == lowerNode {
 		lowerNode.left = node
@@ -410,22 +436,22 @@ func (tree *DomainTree) exchange(node, lowerNode, upperNode *Node) {
 		node.parent.right = node
 	}
 
-	if lowerNode.right != NULL_NODE {
+	if lowerNode.right != nil {
 		lowerNode.right.parent = lowerNode
 	}
 
-	if lowerNode.left != NULL_NODE {
+	if lowerNode.left != nil {
 		lowerNode.left.parent = lowerNode
 	}
 }
 
-func (tree *DomainTree) connectChild(node, oldNode, newNode, upperNode *Node) {
+func (tree *TypedDomainTree[T]) connectChild(node, oldNode, newNode, upperNode *TypedNode[T]) {
 	connectNode := node.parent
-	if node.parent == NULL_NODE {
+	if node.parent == nil {
 		connectNode = upperNode
 	}
 
-	if connectNode != NULL_NODE {
+	if connectNode != nil {
 		if connectNode.left == oldNode {
 			connectNode.left = newNode
 		} else if connectNode.right == oldNode {
@@ -439,10 +465,10 @@ func (tree *DomainTree) connectChild(node, oldNode, newNode, upperNode *Node) {
 	}
 }
 
-func (tree *DomainTree) removeRebalance(root **Node, child, parent *Node) {
-	for parent != NULL_NODE {
+func (tree *TypedDomainTree[T]) removeRebalance(root **TypedNode[T], child, parent *TypedNode[T]) {
+	for parent != nil {
 		sibling := getSibling(parent, child)
-		if sibling == NULL_NODE {
+		if sibling == nil {
 			panic("sibling can`t be null node when remove rebalance")
 		}
 
@@ -458,11 +484,11 @@ func (tree *DomainTree) removeRebalance(root **Node, child, parent *Node) {
 			sibling = getSibling(parent, child)
 		}
 
-		if sibling == NULL_NODE || sibling.color != BLACK {
+		if sibling == nil || sibling.color != BLACK {
 			panic("sibling can`t be null node or its color can`t be red")
 		}
 
-		if sibling.left.color == BLACK && sibling.right.color == BLACK {
+		if isRed(sibling.left) == false && isRed(sibling.right) == false {
 			sibling.color = RED
 			if parent.color == BLACK {
 				child = parent
@@ -474,7 +500,7 @@ func (tree *DomainTree) removeRebalance(root **Node, child, parent *Node) {
 			break
 		}
 
-		if sibling == NULL_NODE || sibling.color != BLACK {
+		if sibling == nil || sibling.color != BLACK {
 			panic("sibling can`t be null node or its color can`t be red")
 		}
 
@@ -483,7 +509,7 @@ func (tree *DomainTree) removeRebalance(root **Node, child, parent *Node) {
 			ss1, ss2 = ss2, ss1
 		}
 
-		if ss2.color == BLACK {
+		if isRed(ss2) == false {
 			sibling.color = RED
 			ss1.color = BLACK
 
@@ -496,7 +522,7 @@ func (tree *DomainTree) removeRebalance(root **Node, child, parent *Node) {
 			sibling = getSibling(parent, child)
 		}
 
-		if sibling == NULL_NODE || sibling.color != BLACK {
+		if sibling == nil || sibling.color != BLACK {
 			panic("sibling can`t be null node or its color can`t be red")
 		}
 
@@ -518,9 +544,9 @@ func (tree *DomainTree) removeRebalance(root **Node, child, parent *Node) {
 	}
 }
 
-func getSibling(parent, child *Node) *Node {
-	if parent == NULL_NODE {
-		return NULL_NODE
+func getSibling[T any](parent, child *TypedNode[T]) *TypedNode[T] {
+	if parent == nil {
+		return nil
 	}
 
 	if parent.left == child {
@@ -530,14 +556,14 @@ func getSibling(parent, child *Node) *Node {
 	return parent.left
 }
 
-func (tree *DomainTree) Dump(depth int) {
+func (tree *TypedDomainTree[T]) Dump(depth int) {
 	tree.indent(depth)
 	fmt.Printf("tree has %d node(s)\n", tree.nodeCount)
 	tree.dumpTreeHelper(tree.root, depth)
 }
 
-func (tree *DomainTree) dumpTreeHelper(node *Node, depth int) {
-	if node == NULL_NODE {
+func (tree *TypedDomainTree[T]) dumpTreeHelper(node *TypedNode[T], depth int) {
+	if node == nil {
 		tree.indent(depth)
 		fmt.Printf("NULL\n")
 		return
@@ -551,7 +577,7 @@ func (tree *DomainTree) dumpTreeHelper(node *Node, depth int) {
 		fmt.Printf("\n")
 	}
 
-	if node.down != NULL_NODE {
+	if node.down != nil {
 		tree.indent(depth + 1)
 		fmt.Printf("begin down from %s\n", node.name.String(false))
 		tree.dumpTreeHelper(node.down, depth+1)
@@ -564,7 +590,7 @@ func (tree *DomainTree) dumpTreeHelper(node *Node, depth int) {
 
 const INDENT_FOR_EACH_DEPTH = 5
 
-func (tree *DomainTree) indent(depth int) {
+func (tree *TypedDomainTree[T]) indent(depth int) {
 	spaceLen := depth * INDENT_FOR_EACH_DEPTH
 	space := make([]byte, spaceLen)
 	for i := 0; i < spaceLen; i++ {
@@ -573,12 +599,12 @@ func (tree *DomainTree) indent(depth int) {
 	fmt.Printf("%s", string(space))
 }
 
-func (tree *DomainTree) ForEach(fn func(*Node)) {
+func (tree *TypedDomainTree[T]) ForEach(fn func(*TypedNode[T])) {
 	tree.forEachHelper(tree.root, tree.returnEmptyNode, fn)
 }
 
-func (tree *DomainTree) forEachHelper(node *Node, returnEmptyNode bool, fn func(*Node)) {
-	if node == NULL_NODE {
+func (tree *TypedDomainTree[T]) forEachHelper(node *TypedNode[T], returnEmptyNode bool, fn func(*TypedNode[T])) {
+	if node == nil {
 		return
 	}
 
@@ -591,12 +617,12 @@ func (tree *DomainTree) forEachHelper(node *Node, returnEmptyNode bool, fn func(
 	tree.forEachHelper(node.down, returnEmptyNode, fn)
 }
 
-func (tree *DomainTree) ForEachEx(fn func(*g53.Name, *Node)) {
+func (tree *TypedDomainTree[T]) ForEachEx(fn func(*g53.Name, *TypedNode[T])) {
 	tree.forEachExHelper(tree.root, g53.Root, tree.returnEmptyNode, fn)
 }
 
-func (tree *DomainTree) forEachExHelper(node *Node, parentFullName *g53.Name, returnEmptyNode bool, fn func(*g53.Name, *Node)) {
-	if node == NULL_NODE {
+func (tree *TypedDomainTree[T]) forEachExHelper(node *TypedNode[T], parentFullName *g53.Name, returnEmptyNode bool, fn func(*g53.Name, *TypedNode[T])) {
+	if node == nil {
 		return
 	}
 
@@ -611,18 +637,18 @@ func (tree *DomainTree) forEachExHelper(node *Node, parentFullName *g53.Name, re
 	tree.forEachExHelper(node.down, newParent, returnEmptyNode, fn)
 }
 
-func (tree *DomainTree) IsNodeNonTerminal(node *Node) bool {
-	return tree.anyHelper(node.down, func(n *Node) bool {
+func (tree *TypedDomainTree[T]) IsNodeNonTerminal(node *TypedNode[T]) bool {
+	return tree.anyHelper(node.down, func(n *TypedNode[T]) bool {
 		return n.IsEmpty() == false
 	})
 }
 
-func (tree *DomainTree) All(fn func(*Node) bool) bool {
+func (tree *TypedDomainTree[T]) All(fn func(*TypedNode[T]) bool) bool {
 	return tree.allHelper(tree.root, fn)
 }
 
-func (tree *DomainTree) allHelper(node *Node, fn func(*Node) bool) bool {
-	if node == NULL_NODE {
+func (tree *TypedDomainTree[T]) allHelper(node *TypedNode[T], fn func(*TypedNode[T]) bool) bool {
+	if node == nil {
 		return true
 	}
 
@@ -637,12 +663,12 @@ func (tree *DomainTree) allHelper(node *Node, fn func(*Node) bool) bool {
 		tree.allHelper(node.down, fn)
 }
 
-func (tree *DomainTree) Any(fn func(*Node) bool) bool {
+func (tree *TypedDomainTree[T]) Any(fn func(*TypedNode[T]) bool) bool {
 	return tree.anyHelper(tree.root, fn)
 }
 
-func (tree *DomainTree) anyHelper(node *Node, fn func(*Node) bool) bool {
-	if node == NULL_NODE {
+func (tree *TypedDomainTree[T]) anyHelper(node *TypedNode[T], fn func(*TypedNode[T]) bool) bool {
+	if node == nil {
 		return false
 	}
 
@@ -657,12 +683,12 @@ func (tree *DomainTree) anyHelper(node *Node, fn func(*Node) bool) bool {
 		tree.anyHelper(node.down, fn)
 }
 
-func (tree *DomainTree) Clone(valueConeFunc ValueCloneFunc) *DomainTree {
+func (tree *TypedDomainTree[T]) Clone(valueConeFunc TypedValueCloneFunc[T]) *TypedDomainTree[T] {
 	if valueConeFunc == nil {
-		valueConeFunc = DefaultValueCloneFunc
+		valueConeFunc = func(v T) T { return v }
 	}
 
-	new := NewDomainTree(tree.returnEmptyNode)
+	new := NewTypedDomainTree[T](tree.returnEmptyNode)
 	new.root = tree.root.Clone(valueConeFunc)
 	new.nodeCount = tree.nodeCount
 	return new
@@ -36,7 +36,7 @@ func createDomainTree(returnEmptyNode bool) *DomainTree {
 	tree := NewDomainTree(returnEmptyNode)
 	for i, d := range domains {
 		node, _ := treeInsertString(tree, d)
-		node.data = i + 1
+		node.SetData(i + 1)
 	}
 	return tree
 }
@@ -63,7 +63,7 @@ func TestTreeInsert(t *testing.T) {
 	node, err = treeInsertString(tree, "example.com")
 	ut.Assert(t, err == nil, "inert new domain should ok but get %v", err)
 	ut.Equal(t, tree.nodeCount, 15)
-	node.data = 12
+	node.SetData(12)
 
 	node, err = treeInsertString(tree, "example.com")
 	ut.Equal(t, err, ErrAlreadyExist)
@@ -172,14 +172,14 @@ func TestTreeNodeCallback(t *testing.T) {
 	tree := createDomainTree(false)
 	node, err := treeInsertString(tree, "callback.example")
 	ut.Equal(t, err, nil)
-	node.data = 1
+	node.SetData(1)
 	ut.Assert(t, node.GetFlag(NF_CALLBACK) == false, "by default, node has no flag")
 	node.SetFlag(NF_CALLBACK, true)
 	// add more levels below and above the callback node for partial match.
 
 	subNode, err := treeInsertString(tree, "sub.callback.example")
 	ut.Equal(t, err, nil)
-	node.data = 2
+	node.SetData(2)
 	parentNode, _ := treeInsertString(tree, "example")
 	node, ret := tree.Search(g53.NameFromStringUnsafe("callback.example"))
 	ut.Assert(t, node.GetFlag(NF_CALLBACK) == true, "node has set flag")
@@ -290,11 +290,11 @@ func TestNonTerminal(t *testing.T) {
 	node, _ = tree.Search(g53.NameFromStringUnsafe("p.w.y.d.e.f"))
 	ut.Assert(t, tree.IsNodeNonTerminal(node) == false, "")
 
-	node.SetData(nil)
+	node.ClearData()
 	node, _ = tree.Search(g53.NameFromStringUnsafe("o.w.y.d.e.f"))
-	node.SetData(nil)
+	node.ClearData()
 	node, _ = tree.Search(g53.NameFromStringUnsafe("q.w.y.d.e.f"))
-	node.SetData(nil)
+	node.ClearData()
 	node, _ = tree.Search(g53.NameFromStringUnsafe("w.y.d.e.f"))
 	ut.Assert(t, tree.IsNodeNonTerminal(node) == false, "")
 	node, _ = tree.Search(g53.NameFromStringUnsafe("d.e.f"))
@@ -479,7 +479,7 @@ func removeRandDomain(tree *DomainTree) error {
 		node, err := tree.Insert(name)
 		if err == nil {
 			names = append(names, name)
-			node.data = i
+			node.SetData(i)
 		}
 	}
 
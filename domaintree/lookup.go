@@ -0,0 +1,126 @@
+package domaintree
+
+import (
+	"github.com/zdnscloud/g53"
+)
+
+var wildcardLabel = g53.NameFromStringUnsafe("*")
+
+// TypedLookupResult is the answer of Lookup, a name exists if it has data or
+// any of its descendants has data, the later one is an empty non-terminal
+//
+// Result is ExactMatch if the name exists, PartialMatch if the name
+// doesn't exist but one of its ancestors exists, otherwise NotFound
+type TypedLookupResult[T any] struct {
+	Result             SearchResult
+	Node               *TypedNode[T]
+	IsEmptyNonTerminal bool
+
+	// the longest existing ancestor of the name, or the name itself if it
+	// exists, ClosestEncloserNode is nil if the closest encloser is an
+	// empty non-terminal which is part of a node name
+	ClosestEncloser     *g53.Name
+	ClosestEncloserNode *TypedNode[T]
+
+	// the source of synthesis *.closest-encloser, only set when the name
+	// doesn't exist
+	Wildcard     *TypedNode[T]
+	WildcardName *g53.Name
+
+	// the greatest name with data which is less than the name in dnssec
+	// canonical order, nil if no such name, which means the name is
+	// covered by the last name in the tree
+	Previous     *TypedNode[T]
+	PreviousName *g53.Name
+}
+
+type LookupResult = TypedLookupResult[interface{}]
+
+// Lookup searches name with the knowledge of wildcard and empty
+// non-terminal, node without data is treated as non-existent no matter
+// the tree returns empty node or not
+func (tree *TypedDomainTree[T]) Lookup(name *g53.Name) *TypedLookupResult[T] {
+	result := &TypedLookupResult[T]{Result: NotFound}
+	chain := NewTypedNodeChain[T]()
+	encloserLevel := 0
+
+	node := tree.root
+	var lastNode *TypedNode[T]
+	lastOrder := 0
+	for node != nil {
+		comparison := name.Compare(node.name, false)
+		lastNode, lastOrder = node, comparison.Order
+		if comparison.Relation == g53.EQUAL {
+			chain.push(node)
+			if tree.nodeExists(node) {
+				result.Node = node
+				result.IsEmptyNonTerminal = node.IsEmpty()
+				encloserLevel = chain.GetLevelCount()
+			}
+			break
+		}
+
+		if comparison.CommonLabelCount == 1 && node.name.IsRoot() == false {
+			if comparison.Order < 0 {
+				node = node.left
+			} else {
+				node = node.right
+			}
+			continue
+		}
+
+		if comparison.Relation == g53.SUBDOMAIN {
+			chain.push(node)
+			if tree.nodeExists(node) {
+				encloserLevel = chain.GetLevelCount()
+			}
+			name, _ = name.Subtract(node.name)
+			node = node.down
+			continue
+		}
+
+		// name shares more than root label with node, since name isn't
+		// subdomain of node, the common part is an empty non-terminal
+		// and it's the deepest ancestor of name could exist
+		if tree.nodeExists(node) {
+			common := comparison.CommonLabelCount
+			encloser, _ := name.Split(name.LabelCount()-uint(common), uint(common))
+			if chain.IsEmpty() == false {
+				encloser, _ = encloser.Concat(chain.GetAbsoluteName())
+			}
+			result.ClosestEncloser = encloser
+			if comparison.Relation == g53.SUPERDOMAIN {
+				result.IsEmptyNonTerminal = true
+			}
+		}
+		break
+	}
+
+	if result.ClosestEncloser == nil && encloserLevel > 0 {
+		result.ClosestEncloserNode = chain.nodes[encloserLevel-1]
+		result.ClosestEncloser = absoluteName(chain.nodes[:encloserLevel])
+	}
+
+	if result.Node != nil || result.IsEmptyNonTerminal {
+		result.Result = ExactMatch
+	} else if result.ClosestEncloser != nil {
+		result.Result = PartialMatch
+		result.WildcardName, _ = wildcardLabel.Concat(result.ClosestEncloser)
+		if wildcard, ret := tree.Search(result.WildcardName); ret == ExactMatch && wildcard.IsEmpty() == false {
+			result.Wildcard = wildcard
+		}
+	}
+
+	if lastNode != nil {
+		isNotEmpty := func(n *TypedNode[T]) bool { return n.IsEmpty() == false }
+		if previous := tree.predecessor(chain, lastNode, lastOrder, isNotEmpty); previous != nil {
+			result.Previous = previous
+			result.PreviousName = chain.GetAbsoluteName()
+		}
+	}
+	return result
+}
+
+func (tree *TypedDomainTree[T]) nodeExists(node *TypedNode[T]) bool {
+	return node.IsEmpty() == false || tree.IsNodeNonTerminal(node)
+}
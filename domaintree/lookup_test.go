@@ -0,0 +1,91 @@
+package domaintree
+
+import (
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+// zone in RFC 4592 2.2.1
+func createWildcardTree() *DomainTree {
+	names := []string{
+		"example", "*.example", "host1.example", "_ssh._tcp.host1.example",
+		"_ssh._tcp.host2.example", "subdel.example",
+	}
+
+	tree := NewDomainTree(false)
+	for i, n := range names {
+		node, _ := treeInsertString(tree, n)
+		node.SetData(i + 1)
+	}
+	return tree
+}
+
+func nameToString(name *g53.Name) string {
+	if name == nil {
+		return ""
+	}
+	return name.String(true)
+}
+
+func TestLookup(t *testing.T) {
+	tree := createWildcardTree()
+	cases := []struct {
+		name            string
+		result          SearchResult
+		isENT           bool
+		closestEncloser string
+		hasEncloserNode bool
+		wildcard        string
+		previous        string
+	}{
+		{"example", ExactMatch, false, "example", true, "", ""},
+		{"host1.example", ExactMatch, false, "host1.example", true, "", "*.example"},
+		{"_tcp.host1.example", ExactMatch, true, "_tcp.host1.example", false, "", "host1.example"},
+		{"host2.example", ExactMatch, true, "host2.example", false, "", "_ssh._tcp.host1.example"},
+		{"host3.example", PartialMatch, false, "example", true, "*.example", "_ssh._tcp.host2.example"},
+		{"_foo.host2.example", PartialMatch, false, "host2.example", false, "", "_ssh._tcp.host1.example"},
+		{"_foo._tcp.host1.example", PartialMatch, false, "_tcp.host1.example", false, "", "host1.example"},
+		{"a.subdel.example", PartialMatch, false, "subdel.example", true, "", "subdel.example"},
+		{"zzz.example", PartialMatch, false, "example", true, "*.example", "subdel.example"},
+		{"a.example", PartialMatch, false, "example", true, "*.example", "*.example"},
+		{"other.org", NotFound, false, "", false, "", "subdel.example"},
+		{"com", NotFound, false, "", false, "", ""},
+	}
+
+	for _, c := range cases {
+		result := tree.Lookup(g53.NameFromStringUnsafe(c.name))
+		ut.Equal(t, result.Result, c.result)
+		ut.Equal(t, result.IsEmptyNonTerminal, c.isENT)
+		ut.Equal(t, nameToString(result.ClosestEncloser), c.closestEncloser)
+		ut.Equal(t, result.ClosestEncloserNode != nil, c.hasEncloserNode)
+		ut.Equal(t, result.Wildcard != nil, c.wildcard != "")
+		if c.wildcard != "" {
+			ut.Equal(t, nameToString(result.WildcardName), c.wildcard)
+			ut.Equal(t, result.Wildcard.Data(), 2)
+		}
+		ut.Equal(t, nameToString(result.PreviousName), c.previous)
+		ut.Equal(t, result.Previous != nil, c.previous != "")
+		if c.result == ExactMatch && c.isENT == false {
+			ut.Assert(t, result.Node.IsEmpty() == false, "exact match node should have data")
+		}
+	}
+}
+
+func TestLookupRemovedName(t *testing.T) {
+	tree := createWildcardTree()
+	tree.Remove(g53.NameFromStringUnsafe("_ssh._tcp.host1.example"))
+
+	//_tcp.host1.example has no data below it anymore
+	result := tree.Lookup(g53.NameFromStringUnsafe("_tcp.host1.example"))
+	ut.Equal(t, result.Result, PartialMatch)
+	ut.Equal(t, result.ClosestEncloser.String(true), "host1.example")
+	ut.Equal(t, result.PreviousName.String(true), "host1.example")
+
+	tree.Remove(g53.NameFromStringUnsafe("example"))
+	result = tree.Lookup(g53.NameFromStringUnsafe("example"))
+	ut.Equal(t, result.Result, ExactMatch)
+	ut.Equal(t, result.IsEmptyNonTerminal, true)
+	ut.Assert(t, result.Previous == nil, "nothing is less than example")
+}
@@ -0,0 +1,240 @@
+package domaintree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zdnscloud/g53"
+)
+
+var (
+	ErrRuleNotFound = errors.New("rule doesn't exist")
+	ErrEmptyPattern = errors.New("empty domain pattern")
+)
+
+type MatchType int
+
+const (
+	// "example.com" matches example.com only
+	MatchExact MatchType = 0
+	// "*.example.com" matches subdomains of example.com
+	MatchSubdomain MatchType = 1
+	// ".example.com" matches example.com and its subdomains
+	MatchSuffix MatchType = 2
+)
+
+const matchTypeCount = 3
+
+var matchTypePrefix = [matchTypeCount]string{"", "*.", "."}
+
+// Rule binds a policy to a domain pattern, exception rule stops rules of
+// ancestors from applying to names it matches
+type Rule[P any] struct {
+	Name      *g53.Name
+	Type      MatchType
+	Exception bool
+	Policy    P
+}
+
+func (r *Rule[P]) String() string {
+	pattern := matchTypePrefix[r.Type] + r.Name.String(true)
+	if r.Exception {
+		pattern = "!" + pattern
+	}
+	return pattern
+}
+
+// ParsePattern parses pattern in the format of "example.com",
+// "*.example.com" or ".example.com", with optional leading "!" for
+// exception, "." is the root, "*.." and ".." are patterns for root
+func ParsePattern(pattern string) (name *g53.Name, typ MatchType, exception bool, err error) {
+	pattern = strings.TrimSpace(pattern)
+	if strings.HasPrefix(pattern, "!") {
+		exception = true
+		pattern = pattern[1:]
+	}
+
+	typ = MatchExact
+	if strings.HasPrefix(pattern, "*.") {
+		typ = MatchSubdomain
+		pattern = pattern[2:]
+	} else if len(pattern) > 1 && pattern[0] == '.' {
+		typ = MatchSuffix
+		pattern = pattern[1:]
+	}
+
+	if pattern == "" {
+		err = ErrEmptyPattern
+		return
+	}
+
+	name, err = g53.NewName(pattern, true)
+	return
+}
+
+// rules with same name are saved in one node, indexed by match type
+type matchEntry[P any] struct {
+	rules [matchTypeCount]*Rule[P]
+}
+
+func (e *matchEntry[P]) isEmpty() bool {
+	return e.rules[MatchExact] == nil && e.rules[MatchSubdomain] == nil && e.rules[MatchSuffix] == nil
+}
+
+// subdomain rule is more specific than suffix rule
+func (e *matchEntry[P]) match(isSelf bool) *Rule[P] {
+	if isSelf {
+		if e.rules[MatchExact] != nil {
+			return e.rules[MatchExact]
+		}
+	} else if e.rules[MatchSubdomain] != nil {
+		return e.rules[MatchSubdomain]
+	}
+	return e.rules[MatchSuffix]
+}
+
+// Matcher finds the rule of the closest name for a domain, the name
+// itself is tried first, then its ancestors from the nearest one.
+// Nodes with rules for subdomains are flagged with NF_CALLBACK, so one
+// search collects all the candidates. Matcher isn't goroutine safe for
+// modification, build a new one and swap it for reload
+type Matcher[P any] struct {
+	tree      *TypedDomainTree[*matchEntry[P]]
+	ruleCount int
+}
+
+func NewMatcher[P any]() *Matcher[P] {
+	return &Matcher[P]{
+		tree: NewTypedDomainTree[*matchEntry[P]](false),
+	}
+}
+
+func (m *Matcher[P]) RuleCount() int {
+	return m.ruleCount
+}
+
+// Add adds pattern with policy, existing rule with the same pattern is
+// replaced
+func (m *Matcher[P]) Add(pattern string, policy P) error {
+	name, typ, exception, err := ParsePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	m.AddRule(&Rule[P]{
+		Name:      name,
+		Type:      typ,
+		Exception: exception,
+		Policy:    policy,
+	})
+	return nil
+}
+
+func (m *Matcher[P]) AddRule(rule *Rule[P]) {
+	node, _ := m.tree.Insert(rule.Name)
+	entry := node.Data()
+	if entry == nil {
+		entry = &matchEntry[P]{}
+		node.SetData(entry)
+	}
+
+	if entry.rules[rule.Type] == nil {
+		m.ruleCount += 1
+	}
+	entry.rules[rule.Type] = rule
+	if entry.rules[MatchSubdomain] != nil || entry.rules[MatchSuffix] != nil {
+		node.SetFlag(NF_CALLBACK, true)
+	}
+}
+
+// Load adds one pattern per line with the same policy, empty line and
+// line starts with "#" are ignored
+func (m *Matcher[P]) Load(r io.Reader, policy P) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum += 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if err := m.Add(line, policy); err != nil {
+			return fmt.Errorf("line %d: %s is invalid: %s", lineNum, line, err.Error())
+		}
+	}
+	return scanner.Err()
+}
+
+func (m *Matcher[P]) Remove(pattern string) error {
+	name, typ, _, err := ParsePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	node, ret := m.tree.Search(name)
+	if ret != ExactMatch || node.Data().rules[typ] == nil {
+		return ErrRuleNotFound
+	}
+
+	entry := node.Data()
+	entry.rules[typ] = nil
+	m.ruleCount -= 1
+	if entry.isEmpty() {
+		return m.tree.Remove(name)
+	}
+	if entry.rules[MatchSubdomain] == nil && entry.rules[MatchSuffix] == nil {
+		node.SetFlag(NF_CALLBACK, false)
+	}
+	return nil
+}
+
+type matchState[P any] struct {
+	rule *Rule[P]
+}
+
+// ancestors are visited from top to bottom, so the last one wins
+func matchAncestor[P any](node *TypedNode[*matchEntry[P]], params interface{}) bool {
+	if rule := node.Data().match(false); rule != nil {
+		params.(*matchState[P]).rule = rule
+	}
+	return false
+}
+
+// Match returns the rule applies to name, nil if there is no such rule,
+// the returned rule may be an exception
+func (m *Matcher[P]) Match(name *g53.Name) *Rule[P] {
+	var state matchState[P]
+	nodePath := NewTypedNodeChain[*matchEntry[P]]()
+	node, ret := m.tree.SearchExt(name, nodePath, matchAncestor[P], &state)
+	if ret == ExactMatch {
+		if rule := node.Data().match(true); rule != nil {
+			return rule
+		}
+	}
+	return state.rule
+}
+
+// Policy returns the policy applies to name, false is returned if no
+// rule or an exception rule matches
+func (m *Matcher[P]) Policy(name *g53.Name) (P, bool) {
+	if rule := m.Match(name); rule != nil && rule.Exception == false {
+		return rule.Policy, true
+	}
+	var zero P
+	return zero, false
+}
+
+// ForEach visits all the rules, iteration stops when fn returns false
+func (m *Matcher[P]) ForEach(fn func(*Rule[P]) bool) {
+	m.tree.All(func(node *TypedNode[*matchEntry[P]]) bool {
+		for _, rule := range node.Data().rules {
+			if rule != nil && fn(rule) == false {
+				return false
+			}
+		}
+		return true
+	})
+}
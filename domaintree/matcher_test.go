@@ -0,0 +1,159 @@
+package domaintree
+
+import (
+	"strings"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+const testBlocklist = `
+# ads
+ads.example.com
+*.tracker.example.com
+.evil.com
+!good.evil.com
+.example.net
+!*.cdn.example.net
+`
+
+func TestParsePattern(t *testing.T) {
+	for _, c := range []struct {
+		pattern   string
+		name      string
+		typ       MatchType
+		exception bool
+	}{
+		{"example.com", "example.com", MatchExact, false},
+		{"*.example.com", "example.com", MatchSubdomain, false},
+		{".Example.com", "example.com", MatchSuffix, false},
+		{"!.example.com", "example.com", MatchSuffix, true},
+		{".", ".", MatchExact, false},
+		{"..", ".", MatchSuffix, false},
+	} {
+		name, typ, exception, err := ParsePattern(c.pattern)
+		ut.Equal(t, err, nil)
+		ut.Equal(t, name.String(true), c.name)
+		ut.Equal(t, typ, c.typ)
+		ut.Equal(t, exception, c.exception)
+		rule := &Rule[int]{Name: name, Type: typ, Exception: exception}
+		ut.Equal(t, rule.String(), strings.ToLower(c.pattern))
+	}
+
+	_, _, _, err := ParsePattern("*.")
+	ut.Equal(t, err, ErrEmptyPattern)
+	_, _, _, err = ParsePattern("a..b")
+	ut.Assert(t, err != nil, "invalid name should fail")
+}
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher[string]()
+	ut.Equal(t, m.Load(strings.NewReader(testBlocklist), "block"), nil)
+	ut.Equal(t, m.RuleCount(), 6)
+
+	for _, c := range []struct {
+		name   string
+		rule   string
+		policy string
+	}{
+		{"ads.example.com", "ads.example.com", "block"},
+		{"www.ads.example.com", "", ""},
+		{"tracker.example.com", "", ""},
+		{"a.b.tracker.example.com", "*.tracker.example.com", "block"},
+		{"evil.com", ".evil.com", "block"},
+		{"www.evil.com", ".evil.com", "block"},
+		{"good.evil.com", "!good.evil.com", ""},
+		{"www.good.evil.com", ".evil.com", "block"},
+		{"cdn.example.net", ".example.net", "block"},
+		{"img.cdn.example.net", "!*.cdn.example.net", ""},
+		{"example.com", "", ""},
+		{"com", "", ""},
+	} {
+		rule := m.Match(g53.NameFromStringUnsafe(c.name))
+		if c.rule == "" {
+			ut.Assert(t, rule == nil, "%s shouldn't match any rule", c.name)
+		} else {
+			ut.Equal(t, rule.String(), c.rule)
+		}
+		policy, ok := m.Policy(g53.NameFromStringUnsafe(c.name))
+		ut.Equal(t, ok, c.policy != "")
+		ut.Equal(t, policy, c.policy)
+	}
+}
+
+func TestMatcherRuleOnSameName(t *testing.T) {
+	m := NewMatcher[int]()
+	ut.Equal(t, m.Add("example.com", 1), nil)
+	ut.Equal(t, m.Add("*.example.com", 2), nil)
+	ut.Equal(t, m.Add(".example.com", 3), nil)
+	ut.Equal(t, m.Add("*.example.com", 4), nil)
+	ut.Equal(t, m.RuleCount(), 3)
+
+	policy, _ := m.Policy(g53.NameFromStringUnsafe("example.com"))
+	ut.Equal(t, policy, 1)
+	policy, _ = m.Policy(g53.NameFromStringUnsafe("www.example.com"))
+	ut.Equal(t, policy, 4)
+
+	ut.Equal(t, m.Remove("example.com"), nil)
+	policy, _ = m.Policy(g53.NameFromStringUnsafe("example.com"))
+	ut.Equal(t, policy, 3)
+	ut.Equal(t, m.Remove("*.example.com"), nil)
+	policy, _ = m.Policy(g53.NameFromStringUnsafe("www.example.com"))
+	ut.Equal(t, policy, 3)
+	ut.Equal(t, m.Remove("*.example.com"), ErrRuleNotFound)
+
+	ut.Equal(t, m.Remove(".example.com"), nil)
+	_, ok := m.Policy(g53.NameFromStringUnsafe("www.example.com"))
+	ut.Equal(t, ok, false)
+	ut.Equal(t, m.RuleCount(), 0)
+	ut.Equal(t, m.tree.NodeCount(), 0)
+}
+
+func TestMatcherNestedRules(t *testing.T) {
+	m := NewMatcher[int]()
+	m.Add("..", 1)
+	m.Add(".cn", 2)
+	m.Add("*.knet.cn", 3)
+	m.Add("www.knet.cn", 4)
+
+	var rules []string
+	m.ForEach(func(r *Rule[int]) bool {
+		rules = append(rules, r.String())
+		return true
+	})
+	ut.Equal(t, len(rules), 4)
+
+	for name, policy := range map[string]int{
+		"com":             1,
+		"cn":              2,
+		"knet.cn":         2,
+		"a.knet.cn":       3,
+		"www.knet.cn":     4,
+		"a.www.knet.cn":   3,
+		"a.b.c.d.knet.cn": 3,
+	} {
+		p, ok := m.Policy(g53.NameFromStringUnsafe(name))
+		ut.Assert(t, ok, "%s should match", name)
+		ut.Equal(t, p, policy)
+	}
+
+	err := m.Load(strings.NewReader("a.cn\nb..cn\n"), 5)
+	ut.Assert(t, err != nil && strings.HasPrefix(err.Error(), "line 2:"), "load should report the bad line")
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	m := NewMatcher[int]()
+	var names []*g53.Name
+	for i := 0; i < 100000; i++ {
+		name := g53.NameFromStringUnsafe(g53.RandomNoneFQDNDomain())
+		names = append(names, name)
+		m.AddRule(&Rule[int]{Name: name, Type: MatchType(i % matchTypeCount), Policy: i})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(names[i%len(names)])
+	}
+}
@@ -0,0 +1,228 @@
+package domaintree
+
+import (
+	"github.com/zdnscloud/g53"
+)
+
+func (tree *TypedDomainTree[T]) isVisible(node *TypedNode[T]) bool {
+	return tree.returnEmptyNode || node.IsEmpty() == false
+}
+
+// First moves node path to the least node in canonical order, nil is
+// returned if tree has no visible node
+func (tree *TypedDomainTree[T]) First(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	nodePath.clear()
+	if tree.root == nil {
+		return nil
+	}
+
+	node := tree.root
+	for node.left != nil {
+		node = node.left
+	}
+	nodePath.push(node)
+	if tree.isVisible(node) {
+		return node
+	}
+	return tree.Next(nodePath)
+}
+
+// Last moves node path to the greatest node in canonical order
+func (tree *TypedDomainTree[T]) Last(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	nodePath.clear()
+	if tree.root == nil {
+		return nil
+	}
+
+	node := tree.root
+	for node.right != nil {
+		node = node.right
+	}
+	nodePath.push(node)
+	node = tree.lastDescendant(nodePath)
+	if tree.isVisible(node) {
+		return node
+	}
+	return tree.Prev(nodePath)
+}
+
+// Next moves node path to the next node in canonical order, node path
+// should point to a node which is returned by search or navigation, nil
+// is returned when the end is reached
+func (tree *TypedDomainTree[T]) Next(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	node := tree.nextNode(nodePath)
+	for node != nil && tree.isVisible(node) == false {
+		node = tree.nextNode(nodePath)
+	}
+	return node
+}
+
+// Prev is the reverse of Next
+func (tree *TypedDomainTree[T]) Prev(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	node := tree.prevNode(nodePath)
+	for node != nil && tree.isVisible(node) == false {
+		node = tree.prevNode(nodePath)
+	}
+	return node
+}
+
+// SearchPredecessor returns the greatest node whose name is less than
+// or equal to name, node path points to the returned node, so Next and
+// Prev could continue from it
+func (tree *TypedDomainTree[T]) SearchPredecessor(name *g53.Name, nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	node, ret := tree.SearchExt(name, nodePath, nil, nil)
+	if ret == ExactMatch {
+		return node
+	}
+
+	if nodePath.lastCompared == nil {
+		return nil
+	}
+	return tree.predecessor(nodePath, nodePath.lastCompared, nodePath.lastComparison.Order, tree.isVisible)
+}
+
+// ForEachInRange visits nodes whose name is between from and to in
+// canonical order, both ends are included, nil from or to means no
+// limit on that end, iteration stops when fn returns false
+func (tree *TypedDomainTree[T]) ForEachInRange(from, to *g53.Name, fn func(*g53.Name, *TypedNode[T]) bool) {
+	nodePath := NewTypedNodeChain[T]()
+	var node *TypedNode[T]
+	if from == nil {
+		node = tree.First(nodePath)
+	} else {
+		node = tree.SearchPredecessor(from, nodePath)
+		if node == nil {
+			node = tree.First(nodePath)
+		} else if nodePath.GetAbsoluteName().Equals(from) == false {
+			node = tree.Next(nodePath)
+		}
+	}
+
+	for node != nil {
+		name := nodePath.GetAbsoluteName()
+		if to != nil && name.Compare(to, false).Order > 0 {
+			break
+		}
+		if fn(name, node) == false {
+			break
+		}
+		node = tree.Next(nodePath)
+	}
+}
+
+// the search stopped at lastNode with lastOrder, node path holds the
+// upper nodes, find the greatest node less than searched name or equal
+// to it if search stopped at the exact node
+func (tree *TypedDomainTree[T]) predecessor(nodePath *TypedNodeChain[T], lastNode *TypedNode[T], lastOrder int, isVisible func(*TypedNode[T]) bool) *TypedNode[T] {
+	if nodePath.IsEmpty() || nodePath.Top() != lastNode {
+		nodePath.push(lastNode)
+	}
+
+	var node *TypedNode[T]
+	if lastOrder > 0 {
+		node = tree.lastDescendant(nodePath)
+	} else {
+		node = tree.prevNode(nodePath)
+	}
+
+	for node != nil && isVisible(node) == false {
+		node = tree.prevNode(nodePath)
+	}
+	return node
+}
+
+// move to the last node in canonical order in the subtree of the top
+// node of node path
+func (tree *TypedDomainTree[T]) lastDescendant(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	node := nodePath.Top()
+	for node.down != nil {
+		node = node.down
+		for node.right != nil {
+			node = node.right
+		}
+		nodePath.push(node)
+	}
+	return node
+}
+
+func (tree *TypedDomainTree[T]) prevNode(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	if nodePath.IsEmpty() {
+		panic("prev node is given a empty node path")
+	}
+
+	node := nodePath.Top()
+	nodePath.Pop()
+	predecessor := tree.levelPredecessor(nodePath, node)
+	if predecessor != nil {
+		nodePath.push(predecessor)
+		return tree.lastDescendant(nodePath)
+	}
+
+	// the upper node is less than all the nodes in its sub tree
+	if nodePath.IsEmpty() {
+		return nil
+	}
+	return nodePath.Top()
+}
+
+// node of read only tree has no parent, so neighbors in the same level
+// are searched from the root of the level, node path holds the upper
+// nodes of the level
+func (tree *TypedDomainTree[T]) levelSuccessor(nodePath *TypedNodeChain[T], node *TypedNode[T]) *TypedNode[T] {
+	if tree.readOnly == false {
+		return node.successor()
+	}
+
+	if node.right != nil {
+		successor := node.right
+		for successor.left != nil {
+			successor = successor.left
+		}
+		return successor
+	}
+
+	var successor *TypedNode[T]
+	current := tree.levelRoot(nodePath)
+	for current != node && current != nil {
+		if node.name.Compare(current.name, false).Order < 0 {
+			successor = current
+			current = current.left
+		} else {
+			current = current.right
+		}
+	}
+	return successor
+}
+
+func (tree *TypedDomainTree[T]) levelPredecessor(nodePath *TypedNodeChain[T], node *TypedNode[T]) *TypedNode[T] {
+	if tree.readOnly == false {
+		return node.predecessor()
+	}
+
+	if node.left != nil {
+		predecessor := node.left
+		for predecessor.right != nil {
+			predecessor = predecessor.right
+		}
+		return predecessor
+	}
+
+	var predecessor *TypedNode[T]
+	current := tree.levelRoot(nodePath)
+	for current != node && current != nil {
+		if node.name.Compare(current.name, false).Order < 0 {
+			current = current.left
+		} else {
+			predecessor = current
+			current = current.right
+		}
+	}
+	return predecessor
+}
+
+func (tree *TypedDomainTree[T]) levelRoot(nodePath *TypedNodeChain[T]) *TypedNode[T] {
+	if nodePath.IsEmpty() {
+		return tree.root
+	}
+	return nodePath.Top().down
+}
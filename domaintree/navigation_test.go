@@ -0,0 +1,163 @@
+package domaintree
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+// visible names of createDomainTree(false) in canonical order
+var canonicalNames = []string{
+	"a", "b", "c", "x.d.e.f", "o.w.y.d.e.f", "p.w.y.d.e.f", "q.w.y.d.e.f",
+	"z.d.e.f", "j.z.d.e.f", "g.h", "i.g.h"}
+
+func TestTreeNextPrev(t *testing.T) {
+	tree := createDomainTree(false)
+	nodePath := NewNodeChain()
+	var names []string
+	for node := tree.First(nodePath); node != nil; node = tree.Next(nodePath) {
+		names = append(names, nodePath.GetAbsoluteName().String(true))
+	}
+	ut.Equal(t, names, canonicalNames)
+
+	names = names[:0]
+	for node := tree.Last(nodePath); node != nil; node = tree.Prev(nodePath) {
+		names = append([]string{nodePath.GetAbsoluteName().String(true)}, names...)
+	}
+	ut.Equal(t, names, canonicalNames)
+
+	emptyTree := NewDomainTree(false)
+	ut.Assert(t, emptyTree.First(nodePath) == nil, "empty tree has no first node")
+	ut.Assert(t, emptyTree.Last(nodePath) == nil, "empty tree has no last node")
+}
+
+func TestTreeNextPrevWithEmptyNode(t *testing.T) {
+	tree := createDomainTree(true)
+	nodePath := NewNodeChain()
+	node, ret := tree.SearchExt(g53.NameFromStringUnsafe("c"), nodePath, nil, nil)
+	ut.Equal(t, ret, ExactMatch)
+	node = tree.Next(nodePath)
+	ut.Equal(t, nodePath.GetAbsoluteName().String(true), "d.e.f")
+	ut.Assert(t, node.IsEmpty(), "d.e.f is empty node")
+	tree.Prev(nodePath)
+	ut.Equal(t, nodePath.GetAbsoluteName().String(true), "c")
+}
+
+func TestSearchPredecessor(t *testing.T) {
+	tree := createDomainTree(false)
+	cases := []struct {
+		name        string
+		predecessor string
+	}{
+		{"a", "a"},
+		{"bb", "b"},
+		{"d.e.f", "c"},
+		{"y.d.e.f", "x.d.e.f"},
+		{"r.w.y.d.e.f", "q.w.y.d.e.f"},
+		{"zz.d.e.f", "j.z.d.e.f"},
+		{"k.z.d.e.f", "j.z.d.e.f"},
+		{"e.f", "c"},
+		{"zzz", "i.g.h"},
+		{"0", ""},
+	}
+
+	for _, c := range cases {
+		nodePath := NewNodeChain()
+		node := tree.SearchPredecessor(g53.NameFromStringUnsafe(c.name), nodePath)
+		if c.predecessor == "" {
+			ut.Assert(t, node == nil, "%s has no predecessor", c.name)
+		} else {
+			ut.Assert(t, node != nil, "%s should have predecessor", c.name)
+			ut.Equal(t, nodePath.GetAbsoluteName().String(true), c.predecessor)
+		}
+	}
+
+	nodePath := NewNodeChain()
+	tree.SearchPredecessor(g53.NameFromStringUnsafe("y.d.e.f"), nodePath)
+	tree.Next(nodePath)
+	ut.Equal(t, nodePath.GetAbsoluteName().String(true), "o.w.y.d.e.f")
+}
+
+func TestForEachInRange(t *testing.T) {
+	tree := createDomainTree(false)
+	rangeNames := func(from, to string) []string {
+		var fromName, toName *g53.Name
+		if from != "" {
+			fromName = g53.NameFromStringUnsafe(from)
+		}
+		if to != "" {
+			toName = g53.NameFromStringUnsafe(to)
+		}
+		var names []string
+		tree.ForEachInRange(fromName, toName, func(name *g53.Name, node *Node) bool {
+			names = append(names, name.String(true))
+			return true
+		})
+		return names
+	}
+
+	ut.Equal(t, rangeNames("", ""), canonicalNames)
+	ut.Equal(t, rangeNames("b", "p.w.y.d.e.f"), canonicalNames[1:6])
+	ut.Equal(t, rangeNames("bb", "y.d.e.f"), []string{"c", "x.d.e.f"})
+	ut.Equal(t, rangeNames("0", "a"), []string{"a"})
+	ut.Equal(t, rangeNames("z.d.e.f", ""), canonicalNames[7:])
+	ut.Equal(t, len(rangeNames("zzz", "")), 0)
+
+	count := 0
+	tree.ForEachInRange(nil, nil, func(name *g53.Name, node *Node) bool {
+		count += 1
+		return count < 3
+	})
+	ut.Equal(t, count, 3)
+}
+
+// names with few kinds of labels, so they share many ancestors
+func randomHierarchicalName() *g53.Name {
+	labels := []string{"a", "b", "c", "d"}
+	name := make([]string, 1+rand.Intn(5))
+	for i := range name {
+		name[i] = labels[rand.Intn(len(labels))]
+	}
+	return g53.NameFromStringUnsafe(strings.Join(name, "."))
+}
+
+func TestNavigationRandomDomain(t *testing.T) {
+	tree := NewDomainTree(false)
+	var names []*g53.Name
+	for i := 0; i < 500; i++ {
+		name := randomHierarchicalName()
+		if node, err := tree.Insert(name); err == nil {
+			node.SetData(i)
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].Compare(names[j], false).Order < 0
+	})
+
+	nodePath := NewNodeChain()
+	i := 0
+	for node := tree.First(nodePath); node != nil; node = tree.Next(nodePath) {
+		ut.Assert(t, nodePath.GetAbsoluteName().Equals(names[i]), "next node isn't in canonical order")
+		i += 1
+	}
+	ut.Equal(t, i, len(names))
+
+	for j := 0; j < 500; j++ {
+		query := randomHierarchicalName()
+		k := sort.Search(len(names), func(i int) bool {
+			return names[i].Compare(query, false).Order > 0
+		})
+		nodePath := NewNodeChain()
+		node := tree.SearchPredecessor(query, nodePath)
+		if k == 0 {
+			ut.Assert(t, node == nil, "%s has no predecessor", query.String(false))
+		} else {
+			ut.Assert(t, nodePath.GetAbsoluteName().Equals(names[k-1]), "predecessor of %s is wrong", query.String(false))
+		}
+	}
+}
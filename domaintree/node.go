@@ -26,57 +26,61 @@ func (color RBNodeColor) String() string {
 	}
 }
 
-type Node struct {
-	parent *Node
-	left   *Node
-	right  *Node
+// TypedNode holds data of type T, node is empty until data is set, so
+// the zero value of T is a valid data
+type TypedNode[T any] struct {
+	parent *TypedNode[T]
+	left   *TypedNode[T]
+	right  *TypedNode[T]
 	color  RBNodeColor
 
-	down *Node
-	flag RBNodeFlag
-	name *g53.Name
-	data interface{}
+	down    *TypedNode[T]
+	flag    RBNodeFlag
+	name    *g53.Name
+	data    T
+	hasData bool
+
+	//transaction which creates the node, only used by CowDomainTree
+	txnID uint64
 }
 
+type Node = TypedNode[interface{}]
+
+// NULL_NODE is kept for compatibility, missing child, parent or down
+// node is nil
 var NULL_NODE *Node
 
-func init() {
-	if NULL_NODE == nil {
-		NULL_NODE = &Node{
-			color: BLACK,
-			name:  g53.Root,
-		}
-		NULL_NODE.parent = NULL_NODE
-		NULL_NODE.left = NULL_NODE
-		NULL_NODE.right = NULL_NODE
-		NULL_NODE.down = NULL_NODE
-	}
+func NewNode(name *g53.Name) *Node {
+	return NewTypedNode[interface{}](name)
 }
 
-func NewNode(name *g53.Name) *Node {
-	return &Node{
-		parent: NULL_NODE,
-		left:   NULL_NODE,
-		right:  NULL_NODE,
-		color:  RED,
-		down:   NULL_NODE,
-		name:   name,
+func NewTypedNode[T any](name *g53.Name) *TypedNode[T] {
+	return &TypedNode[T]{
+		color: RED,
+		name:  name,
 	}
 }
 
-func (node *Node) IsEmpty() bool {
-	return node.data == nil
+func (node *TypedNode[T]) IsEmpty() bool {
+	return node.hasData == false
 }
 
-func (node *Node) IsLeaf() bool {
-	return node.down == NULL_NODE
+// ClearData makes node empty
+func (node *TypedNode[T]) ClearData() {
+	var zero T
+	node.data = zero
+	node.hasData = false
 }
 
-func (node *Node) GetFlag(flag RBNodeFlag) bool {
+func (node *TypedNode[T]) IsLeaf() bool {
+	return node.down == nil
+}
+
+func (node *TypedNode[T]) GetFlag(flag RBNodeFlag) bool {
 	return (node.flag & flag) != 0
 }
 
-func (node *Node) SetFlag(flag RBNodeFlag, set bool) {
+func (node *TypedNode[T]) SetFlag(flag RBNodeFlag, set bool) {
 	if set {
 		node.flag = node.flag | flag
 	} else {
@@ -84,11 +88,11 @@ func (node *Node) SetFlag(flag RBNodeFlag, set bool) {
 	}
 }
 
-func (node *Node) successor() *Node {
+func (node *TypedNode[T]) successor() *TypedNode[T] {
 	current := node
-	if node.right != NULL_NODE {
+	if node.right != nil {
 		current = node.right
-		for current.left != NULL_NODE {
+		for current.left != nil {
 			current = current.left
 		}
 		return current
@@ -98,71 +102,94 @@ func (node *Node) successor() *Node {
 	// root.  If found, the parent of the branch is the successor.
 	// Otherwise, we return the null node
 	parent := current.parent
-	for parent != NULL_NODE && current == parent.right {
+	for parent != nil && current == parent.right {
 		current = parent
 		parent = parent.parent
 	}
 	return parent
 }
 
-func (node *Node) SetData(data interface{}) {
+func (node *TypedNode[T]) predecessor() *TypedNode[T] {
+	current := node
+	if node.left != nil {
+		current = node.left
+		for current.right != nil {
+			current = current.right
+		}
+		return current
+	}
+
+	parent := current.parent
+	for parent != nil && current == parent.left {
+		current = parent
+		parent = parent.parent
+	}
+	return parent
+}
+
+// SetData makes node non-empty even if data is zero value or nil, use
+// ClearData to make it empty
+func (node *TypedNode[T]) SetData(data T) {
 	node.data = data
+	node.hasData = true
 }
 
-func (node *Node) Data() interface{} {
+func (node *TypedNode[T]) Data() T {
 	return node.data
 }
 
-func (node *Node) Name() *g53.Name {
+func (node *TypedNode[T]) Name() *g53.Name {
 	return node.name
 }
 
-func (node *Node) Clean() {
-	if node == NULL_NODE {
+func (node *TypedNode[T]) Clean() {
+	if node == nil {
 		return
 	}
 
-	if node.left != NULL_NODE {
+	if node.left != nil {
 		node.left.Clean()
-		node.left = NULL_NODE
+		node.left = nil
 	}
 
-	if node.right != NULL_NODE {
+	if node.right != nil {
 		node.right.Clean()
-		node.right = NULL_NODE
+		node.right = nil
 	}
 
-	if node.down != NULL_NODE {
+	if node.down != nil {
 		node.down.Clean()
-		node.down = NULL_NODE
+		node.down = nil
 	}
 
-	node.parent = NULL_NODE
+	node.parent = nil
 	node.name = nil
-	node.data = nil
+	node.ClearData()
 }
 
-type ValueCloneFunc func(interface{}) interface{}
+type TypedValueCloneFunc[T any] func(T) T
+
+type ValueCloneFunc = TypedValueCloneFunc[interface{}]
 
 func DefaultValueCloneFunc(v interface{}) interface{} {
 	return v
 }
 
-func (n *Node) Clone(valueConeFunc ValueCloneFunc) *Node {
-	if n == NULL_NODE {
-		return NULL_NODE
+func (n *TypedNode[T]) Clone(valueConeFunc TypedValueCloneFunc[T]) *TypedNode[T] {
+	if n == nil {
+		return nil
 	}
 
 	new := *n
-	if new.data != nil {
+	if new.IsEmpty() == false {
 		new.data = valueConeFunc(new.data)
 	}
 	new.left = new.left.Clone(valueConeFunc)
 	new.right = new.right.Clone(valueConeFunc)
-	if new.left != NULL_NODE {
+	if new.left != nil {
 		new.left.parent = &new
 	}
-	if new.right != NULL_NODE {
+	if new.right != nil {
 		new.right.parent = &new
 	}
 	new.down = new.down.Clone(valueConeFunc)
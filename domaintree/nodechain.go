@@ -7,72 +7,82 @@ import (
 const RBT_MAX_LEVEL = g53.MAX_LABELS
 const NORMAL_TREE_DEPTH = 5
 
-type NodeChain struct {
-	nodes          []*Node
-	lastCompared   *Node
+type TypedNodeChain[T any] struct {
+	nodes          []*TypedNode[T]
+	lastCompared   *TypedNode[T]
 	lastComparison g53.NameComparisonResult
 }
 
+type NodeChain = TypedNodeChain[interface{}]
+
 func NewNodeChain() *NodeChain {
-	return &NodeChain{
-		nodes: make([]*Node, 0, NORMAL_TREE_DEPTH),
+	return NewTypedNodeChain[interface{}]()
+}
+
+func NewTypedNodeChain[T any]() *TypedNodeChain[T] {
+	return &TypedNodeChain[T]{
+		nodes: make([]*TypedNode[T], 0, NORMAL_TREE_DEPTH),
 	}
 }
 
-func (c *NodeChain) clear() {
+func (c *TypedNodeChain[T]) clear() {
 	c.nodes = c.nodes[:0]
 	c.lastCompared = nil
 }
 
-func (c *NodeChain) GetAbsoluteName() *g53.Name {
+func (c *TypedNodeChain[T]) GetAbsoluteName() *g53.Name {
 	if c.IsEmpty() {
 		panic("get name on empty node chain")
 	}
 
-	nameCount := len(c.nodes)
+	return absoluteName(c.nodes)
+}
+
+func absoluteName[T any](nodes []*TypedNode[T]) *g53.Name {
+	nameCount := len(nodes)
 	if nameCount == 1 {
-		return c.nodes[0].name
+		return nodes[0].name
 	}
 
 	names := [RBT_MAX_LEVEL]*g53.Name{}
 	i := 0
 	for j := nameCount - 1; j >= 0; j-- {
-		names[i] = c.nodes[j].name
+		names[i] = nodes[j].name
 		i += 1
 	}
 	absoluteName, _ := names[0].Concat(names[1:nameCount]...)
 	return absoluteName
 }
 
-func (c *NodeChain) IsEmpty() bool {
+func (c *TypedNodeChain[T]) IsEmpty() bool {
 	return len(c.nodes) == 0
 }
 
-func (c *NodeChain) GetLevelCount() int {
+func (c *TypedNodeChain[T]) GetLevelCount() int {
 	return len(c.nodes)
 }
 
-func (c *NodeChain) Top() *Node {
+func (c *TypedNodeChain[T]) Top() *TypedNode[T] {
 	if c.IsEmpty() {
 		panic("top on empty chain")
 	}
 	return c.nodes[len(c.nodes)-1]
 }
 
-func (c *NodeChain) Pop() {
+func (c *TypedNodeChain[T]) Pop() {
 	if c.IsEmpty() {
 		panic("pop on empty chain")
 	}
 	c.nodes = c.nodes[:(len(c.nodes) - 1)]
 }
 
-func (c *NodeChain) push(node *Node) {
+func (c *TypedNodeChain[T]) push(node *TypedNode[T]) {
 	if len(c.nodes) == RBT_MAX_LEVEL {
 		panic("too deep tree")
 	}
 	c.nodes = append(c.nodes, node)
 }
 
-func (c *NodeChain) LastComparison() g53.NameComparisonResult {
+func (c *TypedNodeChain[T]) LastComparison() g53.NameComparisonResult {
 	return c.lastComparison
 }
@@ -0,0 +1,153 @@
+package domaintree
+
+import (
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+type testZone struct {
+	name   string
+	serial int
+}
+
+func createTypedDomainTree() *TypedDomainTree[*testZone] {
+	tree := NewTypedDomainTree[*testZone](false)
+	for i, n := range []string{"cn", "knet.cn", "a.b.knet.cn", "com", "example.com"} {
+		node, _ := tree.Insert(g53.NameFromStringUnsafe(n))
+		node.SetData(&testZone{name: n, serial: i})
+	}
+	return tree
+}
+
+func TestTypedTree(t *testing.T) {
+	tree := createTypedDomainTree()
+	node, ret := tree.Search(g53.NameFromStringUnsafe("knet.cn"))
+	ut.Equal(t, ret, ExactMatch)
+	ut.Equal(t, node.Data().name, "knet.cn")
+
+	//b.knet.cn is created by fission, nil pointer means no data
+	_, ret = tree.Search(g53.NameFromStringUnsafe("b.knet.cn"))
+	ut.Equal(t, ret, PartialMatch)
+
+	node.SetFlag(NF_CALLBACK, true)
+	var visited []string
+	nodePath := NewTypedNodeChain[*testZone]()
+	node, ret = tree.SearchExt(g53.NameFromStringUnsafe("www.a.b.knet.cn"), nodePath, func(n *TypedNode[*testZone], _ interface{}) bool {
+		visited = append(visited, n.Data().name)
+		return false
+	}, nil)
+	ut.Equal(t, ret, PartialMatch)
+	ut.Equal(t, node.Data().name, "a.b.knet.cn")
+	ut.Equal(t, visited, []string{"knet.cn"})
+
+	serials := make(map[string]int)
+	tree.ForEachEx(func(name *g53.Name, n *TypedNode[*testZone]) {
+		serials[name.String(true)] = n.Data().serial
+	})
+	ut.Equal(t, serials, map[string]int{"cn": 0, "knet.cn": 1, "a.b.knet.cn": 2, "com": 3, "example.com": 4})
+
+	ut.Assert(t, tree.All(func(n *TypedNode[*testZone]) bool { return n.Data() != nil }), "visible node should have data")
+	ut.Assert(t, tree.Any(func(n *TypedNode[*testZone]) bool { return n.Data().serial == 4 }), "example.com should be found")
+
+	tree.Remove(g53.NameFromStringUnsafe("knet.cn"))
+	node, ret = tree.Search(g53.NameFromStringUnsafe("knet.cn"))
+	ut.Equal(t, ret, PartialMatch)
+	ut.Equal(t, node.Data().name, "cn")
+}
+
+func TestTypedTreeClone(t *testing.T) {
+	tree := createTypedDomainTree()
+	clone := tree.Clone(func(z *testZone) *testZone {
+		copied := *z
+		copied.serial += 10
+		return &copied
+	})
+	ut.Equal(t, clone.NodeCount(), tree.NodeCount())
+
+	origin, _ := tree.Search(g53.NameFromStringUnsafe("example.com"))
+	node, ret := clone.Search(g53.NameFromStringUnsafe("example.com"))
+	ut.Equal(t, ret, ExactMatch)
+	ut.Equal(t, node.Data().serial, 14)
+	ut.Equal(t, origin.Data().serial, 4)
+
+	shared := tree.Clone(nil)
+	node, _ = shared.Search(g53.NameFromStringUnsafe("example.com"))
+	ut.Assert(t, node.Data() == origin.Data(), "default clone should share data")
+}
+
+func TestTypedTreeValueData(t *testing.T) {
+	tree := NewTypedDomainTree[int](false)
+	node, _ := tree.Insert(g53.NameFromStringUnsafe("knet.cn"))
+	ut.Assert(t, node.IsEmpty(), "new node should be empty")
+	node.SetData(2)
+	_, err := tree.Insert(g53.NameFromStringUnsafe("knet.cn"))
+	ut.Equal(t, err, ErrAlreadyExist)
+
+	node, ret := tree.Search(g53.NameFromStringUnsafe("www.knet.cn"))
+	ut.Equal(t, ret, PartialMatch)
+	ut.Equal(t, node.Data()+1, 3)
+
+	//zero value is valid data
+	node, _ = tree.Insert(g53.NameFromStringUnsafe("a.zdns.cn"))
+	node.SetData(0)
+	ut.Assert(t, node.IsEmpty() == false, "node with zero value isn't empty")
+	_, err = tree.Insert(g53.NameFromStringUnsafe("a.zdns.cn"))
+	ut.Equal(t, err, ErrAlreadyExist)
+	//zdns.cn is split from a.zdns.cn, data should move with it
+	tree.Insert(g53.NameFromStringUnsafe("b.zdns.cn"))
+	node, ret = tree.Search(g53.NameFromStringUnsafe("a.zdns.cn"))
+	ut.Equal(t, ret, ExactMatch)
+	ut.Equal(t, node.Data(), 0)
+	_, ret = tree.Search(g53.NameFromStringUnsafe("zdns.cn"))
+	ut.Equal(t, ret, NotFound)
+
+	ut.Equal(t, tree.Remove(g53.NameFromStringUnsafe("a.zdns.cn")), nil)
+	_, ret = tree.Search(g53.NameFromStringUnsafe("a.zdns.cn"))
+	ut.Assert(t, ret != ExactMatch, "removed node should be empty")
+
+	cow := NewTypedCowDomainTree[bool](false)
+	txn := cow.Begin()
+	cowNode, _ := txn.Insert(g53.NameFromStringUnsafe("a.zdns.cn"))
+	cowNode.SetData(false)
+	txn.Insert(g53.NameFromStringUnsafe("b.zdns.cn"))
+	_, err = txn.Insert(g53.NameFromStringUnsafe("a.zdns.cn"))
+	ut.Equal(t, err, ErrAlreadyExist)
+	ut.Equal(t, txn.Commit(), nil)
+	result := cow.Snapshot().Lookup(g53.NameFromStringUnsafe("a.zdns.cn"))
+	ut.Equal(t, result.Result, ExactMatch)
+
+	//nil pointer is valid data, only ClearData makes node empty
+	ptrTree := NewTypedDomainTree[*testZone](false)
+	ptrNode, _ := ptrTree.Insert(g53.NameFromStringUnsafe("knet.cn"))
+	ptrNode.SetData(nil)
+	ut.Assert(t, ptrNode.IsEmpty() == false, "node with nil pointer isn't empty")
+	_, ret = ptrTree.Search(g53.NameFromStringUnsafe("knet.cn"))
+	ut.Equal(t, ret, ExactMatch)
+	ptrNode.ClearData()
+	ut.Assert(t, ptrNode.IsEmpty(), "cleared node should be empty")
+	_, ret = ptrTree.Search(g53.NameFromStringUnsafe("knet.cn"))
+	ut.Equal(t, ret, NotFound)
+}
+
+func TestTypedMatcherSlicePolicy(t *testing.T) {
+	rrset, _ := g53.RRsetFromString("www.knet.cn. 300 IN A 1.1.1.1")
+	m := NewMatcher[[]*g53.RRset]()
+	ut.Equal(t, m.Add("*.knet.cn", []*g53.RRset{rrset}), nil)
+	policy, ok := m.Policy(g53.NameFromStringUnsafe("www.knet.cn"))
+	ut.Assert(t, ok, "subdomain should match")
+	ut.Equal(t, len(policy), 1)
+}
+
+func TestTypedCowTree(t *testing.T) {
+	tree := NewTypedCowDomainTree[*testZone](false)
+	txn := tree.Begin()
+	node, _ := txn.Insert(g53.NameFromStringUnsafe("knet.cn"))
+	node.SetData(&testZone{name: "knet.cn"})
+	ut.Equal(t, txn.Commit(), nil)
+
+	result := tree.Snapshot().Lookup(g53.NameFromStringUnsafe("www.knet.cn"))
+	ut.Equal(t, result.Result, PartialMatch)
+	ut.Equal(t, result.ClosestEncloserNode.Data().name, "knet.cn")
+}
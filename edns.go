@@ -89,15 +89,17 @@ func EdnsFromRRset(rrset *RRset) *EDNS {
 	version := uint8((flags & VERSION_MASK) >> VERSION_SHIFT)
 
 	opts := []Option{}
-	if len(rrset.Rdatas) > 0 {
-		for _, rdata := range rrset.Rdatas {
-			opt := rdata.(*OPT)
-			if len(opt.Data) == 0 {
-				continue
+	for _, rdata := range rrset.Rdatas {
+		//each option is code, length and data, unknown option is skipped
+		data := rdata.(*OPT).Data
+		for len(data) >= 4 {
+			code := uint16(data[0])<<8 | uint16(data[1])
+			length := 4 + (int(data[2])<<8 | int(data[3]))
+			if length > len(data) {
+				break
 			}
 
-			buf := util.NewInputBuffer(opt.Data)
-			code, _ := buf.ReadUint16()
+			buf := util.NewInputBuffer(data[2:length])
 			if code == EDNS_SUBNET {
 				if option, err := subnetOptFromWire(buf); err == nil {
 					opts = append(opts, option)
@@ -107,6 +109,7 @@ func EdnsFromRRset(rrset *RRset) *EDNS {
 					opts = append(opts, option)
 				}
 			}
+			data = data[length:]
 		}
 	}
 
@@ -119,17 +122,20 @@ func EdnsFromRRset(rrset *RRset) *EDNS {
 	}
 }
 
-func (e *EDNS) Rend(r *MsgRender) {
+func (e *EDNS) flags() uint32 {
 	flags := uint32(e.extendedRcode) << EXTRCODE_SHIFT
 	flags |= (uint32(e.Version) << VERSION_SHIFT) & VERSION_MASK
 	if e.DnssecAware {
 		flags |= EXTFLAG_DO
 	}
+	return flags
+}
 
+func (e *EDNS) Rend(r *MsgRender) {
 	Root.Rend(r)
 	RRType(RR_OPT).Rend(r)
 	RRClass(e.UdpSize).Rend(r)
-	RRTTL(flags).Rend(r)
+	RRTTL(e.flags()).Rend(r)
 	if len(e.Options) == 0 {
 		r.WriteUint16(0)
 	} else {
@@ -143,16 +149,10 @@ func (e *EDNS) Rend(r *MsgRender) {
 }
 
 func (e *EDNS) ToWire(buf *util.OutputBuffer) {
-	flags := uint32(e.extendedRcode) << EXTRCODE_SHIFT
-	flags |= (uint32(e.Version) << VERSION_SHIFT) & VERSION_MASK
-	if e.DnssecAware {
-		flags |= EXTFLAG_DO
-	}
-
 	Root.ToWire(buf)
 	RRType(RR_OPT).ToWire(buf)
 	RRClass(e.UdpSize).ToWire(buf)
-	RRTTL(flags).ToWire(buf)
+	RRTTL(e.flags()).ToWire(buf)
 	buf.WriteUint16(0)
 }
 
@@ -174,11 +174,7 @@ func (e *EDNS) CleanOption() {
 	e.Options = []Option{}
 }
 
+// all the options are in one opt rr
 func (e *EDNS) RRCount() int {
-	optCount := len(e.Options)
-	if optCount == 0 {
-		return 1
-	} else {
-		return optCount
-	}
+	return 1
 }
@@ -3,6 +3,7 @@ package g53
 import (
 	//	"fmt"
 	"github.com/zdnscloud/g53/util"
+	"net"
 	"testing"
 )
 
@@ -47,3 +48,44 @@ func TestEdnsFromToWire(t *testing.T) {
 		DnssecAware:   true,
 	})
 }
+
+func TestEdnsOptionAccess(t *testing.T) {
+	edns := &EDNS{UdpSize: 4096}
+	Assert(t, edns.GetSubnetOpt() == nil, "edns has no subnet option")
+	Assert(t, edns.GetViewOpt() == nil, "edns has no view option")
+
+	edns.Options = append(edns.Options, NewSubnetOpt(net.ParseIP("192.0.2.0"), 24))
+	render := NewMsgRender()
+	edns.Rend(render)
+	edns, err := EdnsFromWire(util.NewInputBuffer(render.Data()))
+	Assert(t, err == nil, "wire data is valid")
+	subnet := edns.GetSubnetOpt()
+	Equal(t, subnet.Family(), uint16(1))
+	Equal(t, subnet.IP().String(), "192.0.2.0")
+	Equal(t, subnet.SourcePrefix(), uint8(24))
+	Equal(t, subnet.ScopePrefix(), uint8(0))
+
+	edns.AddSubnetView("internal")
+	Equal(t, edns.GetViewOpt().View, "internal")
+}
+
+func TestEdnsWithMultipleOptions(t *testing.T) {
+	msg := MakeQuery(NameFromStringUnsafe("www.example.com."), RR_A, 1232, false)
+	msg.Edns.Options = append(msg.Edns.Options, NewSubnetOpt(net.ParseIP("192.0.2.0"), 24))
+	msg.Edns.AddSubnetView("internal")
+	msg.RecalculateSectionRRCount()
+	Equal(t, msg.Header.ARCount, uint16(1))
+
+	render := NewMsgRender()
+	msg.Rend(render)
+	wire := render.Data()
+	Equal(t, uint16(wire[10])<<8|uint16(wire[11]), uint16(1))
+
+	parsed, err := MessageFromWire(util.NewInputBuffer(wire))
+	Assert(t, err == nil, "wire data is valid")
+	Equal(t, parsed.Header.ARCount, uint16(1))
+	Equal(t, len(parsed.Edns.Options), 2)
+	Equal(t, parsed.Edns.GetSubnetOpt().IP().String(), "192.0.2.0")
+	Equal(t, parsed.Edns.GetViewOpt().View, "internal")
+	Equal(t, parsed.Edns.String(), msg.Edns.String())
+}
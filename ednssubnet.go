@@ -103,3 +103,45 @@ func (e *EDNS) AddSubnetV4(ip_ string) error {
 		return fmt.Errorf("invalid ip address:%s", ip_)
 	}
 }
+
+func NewSubnetOpt(ip net.IP, sourcePrefix uint8) *SubnetOpt {
+	family := uint16(1)
+	if ip.To4() == nil {
+		family = 2
+	}
+	return &SubnetOpt{
+		family: family,
+		mask:   sourcePrefix,
+		ip:     ip,
+	}
+}
+
+func (subnet *SubnetOpt) Family() uint16 {
+	return subnet.family
+}
+
+func (subnet *SubnetOpt) IP() net.IP {
+	return subnet.ip
+}
+
+func (subnet *SubnetOpt) SourcePrefix() uint8 {
+	return subnet.mask
+}
+
+func (subnet *SubnetOpt) ScopePrefix() uint8 {
+	return subnet.scope
+}
+
+func (e *EDNS) GetSubnetOpt() *SubnetOpt {
+	for _, opt := range e.Options {
+		if subnet, ok := opt.(*SubnetOpt); ok {
+			return subnet
+		}
+	}
+	return nil
+}
+
+//scope prefix is set by server to tell the subnet the answer covers
+func (subnet *SubnetOpt) SetScopePrefix(scope uint8) {
+	subnet.scope = scope
+}
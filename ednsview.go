@@ -66,3 +66,12 @@ func (e *EDNS) AddSubnetView(view string) error {
 	})
 	return nil
 }
+
+func (e *EDNS) GetViewOpt() *ViewOpt {
+	for _, opt := range e.Options {
+		if view, ok := opt.(*ViewOpt); ok {
+			return view
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package iptable
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Load reads one mapping per line, prefix and label are separated by
+// comma or spaces, empty line and line starts with "#" are ignored
+//
+//	10.0.0.0/8,internal
+//	2001:db8::/32 lab
+func Load(r io.Reader) (*Table[string], error) {
+	t := New[string]()
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum += 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		var fields []string
+		if strings.Contains(line, ",") {
+			fields = strings.SplitN(line, ",", 2)
+		} else {
+			fields = strings.Fields(line)
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: %s should be prefix and label", lineNum, line)
+		}
+
+		prefix, label := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		if err := t.InsertString(prefix, label); err != nil {
+			return nil, fmt.Errorf("line %d: %s is invalid: %s", lineNum, prefix, err.Error())
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func LoadFile(path string) (*Table[string], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// FileTable is a table loaded from file which could be reloaded while
+// it's being looked up, if the new content is invalid, the old table is
+// kept
+type FileTable struct {
+	path    string
+	table   atomic.Value
+	lock    sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+func NewFileTable(path string) (*FileTable, error) {
+	t := &FileTable{
+		path: path,
+	}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Table returns the current table, it shouldn't be modified
+func (t *FileTable) Table() *Table[string] {
+	return t.table.Load().(*Table[string])
+}
+
+func (t *FileTable) Lookup(ip net.IP) (string, int, bool) {
+	return t.Table().Lookup(ip)
+}
+
+func (t *FileTable) Reload() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+	return t.load(info)
+}
+
+// ReloadIfModified reloads the file if its modification time or size is
+// changed since last load
+func (t *FileTable) ReloadIfModified() (bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(t.modTime) && info.Size() == t.size {
+		return false, nil
+	}
+	if err := t.load(info); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (t *FileTable) load(info os.FileInfo) error {
+	table, err := LoadFile(t.path)
+	if err != nil {
+		return err
+	}
+
+	t.table.Store(table)
+	t.modTime = info.ModTime()
+	t.size = info.Size()
+	return nil
+}
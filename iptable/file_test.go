@@ -0,0 +1,68 @@
+package iptable
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ut "github.com/zdnscloud/cement/unittest"
+)
+
+func TestLoad(t *testing.T) {
+	table, err := Load(strings.NewReader(`
+# region mapping
+10.0.0.0/8, internal
+2001:db8::/32 lab
+192.0.2.1,host
+`))
+	ut.Equal(t, err, nil)
+	ut.Equal(t, table.Len(), 3)
+	label, prefixLen, _ := table.Lookup(net.ParseIP("10.0.0.1"))
+	ut.Equal(t, label, "internal")
+	ut.Equal(t, prefixLen, 8)
+	label, _, _ = table.Lookup(net.ParseIP("2001:db8::1"))
+	ut.Equal(t, label, "lab")
+
+	_, err = Load(strings.NewReader("10.0.0.0/8,internal\n10.0.0.0/40,bad"))
+	ut.Assert(t, err != nil && strings.HasPrefix(err.Error(), "line 2:"), "invalid prefix should be reported")
+	_, err = Load(strings.NewReader("10.0.0.0/8"))
+	ut.Assert(t, err != nil, "line without label should fail")
+}
+
+func TestFileTableReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regions.txt")
+	ut.Equal(t, os.WriteFile(path, []byte("10.0.0.0/8,internal\n"), 0644), nil)
+	table, err := NewFileTable(path)
+	ut.Equal(t, err, nil)
+	label, _, _ := table.Lookup(net.ParseIP("10.0.0.1"))
+	ut.Equal(t, label, "internal")
+
+	reloaded, err := table.ReloadIfModified()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, reloaded, false)
+
+	old := table.Table()
+	ut.Equal(t, os.WriteFile(path, []byte("10.0.0.0/8,corp\n10.1.0.0/16,lab\n"), 0644), nil)
+	reloaded, err = table.ReloadIfModified()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, reloaded, true)
+	label, prefixLen, _ := table.Lookup(net.ParseIP("10.1.0.1"))
+	ut.Equal(t, label, "lab")
+	ut.Equal(t, prefixLen, 16)
+	label, _, _ = old.Lookup(net.ParseIP("10.1.0.1"))
+	ut.Equal(t, label, "internal")
+
+	//invalid content keeps the old table
+	ut.Equal(t, os.WriteFile(path, []byte("10.0.0.0/99,bad\n"), 0644), nil)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))
+	_, err = table.ReloadIfModified()
+	ut.Assert(t, err != nil, "invalid file shouldn't be loaded")
+	label, _, _ = table.Lookup(net.ParseIP("10.1.0.1"))
+	ut.Equal(t, label, "lab")
+
+	_, err = NewFileTable(filepath.Join(t.TempDir(), "missing.txt"))
+	ut.Assert(t, err != nil, "missing file should fail")
+}
@@ -0,0 +1,200 @@
+package iptable
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/zdnscloud/g53"
+)
+
+var ErrInvalidPrefix = errors.New("invalid ip prefix")
+
+const ipv4MappedPrefixLen = 96
+
+// Table is a binary trie of ip prefixes, lookup returns the value of the
+// longest prefix which contains the address, ipv4 and ipv4-mapped ipv6
+// address are in the same trie, ipv4-mapped ipv6 prefix is saved as ipv4
+// prefix with 96 less bits
+type Table[V any] struct {
+	v4    node[V]
+	v6    node[V]
+	count int
+}
+
+type node[V any] struct {
+	children [2]*node[V]
+	value    V
+	hasValue bool
+}
+
+func New[V any]() *Table[V] {
+	return &Table[V]{}
+}
+
+func (t *Table[V]) Len() int {
+	return t.count
+}
+
+func bitAt(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+func (t *Table[V]) root(ip net.IP) (*node[V], net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &t.v4, ip4
+	}
+	if ip16 := ip.To16(); ip16 != nil {
+		return &t.v6, ip16
+	}
+	return nil, nil
+}
+
+// prefix of ipv4-mapped ipv6 address shorter than 96 bits covers non
+// ipv4 address, it's invalid
+func (t *Table[V]) prefixRoot(prefix *net.IPNet) (*node[V], net.IP, int, bool) {
+	ones, bits := prefix.Mask.Size()
+	n, ip := t.root(prefix.IP)
+	if n == nil || bits == 0 {
+		return nil, nil, 0, false
+	}
+
+	if len(ip) == net.IPv4len && bits == net.IPv6len*8 {
+		if ones < ipv4MappedPrefixLen {
+			return nil, nil, 0, false
+		}
+		ones, bits = ones-ipv4MappedPrefixLen, net.IPv4len*8
+	}
+	if bits != len(ip)*8 {
+		return nil, nil, 0, false
+	}
+	return n, ip, ones, true
+}
+
+// Insert adds prefix with value, value of existing prefix is replaced
+func (t *Table[V]) Insert(prefix *net.IPNet, value V) error {
+	n, ip, ones, ok := t.prefixRoot(prefix)
+	if ok == false {
+		return ErrInvalidPrefix
+	}
+
+	for i := 0; i < ones; i++ {
+		bit := bitAt(ip, i)
+		if n.children[bit] == nil {
+			n.children[bit] = &node[V]{}
+		}
+		n = n.children[bit]
+	}
+
+	if n.hasValue == false {
+		t.count += 1
+	}
+	n.value = value
+	n.hasValue = true
+	return nil
+}
+
+// InsertString adds prefix in cidr format, address without prefix
+// length is treated as a host prefix
+func (t *Table[V]) InsertString(s string, value V) error {
+	prefix, err := ParsePrefix(s)
+	if err != nil {
+		return err
+	}
+	return t.Insert(prefix, value)
+}
+
+// Remove deletes the prefix, false is returned if it doesn't exist
+func (t *Table[V]) Remove(prefix *net.IPNet) bool {
+	n, ip, ones, ok := t.prefixRoot(prefix)
+	if ok == false {
+		return false
+	}
+
+	for i := 0; i < ones && n != nil; i++ {
+		n = n.children[bitAt(ip, i)]
+	}
+	if n == nil || n.hasValue == false {
+		return false
+	}
+
+	var zero V
+	n.value = zero
+	n.hasValue = false
+	t.count -= 1
+	return true
+}
+
+// Lookup returns the value and the length of the longest prefix which
+// contains ip
+func (t *Table[V]) Lookup(ip net.IP) (V, int, bool) {
+	var value V
+	n, ip := t.root(ip)
+	if n == nil {
+		return value, 0, false
+	}
+
+	prefixLen, found := 0, false
+	if n.hasValue {
+		value, found = n.value, true
+	}
+	for i := 0; i < len(ip)*8; i++ {
+		n = n.children[bitAt(ip, i)]
+		if n == nil {
+			break
+		}
+		if n.hasValue {
+			value, prefixLen, found = n.value, i+1, true
+		}
+	}
+	return value, prefixLen, found
+}
+
+func (t *Table[V]) Contains(ip net.IP) bool {
+	_, _, found := t.Lookup(ip)
+	return found
+}
+
+// LookupSubnet looks up the address of edns client subnet option, only
+// the bits in source prefix are used, scope prefix of the option is set
+// to the length of the matched prefix
+func (t *Table[V]) LookupSubnet(subnet *g53.SubnetOpt) (V, bool) {
+	ip := subnet.IP()
+	bits := net.IPv6len * 8
+	if subnet.Family() == 1 {
+		ip, bits = ip.To4(), net.IPv4len*8
+	}
+
+	var value V
+	if ip == nil || int(subnet.SourcePrefix()) > bits {
+		return value, false
+	}
+
+	ip = ip.Mask(net.CIDRMask(int(subnet.SourcePrefix()), bits))
+	value, prefixLen, found := t.Lookup(ip)
+	if found {
+		subnet.SetScopePrefix(uint8(prefixLen))
+	}
+	return value, found
+}
+
+// ParsePrefix parses address in cidr format, address without prefix
+// length is treated as a host prefix
+func ParsePrefix(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, prefix, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, ErrInvalidPrefix
+		}
+		return prefix, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, ErrInvalidPrefix
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}, nil
+}
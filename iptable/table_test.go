@@ -0,0 +1,105 @@
+package iptable
+
+import (
+	"net"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+func TestTableLookup(t *testing.T) {
+	table := New[string]()
+	for prefix, label := range map[string]string{
+		"0.0.0.0/0":       "world",
+		"10.0.0.0/8":      "internal",
+		"10.1.0.0/16":     "lab",
+		"192.0.2.1":       "host",
+		"2001:db8::/32":   "v6",
+		"2001:db8:1::/48": "v6-lab",
+	} {
+		ut.Equal(t, table.InsertString(prefix, label), nil)
+	}
+	ut.Equal(t, table.Len(), 6)
+
+	for _, c := range []struct {
+		ip        string
+		label     string
+		prefixLen int
+		found     bool
+	}{
+		{"10.1.2.3", "lab", 16, true},
+		{"10.2.2.3", "internal", 8, true},
+		{"::ffff:10.2.2.3", "internal", 8, true},
+		{"192.0.2.1", "host", 32, true},
+		{"192.0.2.2", "world", 0, true},
+		{"2001:db8:1:2::1", "v6-lab", 48, true},
+		{"2001:db8:2::1", "v6", 32, true},
+		{"2001:db9::1", "", 0, false},
+	} {
+		label, prefixLen, found := table.Lookup(net.ParseIP(c.ip))
+		ut.Equal(t, found, c.found)
+		ut.Equal(t, label, c.label)
+		ut.Equal(t, prefixLen, c.prefixLen)
+	}
+
+	prefix, _ := ParsePrefix("10.1.0.0/16")
+	ut.Equal(t, table.Remove(prefix), true)
+	ut.Equal(t, table.Remove(prefix), false)
+	ut.Equal(t, table.Len(), 5)
+	label, _, _ := table.Lookup(net.ParseIP("10.1.2.3"))
+	ut.Equal(t, label, "internal")
+
+	ut.Equal(t, table.InsertString("::ffff:172.16.0.0/108", "mapped"), nil)
+	label, prefixLen, _ := table.Lookup(net.ParseIP("172.16.1.1"))
+	ut.Equal(t, label, "mapped")
+	ut.Equal(t, prefixLen, 12)
+	ut.Equal(t, table.Remove(&net.IPNet{IP: net.ParseIP("172.16.0.0").To4(), Mask: net.CIDRMask(12, 32)}), true)
+	ut.Equal(t, table.Insert(&net.IPNet{IP: net.ParseIP("::ffff:0.0.0.0"), Mask: net.CIDRMask(95, 128)}, "bad"), ErrInvalidPrefix)
+
+	ut.Equal(t, table.InsertString("10.0.0.0/33", "bad"), ErrInvalidPrefix)
+	ut.Equal(t, table.InsertString("example.com", "bad"), ErrInvalidPrefix)
+	ut.Equal(t, table.Contains(nil), false)
+}
+
+func TestLookupSubnet(t *testing.T) {
+	table := New[int]()
+	table.InsertString("198.51.100.0/24", 1)
+	table.InsertString("198.51.100.128/25", 2)
+	table.InsertString("2001:db8::/32", 3)
+
+	for _, c := range []struct {
+		ip           string
+		sourcePrefix uint8
+		value        int
+		scopePrefix  uint8
+		found        bool
+	}{
+		{"198.51.100.200", 32, 2, 25, true},
+		{"198.51.100.200", 24, 1, 24, true},
+		{"198.51.0.0", 16, 0, 0, false},
+		{"2001:db8:1::", 56, 3, 32, true},
+	} {
+		subnet := g53.NewSubnetOpt(net.ParseIP(c.ip), c.sourcePrefix)
+		value, found := table.LookupSubnet(subnet)
+		ut.Equal(t, found, c.found)
+		ut.Equal(t, value, c.value)
+		ut.Equal(t, subnet.ScopePrefix(), c.scopePrefix)
+	}
+}
+
+func BenchmarkTableLookup(b *testing.B) {
+	table := New[int]()
+	ips := make([]net.IP, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		ip := net.IPv4(byte(i>>16), byte(i>>8), byte(i), 0).To4()
+		table.Insert(&net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)}, i)
+		ips = append(ips, net.IPv4(byte(i>>16), byte(i>>8), byte(i), 1))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.Lookup(ips[i%len(ips)])
+	}
+}
@@ -0,0 +1,156 @@
+package g53
+
+import (
+	"github.com/zdnscloud/g53/util"
+)
+
+// LazyMessage decodes header, question, edns and tsig when it's parsed,
+// other rrs are only checked to be well formed and their position is
+// recorded, answer, authority and additional section are decoded when
+// they are accessed. The wire data is referenced instead of copied, so
+// it shouldn't be modified while the message is in use
+type LazyMessage struct {
+	Header   Header
+	Question *Question
+	Edns     *EDNS
+	Tsig     *TSIG
+
+	wire     []byte
+	offsets  [SectionCount]uint
+	counts   [SectionCount]uint16
+	sections [SectionCount]Section
+	decoded  [SectionCount]bool
+}
+
+func LazyMessageFromWire(buf *util.InputBuffer) (*LazyMessage, error) {
+	m := LazyMessage{}
+	if err := m.FromWire(buf); err != nil {
+		return nil, err
+	} else {
+		return &m, nil
+	}
+}
+
+// FromWire reuses the question of the message, so parsing queries into
+// the same message doesn't allocate for header and question
+func (m *LazyMessage) FromWire(buf *util.InputBuffer) error {
+	h := &m.Header
+	if err := HeaderFromWire(h, buf); err != nil {
+		return err
+	}
+
+	if h.QDCount == 1 {
+		if m.Question == nil {
+			m.Question = &Question{}
+		}
+		if err := m.Question.FromWire(buf); err != nil {
+			return err
+		}
+	} else {
+		m.Question = nil
+	}
+
+	m.wire = buf.Data()[:buf.Len()]
+	m.Edns = nil
+	m.Tsig = nil
+	counts := [SectionCount]uint16{h.ANCount, h.NSCount, h.ARCount}
+	lastRRPos := uint(0)
+	lastRRType := RRType(0)
+	for i := 0; i < SectionCount; i++ {
+		m.offsets[i] = buf.Position()
+		m.counts[i] = counts[i]
+		m.sections[i] = nil
+		m.decoded[i] = false
+		for j := uint16(0); j < counts[i]; j++ {
+			lastRRPos = buf.Position()
+			typ, err := skipRRFromWire(buf)
+			if err != nil {
+				return err
+			}
+			lastRRType = typ
+		}
+	}
+
+	if h.ARCount > 0 && (lastRRType == RR_OPT || lastRRType == RR_TSIG) {
+		end := buf.Position()
+		buf.SetPosition(lastRRPos)
+		rrset, err := RRsetFromWire(buf)
+		if err != nil {
+			return err
+		}
+		if lastRRType == RR_OPT {
+			m.Edns = EdnsFromRRset(rrset)
+		} else {
+			m.Tsig = TSIGFromRRset(rrset)
+		}
+		m.counts[AdditionalSection] -= 1
+		buf.SetPosition(end)
+	}
+	return nil
+}
+
+// name and rdata length are validated, rdata isn't parsed
+func skipRRFromWire(buf *util.InputBuffer) (RRType, error) {
+	if _, err := WireNameFromWire(buf); err != nil {
+		return 0, err
+	}
+
+	typ, err := TypeFromWire(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	//class and ttl
+	if _, err := buf.ReadBytes(6); err != nil {
+		return 0, err
+	}
+
+	rdlen, err := buf.ReadUint16()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := buf.ReadBytes(uint(rdlen)); err != nil {
+		return 0, err
+	}
+	return typ, nil
+}
+
+// GetSection decodes the section on first access, error in rdata is
+// reported here
+func (m *LazyMessage) GetSection(st SectionType) (Section, error) {
+	if m.decoded[st] {
+		return m.sections[st], nil
+	}
+
+	buf := util.NewInputBuffer(m.wire)
+	buf.SetPosition(m.offsets[st])
+	s, err := sectionFromWire(buf, m.counts[st])
+	if err != nil {
+		return nil, err
+	}
+
+	m.sections[st] = s
+	m.decoded[st] = true
+	return s, nil
+}
+
+// ToMessage decodes all the sections, the returned message shares
+// question, edns and tsig with the lazy message
+func (m *LazyMessage) ToMessage() (*Message, error) {
+	msg := &Message{
+		Header:   m.Header,
+		Question: m.Question,
+		Edns:     m.Edns,
+		Tsig:     m.Tsig,
+	}
+
+	for i := 0; i < SectionCount; i++ {
+		s, err := m.GetSection(SectionType(i))
+		if err != nil {
+			return nil, err
+		}
+		msg.Sections[i] = s
+	}
+	return msg, nil
+}
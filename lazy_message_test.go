@@ -0,0 +1,96 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestLazyMessageFromWire(t *testing.T) {
+	wire, _ := util.HexStrToBytes(knetMessageWire)
+	m, err := MessageFromWire(util.NewInputBuffer(wire))
+	Assert(t, err == nil, "message from wire failed:%v", err)
+
+	buf := util.NewInputBuffer(wire)
+	lm, err := LazyMessageFromWire(buf)
+	Assert(t, err == nil, "lazy message from wire failed:%v", err)
+	Equal(t, buf.Position(), uint(len(wire)))
+	Equal(t, lm.Header, m.Header)
+	matchQuestion(t, lm.Question, m.Question)
+	Equal(t, lm.Edns, m.Edns)
+	Assert(t, lm.Tsig == nil, "knet message has no tsig")
+
+	for _, st := range []SectionType{AdditionalSection, AnswerSection, AuthSection} {
+		s, err := lm.GetSection(st)
+		Assert(t, err == nil, "decode section failed:%v", err)
+		matchSection(t, s, m.GetSection(st))
+	}
+
+	nm, err := lm.ToMessage()
+	Assert(t, err == nil, "lazy message to message failed:%v", err)
+	render := NewMsgRender()
+	nm.Rend(render)
+	WireMatch(t, wire, render.Data())
+}
+
+func TestLazyMessageReuse(t *testing.T) {
+	var lm LazyMessage
+	wire, _ := util.HexStrToBytes(knetMessageWire)
+	Assert(t, lm.FromWire(util.NewInputBuffer(wire)) == nil, "lazy message from wire failed")
+	answer, _ := lm.GetSection(AnswerSection)
+	Equal(t, len(answer), 1)
+
+	wire, _ = util.HexStrToBytes(typicalQueryWire)
+	Assert(t, lm.FromWire(util.NewInputBuffer(wire)) == nil, "lazy message from wire failed")
+	NameEqToStr(t, lm.Question.Name, "test.example.com")
+	Equal(t, lm.Edns.UdpSize, uint16(4096))
+	answer, _ = lm.GetSection(AnswerSection)
+	Equal(t, len(answer), 0)
+	additional, _ := lm.GetSection(AdditionalSection)
+	Equal(t, len(additional), 0)
+}
+
+func TestMalformedLazyMessage(t *testing.T) {
+	wire, _ := util.HexStrToBytes(knetMessageWire)
+	for _, l := range []int{11, 20, 40, 100, len(wire) - 1} {
+		_, err := LazyMessageFromWire(util.NewInputBuffer(wire[:l]))
+		Assert(t, err != nil, "truncated message with len %d should fail", l)
+	}
+
+	//answer name points to itself
+	bad := append([]byte(nil), wire...)
+	bad[29], bad[30] = 0xc0, 29
+	_, err := LazyMessageFromWire(util.NewInputBuffer(bad))
+	Equal(t, err, ErrBadCompressionPointer)
+
+	//rdata of a with 3 bytes is only detected when answer is decoded
+	query, _ := util.HexStrToBytes("04b0818000010001000000000474657374076578616d706c6503636f6d0000010001c00c00010001000002580003c0a801")
+	lm, err := LazyMessageFromWire(util.NewInputBuffer(query))
+	Assert(t, err == nil, "rdata shouldn't be parsed:%v", err)
+	_, err = lm.GetSection(AnswerSection)
+	Assert(t, err != nil, "bad a rdata should be detected")
+	_, err = MessageFromWire(util.NewInputBuffer(query))
+	Assert(t, err != nil, "bad a rdata should be detected")
+}
+
+func BenchmarkLazyParseKnetMessage(b *testing.B) {
+	wire, _ := util.HexStrToBytes(knetMessageWire)
+	buf := util.NewInputBuffer(wire)
+	var lm LazyMessage
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		lm.FromWire(buf)
+	}
+}
+
+func BenchmarkLazyParseQuery(b *testing.B) {
+	wire, _ := util.HexStrToBytes(typicalQueryWire)
+	buf := util.NewInputBuffer(wire)
+	var lm LazyMessage
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		lm.FromWire(buf)
+	}
+}
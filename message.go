@@ -120,7 +120,6 @@ func (m *Message) FromWire(buf *util.InputBuffer) error {
 }
 
 func (m *Message) sectionFromWire(st SectionType, buf *util.InputBuffer) error {
-	var s Section
 	var count uint16
 	switch st {
 	case AnswerSection:
@@ -131,11 +130,36 @@ func (m *Message) sectionFromWire(st SectionType, buf *util.InputBuffer) error {
 		count = m.Header.ARCount
 	}
 
+	s, err := sectionFromWire(buf, count)
+	if err != nil {
+		return err
+	}
+
+	if st == AdditionalSection && len(s) > 0 {
+		lastRRset := s[len(s)-1]
+		if lastRRset.Type == RR_OPT {
+			m.Edns = EdnsFromRRset(lastRRset)
+			s = s[:len(s)-1]
+		} else if lastRRset.Type == RR_TSIG {
+			m.Tsig = TSIGFromRRset(lastRRset)
+			s = s[:len(s)-1]
+		}
+	}
+
+	if len(s) == 0 {
+		s = nil
+	}
+	m.Sections[st] = s
+	return nil
+}
+
+func sectionFromWire(buf *util.InputBuffer, count uint16) (Section, error) {
+	var s Section
 	var lastRRset *RRset
 	for i := uint16(0); i < count; i++ {
 		rrset, err := RRsetFromWire(buf)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if lastRRset == nil {
@@ -143,7 +167,11 @@ func (m *Message) sectionFromWire(st SectionType, buf *util.InputBuffer) error {
 			continue
 		}
 
-		if lastRRset.IsSameRRset(rrset) {
+		//in update message, rr with same name and type may have
+		//different class or empty rdata, they shouldn't be merged
+		if lastRRset.IsSameRRset(rrset) &&
+			lastRRset.Class == rrset.Class &&
+			len(lastRRset.Rdatas) > 0 && len(rrset.Rdatas) > 0 {
 			lastRRset.Rdatas = append(lastRRset.Rdatas, rrset.Rdatas[0])
 		} else {
 			s = append(s, lastRRset)
@@ -152,17 +180,9 @@ func (m *Message) sectionFromWire(st SectionType, buf *util.InputBuffer) error {
 	}
 
 	if lastRRset != nil {
-		if st == AdditionalSection && lastRRset.Type == RR_OPT {
-			m.Edns = EdnsFromRRset(lastRRset)
-		} else if st == AdditionalSection && lastRRset.Type == RR_TSIG {
-			m.Tsig = TSIGFromRRset(lastRRset)
-		} else {
-			s = append(s, lastRRset)
-		}
+		s = append(s, lastRRset)
 	}
-
-	m.Sections[st] = s
-	return nil
+	return s, nil
 }
 
 func (m *Message) Rend(r *MsgRender) {
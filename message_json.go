@@ -0,0 +1,543 @@
+package g53
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+//json representation of message and rrset defined in RFC 8427, edns and
+//tsig are rr in additional section, flags are marshaled to booleans but
+//0 and 1 are also accepted in unmarshal
+
+var (
+	ErrJSONMissingMember = errors.New("json member is missing")
+	ErrJSONInvalidMember = errors.New("json member has invalid value")
+	ErrJSONRRsetMismatch = errors.New("rrs in json rrset have different name, type or class")
+)
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// jsonObject keeps the order of members
+type jsonObject []jsonMember
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var jsonFlags = []struct {
+	key  string
+	flag FlagField
+}{
+	{"AA", FLAG_AA},
+	{"TC", FLAG_TC},
+	{"RD", FLAG_RD},
+	{"RA", FLAG_RA},
+	{"AD", FLAG_AD},
+	{"CD", FLAG_CD},
+}
+
+// MarshalJSON returns the parsed fields of message, counts are calculated
+// from the content of message
+func (m *Message) MarshalJSON() ([]byte, error) {
+	obj := jsonObject{
+		{"ID", m.Header.Id},
+		{"QR", m.Header.GetFlag(FLAG_QR)},
+		{"Opcode", uint8(m.Header.Opcode)},
+	}
+	for _, f := range jsonFlags {
+		obj = append(obj, jsonMember{f.key, m.Header.GetFlag(f.flag)})
+	}
+	obj = append(obj, jsonMember{"RCODE", uint8(m.Header.Rcode)})
+
+	additional := m.additionalRRs()
+	qdcount := 0
+	if m.Question != nil {
+		qdcount = 1
+	}
+	obj = append(obj,
+		jsonMember{"QDCOUNT", qdcount},
+		jsonMember{"ANCOUNT", m.Sections[AnswerSection].rrCount()},
+		jsonMember{"NSCOUNT", m.Sections[AuthSection].rrCount()},
+		jsonMember{"ARCOUNT", len(additional)})
+
+	if q := m.Question; q != nil {
+		obj = append(obj,
+			jsonMember{"QNAME", q.Name.String(false)},
+			jsonMember{"QTYPE", uint16(q.Type)},
+			jsonMember{"QTYPEname", q.Type.String()},
+			jsonMember{"QCLASS", uint16(q.Class)},
+			jsonMember{"QCLASSname", q.Class.String()})
+	}
+
+	if rrs := m.Sections[AnswerSection].jsonRRs(); len(rrs) > 0 {
+		obj = append(obj, jsonMember{"answerRRs", rrs})
+	}
+	if rrs := m.Sections[AuthSection].jsonRRs(); len(rrs) > 0 {
+		obj = append(obj, jsonMember{"authorityRRs", rrs})
+	}
+	if len(additional) > 0 {
+		obj = append(obj, jsonMember{"additionalRRs", additional})
+	}
+	return json.Marshal(obj)
+}
+
+// MarshalJSONOctets returns the message in wire format as messageOctetsHEX
+func (m *Message) MarshalJSONOctets() ([]byte, error) {
+	return json.Marshal(jsonObject{
+		{"messageOctetsHEX", strings.ToUpper(hex.EncodeToString(m.wire()))},
+	})
+}
+
+// counts are recalculated like parsed fields, tsig is appended without
+// signing, since the key isn't known here
+func (m *Message) wire() []byte {
+	withoutTsig := *m
+	withoutTsig.Tsig = nil
+	withoutTsig.RecalculateSectionRRCount()
+	render := NewMsgRender()
+	withoutTsig.Rend(render)
+	if m.Tsig == nil {
+		return append([]byte(nil), render.Data()...)
+	}
+
+	buf := util.NewOutputBuffer(render.Len() + 128)
+	buf.WriteData(render.Data())
+	m.Tsig.ToWire(buf)
+	buf.WriteUint16At(withoutTsig.Header.ARCount+1, 10)
+	return buf.Data()
+}
+
+func (m *Message) additionalRRs() []jsonObject {
+	rrs := m.Sections[AdditionalSection].jsonRRs()
+	if m.Edns != nil {
+		rrs = append(rrs, m.Edns.jsonRR())
+	}
+	if m.Tsig != nil {
+		rrs = append(rrs, m.Tsig.jsonRR())
+	}
+	return rrs
+}
+
+func (s Section) jsonRRs() []jsonObject {
+	var rrs []jsonObject
+	for _, rrset := range s {
+		rrs = append(rrs, rrset.jsonRRs()...)
+	}
+	return rrs
+}
+
+func (rrset *RRset) jsonRRs() []jsonObject {
+	if len(rrset.Rdatas) == 0 {
+		return []jsonObject{jsonRR(rrset.Name, rrset.Type, rrset.Class, rrset.Ttl, nil, "")}
+	}
+
+	rrs := make([]jsonObject, 0, len(rrset.Rdatas))
+	for _, rdata := range rrset.Rdatas {
+		buf := util.NewOutputBuffer(64)
+		rdata.ToWire(buf)
+		presentation := ""
+		if rrset.Type != RR_OPT {
+			presentation = rdata.String()
+		}
+		rrs = append(rrs, jsonRR(rrset.Name, rrset.Type, rrset.Class, rrset.Ttl, buf.Data(), presentation))
+	}
+	return rrs
+}
+
+// rdata nil means the rr has no rdata, presentation is omitted if it's
+// empty
+func jsonRR(name *Name, typ RRType, class RRClass, ttl RRTTL, rdata []byte, presentation string) jsonObject {
+	obj := jsonObject{
+		{"NAME", name.String(false)},
+		{"TYPE", uint16(typ)},
+	}
+	if _, ok := typeNameMap[typ]; ok {
+		obj = append(obj, jsonMember{"TYPEname", typ.String()})
+	}
+	obj = append(obj, jsonMember{"CLASS", uint16(class)})
+	//class of opt is udp size
+	if typ != RR_OPT && class.String() != "unknownclass" {
+		obj = append(obj, jsonMember{"CLASSname", class.String()})
+	}
+	obj = append(obj,
+		jsonMember{"TTL", uint32(ttl)},
+		jsonMember{"RDLENGTH", len(rdata)})
+	if rdata != nil {
+		obj = append(obj, jsonMember{"RDATAHEX", strings.ToUpper(hex.EncodeToString(rdata))})
+	}
+	if presentation != "" {
+		obj = append(obj, jsonMember{"rdata" + typ.String(), presentation})
+	}
+	return obj
+}
+
+func (e *EDNS) jsonRR() jsonObject {
+	render := NewMsgRender()
+	for _, opt := range e.Options {
+		opt.Rend(render)
+	}
+	rdata := append([]byte{}, render.Data()...)
+	return jsonRR(Root, RR_OPT, RRClass(e.UdpSize), RRTTL(e.flags()), rdata, "")
+}
+
+func (t *TSIG) jsonRR() jsonObject {
+	buf := util.NewOutputBuffer(128)
+	t.rdataToWire(buf)
+	return jsonRR(t.Header.Name, RR_TSIG, t.Header.Class, t.Header.Ttl, buf.Data(), "")
+}
+
+// MarshalJSON returns an array with an object for each rr
+func (rrset *RRset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rrset.jsonRRs())
+}
+
+// UnmarshalJSON accepts an array of rr objects or a single rr object,
+// all the rrs should have same name, type and class
+func (rrset *RRset) UnmarshalJSON(data []byte) error {
+	var objs []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objs); err != nil {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+	}
+
+	rrsets, err := rrsetsFromJSON(objs)
+	if err != nil {
+		return err
+	}
+	if len(rrsets) != 1 {
+		return ErrJSONRRsetMismatch
+	}
+	*rrset = *rrsets[0]
+	return nil
+}
+
+// UnmarshalJSON uses messageOctetsHEX if it exists, otherwise message is
+// built from the parsed fields, counts in json are ignored
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	if _, ok := obj["messageOctetsHEX"]; ok {
+		s, err := jsonString(obj, "messageOctetsHEX")
+		if err != nil {
+			return err
+		}
+		wire, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("%w: messageOctetsHEX", ErrJSONInvalidMember)
+		}
+		return m.FromWire(util.NewInputBuffer(wire))
+	}
+
+	*m = Message{}
+	if err := m.headerFromJSON(obj); err != nil {
+		return err
+	}
+	if err := m.questionFromJSON(obj); err != nil {
+		return err
+	}
+
+	for _, section := range []struct {
+		key string
+		st  SectionType
+	}{
+		{"answerRRs", AnswerSection},
+		{"authorityRRs", AuthSection},
+		{"additionalRRs", AdditionalSection},
+	} {
+		if err := m.sectionFromJSON(obj, section.key, section.st); err != nil {
+			return err
+		}
+	}
+	m.RecalculateSectionRRCount()
+	return nil
+}
+
+func (m *Message) headerFromJSON(obj map[string]json.RawMessage) error {
+	id, err := jsonUint(obj, "ID", 0xffff)
+	if err != nil {
+		return err
+	}
+	m.Header.Id = uint16(id)
+
+	opcode, err := jsonOptionalUint(obj, "Opcode", 15)
+	if err != nil {
+		return err
+	}
+	m.Header.Opcode = Opcode(opcode)
+
+	rcode, err := jsonOptionalUint(obj, "RCODE", 15)
+	if err != nil {
+		return err
+	}
+	m.Header.Rcode = Rcode(rcode)
+
+	flags := append([]struct {
+		key  string
+		flag FlagField
+	}{{"QR", FLAG_QR}}, jsonFlags...)
+	for _, f := range flags {
+		set, err := jsonBool(obj, f.key)
+		if err != nil {
+			return err
+		}
+		m.Header.SetFlag(f.flag, set)
+	}
+	return nil
+}
+
+func (m *Message) questionFromJSON(obj map[string]json.RawMessage) error {
+	if _, ok := obj["QNAME"]; ok == false {
+		return nil
+	}
+
+	s, err := jsonString(obj, "QNAME")
+	if err != nil {
+		return err
+	}
+	name, err := NameFromString(s)
+	if err != nil {
+		return err
+	}
+	typ, err := jsonType(obj, "QTYPE", "QTYPEname")
+	if err != nil {
+		return err
+	}
+	class, err := jsonClass(obj, "QCLASS", "QCLASSname")
+	if err != nil {
+		return err
+	}
+
+	m.Question = &Question{
+		Name:  name,
+		Type:  typ,
+		Class: class,
+	}
+	return nil
+}
+
+func (m *Message) sectionFromJSON(obj map[string]json.RawMessage, key string, st SectionType) error {
+	raw, ok := obj[key]
+	if ok == false {
+		return nil
+	}
+
+	var objs []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &objs); err != nil {
+		return fmt.Errorf("%w: %s", ErrJSONInvalidMember, key)
+	}
+	rrsets, err := rrsetsFromJSON(objs)
+	if err != nil {
+		return err
+	}
+
+	var s Section
+	for _, rrset := range rrsets {
+		if st == AdditionalSection && rrset.Type == RR_OPT {
+			m.Edns = EdnsFromRRset(rrset)
+		} else if st == AdditionalSection && rrset.Type == RR_TSIG && len(rrset.Rdatas) > 0 {
+			m.Tsig = TSIGFromRRset(rrset)
+		} else {
+			s = append(s, rrset)
+		}
+	}
+	m.Sections[st] = s
+	return nil
+}
+
+// consecutive rrs with same name, type and class are merged like wire
+// format
+func rrsetsFromJSON(objs []map[string]json.RawMessage) ([]*RRset, error) {
+	var rrsets []*RRset
+	var last *RRset
+	for _, obj := range objs {
+		rrset, err := rrFromJSON(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if last != nil && last.IsSameRRset(rrset) && last.Class == rrset.Class &&
+			len(last.Rdatas) > 0 && len(rrset.Rdatas) > 0 {
+			last.Rdatas = append(last.Rdatas, rrset.Rdatas[0])
+			continue
+		}
+		rrsets = append(rrsets, rrset)
+		last = rrset
+	}
+	return rrsets, nil
+}
+
+func rrFromJSON(obj map[string]json.RawMessage) (*RRset, error) {
+	s, err := jsonString(obj, "NAME")
+	if err != nil {
+		return nil, err
+	}
+	name, err := NameFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := jsonType(obj, "TYPE", "TYPEname")
+	if err != nil {
+		return nil, err
+	}
+	class, err := jsonClass(obj, "CLASS", "CLASSname")
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := jsonOptionalUint(obj, "TTL", 0xffffffff)
+	if err != nil {
+		return nil, err
+	}
+
+	rrset := &RRset{
+		Name:  name,
+		Type:  typ,
+		Class: class,
+		Ttl:   RRTTL(ttl),
+	}
+	rdata, err := rdataFromJSON(obj, typ)
+	if err != nil {
+		return nil, err
+	}
+	if rdata != nil {
+		rrset.Rdatas = []Rdata{rdata}
+	}
+	return rrset, nil
+}
+
+// RDATAHEX is preferred, rdata in presentation format is used if it
+// doesn't exist, nil is returned if neither exists
+func rdataFromJSON(obj map[string]json.RawMessage, typ RRType) (Rdata, error) {
+	if _, ok := obj["RDATAHEX"]; ok {
+		s, err := jsonString(obj, "RDATAHEX")
+		if err != nil {
+			return nil, err
+		}
+		data, err := hex.DecodeString(s)
+		if err != nil || len(data) > 0xffff {
+			return nil, fmt.Errorf("%w: RDATAHEX", ErrJSONInvalidMember)
+		}
+
+		buf := util.NewOutputBuffer(uint(len(data) + 2))
+		buf.WriteUint16(uint16(len(data)))
+		buf.WriteData(data)
+		return RdataFromWire(typ, util.NewInputBuffer(buf.Data()))
+	}
+
+	key := "rdata" + typ.String()
+	if _, ok := obj[key]; ok {
+		s, err := jsonString(obj, key)
+		if err != nil {
+			return nil, err
+		}
+		return RdataFromString(typ, s)
+	}
+	return nil, nil
+}
+
+func jsonString(obj map[string]json.RawMessage, key string) (string, error) {
+	raw, ok := obj[key]
+	if ok == false {
+		return "", fmt.Errorf("%w: %s", ErrJSONMissingMember, key)
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrJSONInvalidMember, key)
+	}
+	return s, nil
+}
+
+func jsonUint(obj map[string]json.RawMessage, key string, max uint64) (uint64, error) {
+	raw, ok := obj[key]
+	if ok == false {
+		return 0, fmt.Errorf("%w: %s", ErrJSONMissingMember, key)
+	}
+
+	var v uint64
+	if err := json.Unmarshal(raw, &v); err != nil || v > max {
+		return 0, fmt.Errorf("%w: %s", ErrJSONInvalidMember, key)
+	}
+	return v, nil
+}
+
+func jsonOptionalUint(obj map[string]json.RawMessage, key string, max uint64) (uint64, error) {
+	if _, ok := obj[key]; ok == false {
+		return 0, nil
+	}
+	return jsonUint(obj, key, max)
+}
+
+// missing flag is false
+func jsonBool(obj map[string]json.RawMessage, key string) (bool, error) {
+	raw, ok := obj[key]
+	if ok == false {
+		return false, nil
+	}
+
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	if v, err := jsonUint(obj, key, 1); err == nil {
+		return v == 1, nil
+	}
+	return false, fmt.Errorf("%w: %s", ErrJSONInvalidMember, key)
+}
+
+// number member is preferred, name member is used if it doesn't exist
+func jsonType(obj map[string]json.RawMessage, key, nameKey string) (RRType, error) {
+	if _, ok := obj[key]; ok {
+		v, err := jsonUint(obj, key, 0xffff)
+		return RRType(v), err
+	}
+
+	s, err := jsonString(obj, nameKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrJSONMissingMember, key)
+	}
+	return TypeFromString(s)
+}
+
+func jsonClass(obj map[string]json.RawMessage, key, nameKey string) (RRClass, error) {
+	if _, ok := obj[key]; ok {
+		v, err := jsonUint(obj, key, 0xffff)
+		return RRClass(v), err
+	}
+
+	s, err := jsonString(obj, nameKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrJSONMissingMember, key)
+	}
+	return ClassFromString(s)
+}
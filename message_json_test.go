@@ -0,0 +1,169 @@
+package g53
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func messageWire(m *Message) []byte {
+	render := NewMsgRender()
+	m.Rend(render)
+	return append([]byte(nil), render.Data()...)
+}
+
+func makeJSONTestMessage() *Message {
+	query := MakeQuery(NameFromStringUnsafe("www.example.com."), RR_A, 1232, true)
+	query.Header.Id = 19678
+	response := query.MakeResponse()
+	response.Header.SetFlag(FLAG_AA, true)
+	response.Edns = query.Edns
+	response.Edns.Options = append(response.Edns.Options, NewSubnetOpt(net.ParseIP("192.0.2.0"), 24))
+	response.Edns.AddSubnetView("internal")
+	for st, rrs := range map[SectionType][]string{
+		AnswerSection:     {"www.example.com. 300 IN A 192.0.2.1", "www.example.com. 300 IN A 192.0.2.2"},
+		AuthSection:       {"example.com. 3600 IN NS ns.example.com."},
+		AdditionalSection: {"ns.example.com. 3600 IN AAAA 2001:db8::53"},
+	} {
+		for _, rr := range rrs {
+			rrset, _ := RRsetFromString(rr)
+			response.AddRR(st, rrset.Name, rrset.Type, rrset.Class, rrset.Ttl, rrset.Rdatas[0], true)
+		}
+	}
+	response.RecalculateSectionRRCount()
+	return response
+}
+
+func TestMessageJSON(t *testing.T) {
+	msg := makeJSONTestMessage()
+	data, err := json.Marshal(msg)
+	Assert(t, err == nil, "marshal message failed %v", err)
+
+	var obj map[string]interface{}
+	json.Unmarshal(data, &obj)
+	Equal(t, obj["ID"], float64(19678))
+	Equal(t, obj["QR"], true)
+	Equal(t, obj["AA"], true)
+	Equal(t, obj["TC"], false)
+	Equal(t, obj["QNAME"], "www.example.com.")
+	Equal(t, obj["QTYPEname"], "A")
+	Equal(t, obj["ANCOUNT"], float64(2))
+	Equal(t, obj["ARCOUNT"], float64(2))
+	answers := obj["answerRRs"].([]interface{})
+	Equal(t, len(answers), 2)
+	Equal(t, answers[1].(map[string]interface{})["rdataA"], "192.0.2.2")
+	Equal(t, answers[1].(map[string]interface{})["RDATAHEX"], "C0000202")
+	opt := obj["additionalRRs"].([]interface{})[1].(map[string]interface{})
+	Equal(t, opt["TYPE"], float64(RR_OPT))
+	Equal(t, opt["CLASS"], float64(1232))
+	Equal(t, opt["TTL"], float64(EXTFLAG_DO))
+
+	var parsed Message
+	err = json.Unmarshal(data, &parsed)
+	Assert(t, err == nil, "unmarshal message failed %v", err)
+	Equal(t, parsed.String(), msg.String())
+	WireMatch(t, messageWire(msg), messageWire(&parsed))
+	Equal(t, parsed.Edns.GetViewOpt().View, "internal")
+
+	data, err = msg.MarshalJSONOctets()
+	Assert(t, err == nil, "marshal message octets failed %v", err)
+	var octets map[string]string
+	json.Unmarshal(data, &octets)
+	Equal(t, len(octets), 1)
+	Assert(t, octets["messageOctetsHEX"] != "", "message octets should be set")
+	parsed = Message{}
+	err = json.Unmarshal(data, &parsed)
+	Assert(t, err == nil, "unmarshal message octets failed %v", err)
+	WireMatch(t, messageWire(msg), messageWire(&parsed))
+	Equal(t, len(parsed.Edns.Options), len(msg.Edns.Options))
+}
+
+func TestMessageJSONWithTsig(t *testing.T) {
+	wire := []byte{186, 134, 40, 0, 0, 1, 0, 0, 0, 1, 0, 1, 1, 97, 4, 116, 101, 115, 116, 0, 0, 6, 0, 1, 2, 103, 103, 192, 12, 0, 1, 0, 1, 0, 0, 14, 16, 0, 4, 1, 1, 1, 7, 192, 14, 0, 250, 0, 255, 0, 0, 0, 0, 0, 58, 8, 104, 109, 97, 99, 45, 109, 100, 53, 7, 115, 105, 103, 45, 97, 108, 103, 3, 114, 101, 103, 3, 105, 110, 116, 0, 0, 0, 89, 71, 159, 60, 1, 44, 0, 16, 179, 15, 124, 89, 45, 67, 115, 11, 208, 10, 100, 75, 194, 99, 65, 185, 186, 134, 0, 0, 0, 0}
+	msg, err := MessageFromWire(util.NewInputBuffer(wire))
+	Assert(t, err == nil, "message with tsig is valid")
+
+	reparsed, err := MessageFromWire(util.NewInputBuffer(msg.wire()))
+	Assert(t, err == nil, "wire with unsigned tsig is valid")
+	Equal(t, reparsed.String(), msg.String())
+
+	data, err := json.Marshal(msg)
+	Assert(t, err == nil, "marshal message failed %v", err)
+	var parsed Message
+	err = json.Unmarshal(data, &parsed)
+	Assert(t, err == nil, "unmarshal message failed %v", err)
+	Assert(t, parsed.Tsig != nil, "tsig should be parsed")
+	Equal(t, parsed.Tsig.Header.Name.String(false), "test.")
+	Equal(t, parsed.Tsig.Algorithm, msg.Tsig.Algorithm)
+	Equal(t, parsed.Tsig.TimeSigned, uint64(1497866044))
+	Assert(t, bytes.Equal(parsed.Tsig.MAC, msg.Tsig.MAC), "tsig mac should be same")
+	Equal(t, parsed.wire(), msg.wire())
+
+	data, _ = msg.MarshalJSONOctets()
+	parsed = Message{}
+	err = json.Unmarshal(data, &parsed)
+	Assert(t, err == nil, "unmarshal message octets failed %v", err)
+	Equal(t, parsed.Tsig.OrigId, uint16(47750))
+}
+
+func TestMessageJSONFromRFCExample(t *testing.T) {
+	data := []byte(`{"ID": 19678, "QR": 0, "Opcode": 0, "AA": 0, "TC": 0, "RD": 1,
+		"RA": 0, "AD": 0, "CD": 0, "RCODE": 0, "QDCOUNT": 1, "ANCOUNT": 0,
+		"NSCOUNT": 0, "ARCOUNT": 0, "QNAME": "example.com", "QTYPE": 1,
+		"QCLASS": 1}`)
+	var msg Message
+	err := json.Unmarshal(data, &msg)
+	Assert(t, err == nil, "unmarshal rfc example failed %v", err)
+	Equal(t, msg.Header.Id, uint16(19678))
+	Equal(t, msg.Header.GetFlag(FLAG_RD), true)
+	Equal(t, msg.Header.GetFlag(FLAG_QR), false)
+	Equal(t, msg.Header.QDCount, uint16(1))
+	NameEqToStr(t, msg.Question.Name, "example.com.")
+	Equal(t, msg.Question.Type, RR_A)
+
+	for _, invalid := range []string{
+		`{"QR": false}`,
+		`{"ID": 70000}`,
+		`{"ID": 1, "QR": 2}`,
+		`{"ID": 1, "QNAME": "example.com", "QCLASS": 1}`,
+		`{"ID": 1, "answerRRs": [{"NAME": "example.com", "TYPE": 1, "CLASS": 1, "RDATAHEX": "C0"}]}`,
+		`{"messageOctetsHEX": "zz"}`,
+	} {
+		err := json.Unmarshal([]byte(invalid), &msg)
+		Assert(t, err != nil, "%s should be invalid", invalid)
+	}
+}
+
+func TestRRsetJSON(t *testing.T) {
+	rrset, _ := RRsetFromString("example.com. 300 IN MX 10 mail.example.com.")
+	rdata, _ := MXFromString("20 mail2.example.com.")
+	rrset.AddRdata(rdata)
+
+	data, err := json.Marshal(rrset)
+	Assert(t, err == nil, "marshal rrset failed %v", err)
+	var rrs []map[string]interface{}
+	json.Unmarshal(data, &rrs)
+	Equal(t, len(rrs), 2)
+	Equal(t, rrs[0]["NAME"], "example.com.")
+	Equal(t, rrs[0]["TYPEname"], "MX")
+	Equal(t, rrs[0]["CLASSname"], "IN")
+	Equal(t, rrs[1]["rdataMX"], "20 mail2.example.com.")
+
+	var parsed RRset
+	err = json.Unmarshal(data, &parsed)
+	Assert(t, err == nil, "unmarshal rrset failed %v", err)
+	Assert(t, parsed.Equals(rrset), "rrset should be same after unmarshal")
+
+	//single rr with presentation format only
+	err = json.Unmarshal([]byte(`{"NAME": "www.example.com", "TYPEname": "A", "CLASSname": "IN", "TTL": 60, "rdataA": "192.0.2.1"}`), &parsed)
+	Assert(t, err == nil, "unmarshal rr failed %v", err)
+	expected, _ := RRsetFromString("www.example.com. 60 IN A 192.0.2.1")
+	Assert(t, parsed.Equals(expected), "rr should be parsed from presentation format")
+
+	err = json.Unmarshal([]byte(`[{"NAME": "a.example.com", "TYPE": 1, "CLASS": 1, "rdataA": "192.0.2.1"},
+		{"NAME": "b.example.com", "TYPE": 1, "CLASS": 1, "rdataA": "192.0.2.2"}]`), &parsed)
+	Equal(t, err, ErrJSONRRsetMismatch)
+}
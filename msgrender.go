@@ -134,11 +134,23 @@ func (r *MsgRender) Clear() {
 	}
 }
 
+// hash of each suffix is calculated incrementally from root label, so
+// all the suffixes are hashed in one pass
+func (r *MsgRender) suffixHashs(name *Name) {
+	last := name.labelCount - 1
+	hash := hashRaw(name.raw[name.offsets[last]:], r.caseSensitive)
+	for i := int(last) - 1; i >= 0; i-- {
+		hash = hashRawFrom(hash, name.raw[name.offsets[i]:name.offsets[i+1]], r.caseSensitive)
+		r.seqHashs[i] = hash
+	}
+}
+
 func (r *MsgRender) WriteName(name *Name, compress bool) {
 	nlables := name.LabelCount()
 	var nlabelsUncomp uint
 	ptrOffset := NO_OFFSET
 
+	r.suffixHashs(name)
 	ref := fromName(name)
 	var parentBuf util.InputBuffer
 	for nlabelsUncomp = 0; nlabelsUncomp < nlables; nlabelsUncomp++ {
@@ -151,7 +163,6 @@ func (r *MsgRender) WriteName(name *Name, compress bool) {
 			break
 		}
 
-		r.seqHashs[nlabelsUncomp] = ref.Hash(r.caseSensitive)
 		if compress {
 			parentBuf.SetData(ref.Raw())
 			ptrOffset = r.findOffset(r.buf, &parentBuf, r.seqHashs[nlabelsUncomp])
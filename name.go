@@ -283,13 +283,33 @@ func NameFromStringUnsafe(s string) *Name {
 	return name
 }
 
+var (
+	ErrTooLongName           = errors.New("too long name")
+	ErrUnknownLabelType      = errors.New("unknown label character")
+	ErrBadCompressionPointer = errors.New("bad compression pointer")
+	ErrIncompleteName        = errors.New("imcomplete wire format")
+)
+
 func NameFromWire(buf *util.InputBuffer, downcase bool) (*Name, error) {
+	//5, 15 is the experienced value for label and name len
+	name := &Name{
+		raw:     make([]byte, 0, 15),
+		offsets: make([]byte, 0, 5),
+	}
+	if err := name.FromWire(buf, downcase); err != nil {
+		return nil, err
+	}
+	return name, nil
+}
+
+//FromWire reuses the storage of name, so decoding into a name with
+//enough capacity doesn't allocate, name shouldn't be shared with others
+func (name *Name) FromWire(buf *util.InputBuffer, downcase bool) error {
 	n := uint(0)
 	nused := uint(0)
 	done := false
-	//5, 15 is the experienced value for label and name len
-	offsets := make([]byte, 0, 5)
-	raw := make([]byte, 0, 15)
+	offsets := name.offsets[:0]
+	raw := name.raw[:0]
 	seenPointer := false
 	state := fwStart
 	cused := uint(0)
@@ -311,7 +331,7 @@ func NameFromWire(buf *util.InputBuffer, downcase bool) (*Name, error) {
 			if c <= MAX_LABEL_LEN {
 				offsets = append(offsets, byte(nused))
 				if nused+uint(c)+1 > MAX_WIRE {
-					return nil, errors.New("too long name")
+					return ErrTooLongName
 				}
 
 				nused = nused + uint(c) + 1
@@ -326,7 +346,7 @@ func NameFromWire(buf *util.InputBuffer, downcase bool) (*Name, error) {
 				n = 1
 				state = fwNewCurrent
 			} else {
-				return nil, errors.New("unknown label character")
+				return ErrUnknownLabelType
 			}
 		case fwOrdinary:
 			if downcase {
@@ -345,7 +365,7 @@ func NameFromWire(buf *util.InputBuffer, downcase bool) (*Name, error) {
 				break
 			}
 			if newCurrent >= biggestPointer {
-				return nil, errors.New("bad compression pointer")
+				return ErrBadCompressionPointer
 			}
 			biggestPointer = newCurrent
 			current = newCurrent
@@ -358,11 +378,15 @@ func NameFromWire(buf *util.InputBuffer, downcase bool) (*Name, error) {
 	}
 
 	if done == false {
-		return nil, errors.New("imcomplete wire format")
+		return ErrIncompleteName
 	}
 
 	buf.SetPosition(posBegin + cused)
-	return &Name{raw, offsets, uint(len(raw)), uint(len(offsets))}, nil
+	name.raw = raw
+	name.offsets = offsets
+	name.length = uint(len(raw))
+	name.labelCount = uint(len(offsets))
+	return nil
 }
 
 func (name *Name) Length() uint {
@@ -684,8 +708,13 @@ func (name *Name) Hash(caseSensitive bool) uint32 {
 }
 
 func hashRaw(raw []byte, caseSensitive bool) uint32 {
+	return hashRawFrom(0, raw, caseSensitive)
+}
+
+//hash could be calculated incrementally, which is used to hash name
+//which isn't stored continuously like compressed name in message
+func hashRawFrom(hash uint32, raw []byte, caseSensitive bool) uint32 {
 	hashLen := len(raw)
-	hash := uint32(0)
 	if caseSensitive {
 		for i := 0; i < hashLen; i++ {
 			hash ^= uint32(raw[i]) + 0x9e3779b9 + (hash << 6) + (hash >> 2)
@@ -0,0 +1,54 @@
+package g53
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const idnaACEPrefix = "xn--"
+
+// uts46 nontransitional processing, underscore used by srv and other
+// service labels isn't rejected, since they are valid dns labels
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+)
+
+// NameFromUnicode converts unicode name into punycode with idna mapping
+// and validation, ascii label is kept as it is, so NameFromUnicode
+// works as NameFromString for ascii only name
+func NameFromUnicode(s string) (*Name, error) {
+	ascii, err := idnaProfile.ToASCII(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid idn %s: %s", s, err.Error())
+	}
+	return NameFromString(ascii)
+}
+
+// ToUnicode converts punycode labels back into unicode, other labels
+// are in the same format as String
+func (name *Name) ToUnicode() (string, error) {
+	if name.IsRoot() {
+		return ".", nil
+	}
+
+	var buf strings.Builder
+	for i := uint(0); i+1 < name.labelCount; i++ {
+		label, _ := name.Split(i, 1)
+		s := label.String(true)
+		if len(s) > len(idnaACEPrefix) && strings.EqualFold(s[:len(idnaACEPrefix)], idnaACEPrefix) {
+			u, err := idnaProfile.ToUnicode(s)
+			if err != nil {
+				return "", fmt.Errorf("invalid idn label %s: %s", s, err.Error())
+			}
+			s = u
+		}
+		buf.WriteString(s)
+		buf.WriteByte('.')
+	}
+	return buf.String(), nil
+}
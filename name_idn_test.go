@@ -0,0 +1,52 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestNameFromUnicode(t *testing.T) {
+	cases := []struct {
+		unicode string
+		ascii   string
+		back    string
+	}{
+		{"中国.cn", "xn--fiqs8s.cn.", "中国.cn."},
+		{"例子.测试.", "xn--fsqu00a.xn--0zwm56d.", "例子.测试."},
+		{"Bücher.Example", "xn--bcher-kva.example.", "bücher.example."},
+		{"faß.de", "xn--fa-hia.de.", "faß.de."},
+		{"ＡＢＣ。com", "abc.com.", "abc.com."},
+		{"_sip._tcp.例子.com", "_sip._tcp.xn--fsqu00a.com.", "_sip._tcp.例子.com."},
+		{"*.例子.com", "*.xn--fsqu00a.com.", "*.例子.com."},
+		{"www.knet.cn", "www.knet.cn.", "www.knet.cn."},
+		{".", ".", "."},
+	}
+
+	for _, c := range cases {
+		name, err := NameFromUnicode(c.unicode)
+		Assert(t, err == nil, "parse %s failed:%v", c.unicode, err)
+		Equal(t, name.String(false), c.ascii)
+		u, err := name.ToUnicode()
+		Assert(t, err == nil, "%s to unicode failed:%v", c.ascii, err)
+		Equal(t, u, c.back)
+	}
+
+	for _, s := range []string{
+		"-abc.com",
+		"xn--zz.com",
+		"a..b",
+		"1א.com",
+		"a\u0378.com",
+	} {
+		_, err := NameFromUnicode(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+
+	_, err := NameFromStringUnsafe("xn--zz.com").ToUnicode()
+	Assert(t, err != nil, "invalid punycode label should fail")
+
+	name := NameFromStringUnsafe("a\\.b.xn--fiqs8s")
+	u, err := name.ToUnicode()
+	Assert(t, err == nil, "to unicode failed:%v", err)
+	Equal(t, u, "a\\.b.中国.")
+	Equal(t, name.String(false), "a\\.b.xn--fiqs8s.")
+}
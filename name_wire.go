@@ -0,0 +1,267 @@
+package g53
+
+import (
+	"github.com/zdnscloud/g53/util"
+)
+
+// WireName is a borrowed view of a name in wire format message, the
+// name isn't copied out, compression pointers are followed on demand,
+// so it's only valid while the underlying message isn't modified
+type WireName struct {
+	msg        []byte
+	pos        uint
+	length     uint
+	labelCount uint
+}
+
+// WireNameFromWire validates the name at current position of buf with
+// the same rules as NameFromWire, and moves buf after the name
+func WireNameFromWire(buf *util.InputBuffer) (WireName, error) {
+	msg := buf.Data()[:buf.Len()]
+	begin := buf.Position()
+	current := begin
+	biggestPointer := begin
+	end := uint(0)
+	seenPointer := false
+	length := uint(0)
+	labelCount := uint(0)
+
+	for {
+		if current >= uint(len(msg)) {
+			return WireName{}, ErrIncompleteName
+		}
+
+		c := msg[current]
+		if c <= MAX_LABEL_LEN {
+			if length+uint(c)+1 > MAX_WIRE {
+				return WireName{}, ErrTooLongName
+			}
+			if current+uint(c)+1 > uint(len(msg)) {
+				return WireName{}, ErrIncompleteName
+			}
+			length += uint(c) + 1
+			labelCount += 1
+			current += uint(c) + 1
+			if c == 0 {
+				break
+			}
+		} else if c&COMPRESS_POINTER_MARK8 == COMPRESS_POINTER_MARK8 {
+			if current+2 > uint(len(msg)) {
+				return WireName{}, ErrIncompleteName
+			}
+			pointer := uint(c & ^uint8(COMPRESS_POINTER_MARK8))*256 + uint(msg[current+1])
+			if pointer >= biggestPointer {
+				return WireName{}, ErrBadCompressionPointer
+			}
+			biggestPointer = pointer
+			if seenPointer == false {
+				end = current + 2
+				seenPointer = true
+			}
+			current = pointer
+		} else {
+			return WireName{}, ErrUnknownLabelType
+		}
+	}
+
+	if seenPointer == false {
+		end = current
+	}
+	buf.SetPosition(end)
+	return WireName{
+		msg:        msg,
+		pos:        begin,
+		length:     length,
+		labelCount: labelCount,
+	}, nil
+}
+
+// follow compression pointers until a label, return its position
+func (w *WireName) labelAt(pos uint) uint {
+	for w.msg[pos]&COMPRESS_POINTER_MARK8 == COMPRESS_POINTER_MARK8 {
+		pos = uint(w.msg[pos] & ^uint8(COMPRESS_POINTER_MARK8))*256 + uint(w.msg[pos+1])
+	}
+	return pos
+}
+
+func (w *WireName) Length() uint {
+	return w.length
+}
+
+func (w *WireName) LabelCount() uint {
+	return w.labelCount
+}
+
+func (w *WireName) IsRoot() bool {
+	return w.labelCount == 1
+}
+
+// Hash returns the same value as Hash of the decoded name
+func (w *WireName) Hash(caseSensitive bool) uint32 {
+	hash := uint32(0)
+	pos := w.pos
+	for i := uint(0); i < w.labelCount; i++ {
+		pos = w.labelAt(pos)
+		next := pos + uint(w.msg[pos]) + 1
+		hash = hashRawFrom(hash, w.msg[pos:next], caseSensitive)
+		pos = next
+	}
+	return hash
+}
+
+// Equals compares with name case insensitively without decoding
+func (w *WireName) Equals(name *Name) bool {
+	if w.length != name.length || w.labelCount != name.labelCount {
+		return false
+	}
+
+	pos := w.pos
+	for i := uint(0); i < w.labelCount; i++ {
+		pos = w.labelAt(pos)
+		next := pos + uint(w.msg[pos]) + 1
+		if equalsIgnoreCase(w.msg[pos:next], name.raw[name.offsets[i]:]) == false {
+			return false
+		}
+		pos = next
+	}
+	return true
+}
+
+func (w *WireName) EqualsWire(other *WireName) bool {
+	if w.length != other.length || w.labelCount != other.labelCount {
+		return false
+	}
+
+	pos, otherPos := w.pos, other.pos
+	for i := uint(0); i < w.labelCount; i++ {
+		pos, otherPos = w.labelAt(pos), other.labelAt(otherPos)
+		next := pos + uint(w.msg[pos]) + 1
+		if equalsIgnoreCase(w.msg[pos:next], other.msg[otherPos:]) == false {
+			return false
+		}
+		otherPos += next - pos
+		pos = next
+	}
+	return true
+}
+
+// label is compared with the same length prefix of other
+func equalsIgnoreCase(label []byte, other []byte) bool {
+	for i, c := range label {
+		if maptolower[c] != maptolower[other[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// AppendRaw appends uncompressed wire format of the name to raw and the
+// offset of each label to offsets
+func (w *WireName) AppendRaw(raw []byte, offsets []byte, downcase bool) ([]byte, []byte) {
+	base := len(raw)
+	pos := w.pos
+	for i := uint(0); i < w.labelCount; i++ {
+		pos = w.labelAt(pos)
+		next := pos + uint(w.msg[pos]) + 1
+		offsets = append(offsets, byte(len(raw)-base))
+		if downcase {
+			raw = append(raw, w.msg[pos])
+			for _, c := range w.msg[pos+1 : next] {
+				raw = append(raw, maptolower[c])
+			}
+		} else {
+			raw = append(raw, w.msg[pos:next]...)
+		}
+		pos = next
+	}
+	return raw, offsets
+}
+
+// CopyTo decodes the view into name and reuses its storage
+func (w *WireName) CopyTo(name *Name, downcase bool) {
+	name.raw, name.offsets = w.AppendRaw(name.raw[:0], name.offsets[:0], downcase)
+	name.length = w.length
+	name.labelCount = w.labelCount
+}
+
+func (w *WireName) ToName(downcase bool) *Name {
+	name := &Name{
+		raw:     make([]byte, 0, w.length),
+		offsets: make([]byte, 0, w.labelCount),
+	}
+	w.CopyTo(name, downcase)
+	return name
+}
+
+func (w *WireName) String(omitFinalDot bool) string {
+	return w.ToName(false).String(omitFinalDot)
+}
+
+// NameArena allocates names from preallocated storage, names from arena
+// are valid until Reset, after that the storage will be reused by new
+// names, when storage is used up, names are allocated from heap
+type NameArena struct {
+	names   []Name
+	data    []byte
+	scratch Name
+}
+
+// average name with 8 labels and 64 bytes
+const arenaBytesPerName = 72
+
+func NewNameArena(nameCount int) *NameArena {
+	return &NameArena{
+		names: make([]Name, 0, nameCount),
+		data:  make([]byte, 0, nameCount*arenaBytesPerName),
+		scratch: Name{
+			raw:     make([]byte, 0, MAX_WIRE),
+			offsets: make([]byte, 0, MAX_LABELS),
+		},
+	}
+}
+
+func (a *NameArena) NameFromWire(buf *util.InputBuffer, downcase bool) (*Name, error) {
+	if err := a.scratch.FromWire(buf, downcase); err != nil {
+		return nil, err
+	}
+	return a.alloc(&a.scratch), nil
+}
+
+func (a *NameArena) NameFromWireName(w *WireName, downcase bool) *Name {
+	w.CopyTo(&a.scratch, downcase)
+	return a.alloc(&a.scratch)
+}
+
+func (a *NameArena) alloc(src *Name) *Name {
+	var name *Name
+	if len(a.names) < cap(a.names) {
+		a.names = a.names[:len(a.names)+1]
+		name = &a.names[len(a.names)-1]
+	} else {
+		name = &Name{}
+	}
+
+	size := len(src.raw) + len(src.offsets)
+	var mem []byte
+	if used := len(a.data); used+size <= cap(a.data) {
+		a.data = a.data[:used+size]
+		mem = a.data[used : used+size : used+size]
+	} else {
+		mem = make([]byte, size)
+	}
+
+	//limit the capacity, so append to the name won't overwrite others
+	rawLen := len(src.raw)
+	copy(mem, src.raw)
+	copy(mem[rawLen:], src.offsets)
+	name.raw = mem[:rawLen:rawLen]
+	name.offsets = mem[rawLen:]
+	name.length = src.length
+	name.labelCount = src.labelCount
+	return name
+}
+
+func (a *NameArena) Reset() {
+	a.names = a.names[:0]
+	a.data = a.data[:0]
+}
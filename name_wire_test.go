@@ -0,0 +1,229 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// a.example.com, b.example.com with pointer to example.com, and pointer
+// to b.example.com
+const compressedNamesWire = "0161076578616d706c6503636f6d000162c002c00f"
+
+// query for test.example.com A with edns
+const typicalQueryWire = "04b0010000010000000000010474657374076578616d706c6503636f6d00000100010000291000000000000000"
+
+func TestWireNameFromWire(t *testing.T) {
+	wire, _ := util.HexStrToBytes(compressedNamesWire)
+	buf := util.NewInputBuffer(wire)
+	wireBuf := util.NewInputBuffer(wire)
+	expects := []string{"a.example.com.", "b.example.com.", "b.example.com."}
+	for _, expect := range expects {
+		name, err := NameFromWire(buf, false)
+		Assert(t, err == nil, "name from wire failed:%v", err)
+		w, err := WireNameFromWire(wireBuf)
+		Assert(t, err == nil, "wire name from wire failed:%v", err)
+		Equal(t, wireBuf.Position(), buf.Position())
+
+		Equal(t, w.String(false), expect)
+		Equal(t, w.Length(), name.Length())
+		Equal(t, w.LabelCount(), name.LabelCount())
+		Equal(t, w.Hash(false), name.Hash(false))
+		Equal(t, w.Hash(true), name.Hash(true))
+		Assert(t, w.Equals(name), "wire name should equal to decoded name")
+		Assert(t, w.ToName(false).CaseSensitiveEquals(name), "wire name to name should equal to decoded name")
+	}
+
+	buf.SetPosition(15)
+	b, _ := WireNameFromWire(buf)
+	buf.SetPosition(19)
+	pointerToB, _ := WireNameFromWire(buf)
+	buf.SetPosition(0)
+	a, _ := WireNameFromWire(buf)
+	Assert(t, b.EqualsWire(&pointerToB), "same name with different compression should be equal")
+	Assert(t, a.EqualsWire(&b) == false, "different names shouldn't be equal")
+	Assert(t, a.Equals(NameFromStringUnsafe("A.EXAMPLE.COM")), "wire name compare should be case insensitive")
+	Assert(t, a.Equals(NameFromStringUnsafe("example.com")) == false, "different names shouldn't be equal")
+}
+
+func TestWireNameKeepCase(t *testing.T) {
+	name, _ := NewName("wWw.Knet.cn", false)
+	render := NewMsgRender()
+	name.Rend(render)
+	w, err := WireNameFromWire(util.NewInputBuffer(render.Data()))
+	Assert(t, err == nil, "wire name from wire failed:%v", err)
+	Equal(t, w.ToName(false).String(false), "wWw.Knet.cn.")
+	Equal(t, w.ToName(true).String(false), "www.knet.cn.")
+	Assert(t, w.Hash(true) != NameFromStringUnsafe("www.knet.cn").Hash(true), "case sensitive hash should differ")
+	Equal(t, w.Hash(false), NameFromStringUnsafe("www.knet.cn").Hash(false))
+}
+
+func TestMalformedWireName(t *testing.T) {
+	for _, tc := range []struct {
+		wire string
+		err  error
+	}{
+		{"0161076578616d706c65", ErrIncompleteName},
+		{"01610765786170", ErrIncompleteName},
+		{"0161c0", ErrIncompleteName},
+		{"0161c000", ErrBadCompressionPointer},
+		{"0161c005", ErrBadCompressionPointer},
+		{"016180", ErrUnknownLabelType},
+	} {
+		wire, _ := util.HexStrToBytes(tc.wire)
+		_, err := NameFromWire(util.NewInputBuffer(wire), false)
+		Equal(t, err, tc.err)
+		_, err = WireNameFromWire(util.NewInputBuffer(wire))
+		Equal(t, err, tc.err)
+	}
+
+	var wire []byte
+	for i := 0; i < 5; i++ {
+		wire = append(wire, 63)
+		for j := 0; j < 63; j++ {
+			wire = append(wire, 'a')
+		}
+	}
+	wire = append(wire, 0)
+	_, err := NameFromWire(util.NewInputBuffer(wire), false)
+	Equal(t, err, ErrTooLongName)
+	_, err = WireNameFromWire(util.NewInputBuffer(wire))
+	Equal(t, err, ErrTooLongName)
+}
+
+func TestNameArena(t *testing.T) {
+	wire, _ := util.HexStrToBytes(compressedNamesWire)
+	buf := util.NewInputBuffer(wire)
+	arena := NewNameArena(2)
+	var names []*Name
+	for i := 0; i < 3; i++ {
+		name, err := arena.NameFromWire(buf, true)
+		Assert(t, err == nil, "arena name from wire failed:%v", err)
+		names = append(names, name)
+	}
+	NameEqToStr(t, names[0], "a.example.com")
+	NameEqToStr(t, names[1], "b.example.com")
+	NameEqToStr(t, names[2], "b.example.com")
+
+	//append to name from arena shouldn't overwrite its neighbor
+	concat, _ := names[0].Concat(NameFromStringUnsafe("cn"))
+	NameEqToStr(t, concat, "a.example.com.cn")
+	NameEqToStr(t, names[1], "b.example.com")
+
+	arena.Reset()
+	buf.SetPosition(15)
+	w, _ := WireNameFromWire(buf)
+	name := arena.NameFromWireName(&w, true)
+	NameEqToStr(t, name, "b.example.com")
+	Assert(t, name == &arena.names[0], "arena should be reused after reset")
+}
+
+func TestQuestionFromWireReuse(t *testing.T) {
+	wire, _ := util.HexStrToBytes(typicalQueryWire)
+	buf := util.NewInputBuffer(wire)
+	var h Header
+	var q Question
+	Assert(t, HeaderFromWire(&h, buf) == nil, "parse header failed")
+	Assert(t, q.FromWire(buf) == nil, "parse question failed")
+	NameEqToStr(t, q.Name, "test.example.com")
+	Equal(t, q.Type, RR_A)
+	Equal(t, q.Class, CLASS_IN)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.SetPosition(0)
+		HeaderFromWire(&h, buf)
+		q.FromWire(buf)
+	})
+	Equal(t, allocs, float64(0))
+
+	arena := NewNameArena(4)
+	allocs = testing.AllocsPerRun(100, func() {
+		arena.Reset()
+		buf.SetPosition(12)
+		arena.NameFromWire(buf, true)
+	})
+	Equal(t, allocs, float64(0))
+
+	allocs = testing.AllocsPerRun(100, func() {
+		buf.SetPosition(12)
+		w, _ := WireNameFromWire(buf)
+		w.Hash(false)
+		w.Equals(q.Name)
+	})
+	Equal(t, allocs, float64(0))
+}
+
+func BenchmarkNameFromWire(b *testing.B) {
+	wire, _ := util.HexStrToBytes(compressedNamesWire)
+	buf := util.NewInputBuffer(wire)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		for j := 0; j < 3; j++ {
+			NameFromWire(buf, true)
+		}
+	}
+}
+
+func BenchmarkNameFromWireReuse(b *testing.B) {
+	wire, _ := util.HexStrToBytes(compressedNamesWire)
+	buf := util.NewInputBuffer(wire)
+	var name Name
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		for j := 0; j < 3; j++ {
+			name.FromWire(buf, true)
+		}
+	}
+}
+
+func BenchmarkNameArenaFromWire(b *testing.B) {
+	wire, _ := util.HexStrToBytes(compressedNamesWire)
+	buf := util.NewInputBuffer(wire)
+	arena := NewNameArena(3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		arena.Reset()
+		for j := 0; j < 3; j++ {
+			arena.NameFromWire(buf, true)
+		}
+	}
+}
+
+func BenchmarkWireNameFromWire(b *testing.B) {
+	wire, _ := util.HexStrToBytes(compressedNamesWire)
+	buf := util.NewInputBuffer(wire)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		for j := 0; j < 3; j++ {
+			w, _ := WireNameFromWire(buf)
+			w.Hash(false)
+		}
+	}
+}
+
+func BenchmarkParseQuery(b *testing.B) {
+	wire, _ := util.HexStrToBytes(typicalQueryWire)
+	buf := util.NewInputBuffer(wire)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		MessageFromWire(buf)
+	}
+}
+
+func BenchmarkParseQueryReuse(b *testing.B) {
+	wire, _ := util.HexStrToBytes(typicalQueryWire)
+	buf := util.NewInputBuffer(wire)
+	var h Header
+	var q Question
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.SetPosition(0)
+		HeaderFromWire(&h, buf)
+		q.FromWire(buf)
+	}
+}
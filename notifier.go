@@ -0,0 +1,113 @@
+package g53
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrNotifyNotAcked = errors.New("notify isn't acknowledged")
+
+const (
+	DefaultNotifyTimeout       = 2 * time.Second
+	DefaultNotifyRetries       = 5
+	DefaultNotifyRetryInterval = time.Second
+)
+
+// Notifier send notify of a zone to secondaries, the notify to each
+// secondary is resent with exponential backoff until it's acknowledged
+// or retries are used up
+type Notifier struct {
+	Secondaries   []string //address in host:port format
+	Timeout       time.Duration
+	Retries       int
+	RetryInterval time.Duration
+}
+
+func NewNotifier(secondaries []string) *Notifier {
+	return &Notifier{
+		Secondaries:   secondaries,
+		Timeout:       DefaultNotifyTimeout,
+		Retries:       DefaultNotifyRetries,
+		RetryInterval: DefaultNotifyRetryInterval,
+	}
+}
+
+// return the error of each secondary, nil means the notify is acknowledged
+func (n *Notifier) Notify(zone *Name, soa *RRset) map[string]error {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error)
+	for _, secondary := range n.Secondaries {
+		wg.Add(1)
+		go func(secondary string) {
+			defer wg.Done()
+			err := n.notifyOne(secondary, zone, soa)
+			lock.Lock()
+			results[secondary] = err
+			lock.Unlock()
+		}(secondary)
+	}
+	wg.Wait()
+	return results
+}
+
+func (n *Notifier) notifyOne(secondary string, zone *Name, soa *RRset) error {
+	msg := MakeNotify(zone, soa)
+	msg.RecalculateSectionRRCount()
+	render := NewMsgRender()
+	msg.Rend(render)
+	query := render.Data()
+
+	conn, err := util.NewUDPConn(secondary)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	interval := n.RetryInterval
+	for i := 0; i <= n.Retries; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+			interval *= 2
+		}
+
+		if err = util.UDPWrite(query, conn); err != nil {
+			continue
+		}
+
+		if err = n.waitAck(conn, msg); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (n *Notifier) waitAck(conn *net.UDPConn, msg *Message) error {
+	deadline := time.Now().Add(n.Timeout)
+	for {
+		timeout := deadline.Sub(time.Now())
+		if timeout <= 0 {
+			return ErrNotifyNotAcked
+		}
+
+		data, err := util.UDPRead(conn, timeout)
+		if err != nil {
+			return err
+		}
+
+		resp, err := MessageFromWire(util.NewInputBuffer(data))
+		if err != nil || resp.Header.Id != msg.Header.Id {
+			continue
+		}
+
+		if msg.IsNotifyAck(resp) {
+			return nil
+		} else {
+			return ErrNotifyNotAcked
+		}
+	}
+}
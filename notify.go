@@ -0,0 +1,101 @@
+package g53
+
+import (
+	"errors"
+	"net"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrNotNotify             = errors.New("message isn't a notify")
+	ErrNotifyIsResponse      = errors.New("notify message is a response")
+	ErrNotifyQuestionInvalid = errors.New("notify question should be soa of class in")
+	ErrNotifyNoAuthFlag      = errors.New("notify message hasn't aa flag")
+	ErrNotifySourceRefused   = errors.New("notify source isn't an allowed primary")
+)
+
+// soa is optional, if it's provided, it will be put into answer section
+// as hint of the new serial
+func MakeNotify(zone *Name, soa *RRset) *Message {
+	h := Header{}
+	h.Opcode = OP_NOTIFY
+	h.Id = util.GenMessageId()
+	h.SetFlag(FLAG_AA, true)
+	h.QDCount = 1
+
+	q := &Question{
+		Name:  zone,
+		Type:  RR_SOA,
+		Class: CLASS_IN,
+	}
+
+	msg := &Message{
+		Header:   h,
+		Question: q,
+	}
+	if soa != nil {
+		msg.AddRRset(AnswerSection, soa)
+		msg.Header.ANCount = uint16(msg.SectionRRCount(AnswerSection))
+	}
+	return msg
+}
+
+// check the notify message send from source, if primaries is empty
+// source address won't be checked
+func (m *Message) ValidateNotify(source net.IP, primaries []net.IP) error {
+	if m.Header.Opcode != OP_NOTIFY {
+		return ErrNotNotify
+	}
+
+	if m.Header.GetFlag(FLAG_QR) {
+		return ErrNotifyIsResponse
+	}
+
+	if m.Question == nil || m.Question.Type != RR_SOA || m.Question.Class != CLASS_IN {
+		return ErrNotifyQuestionInvalid
+	}
+
+	if m.Header.GetFlag(FLAG_AA) == false {
+		return ErrNotifyNoAuthFlag
+	}
+
+	if len(primaries) == 0 {
+		return nil
+	}
+
+	for _, primary := range primaries {
+		if primary.Equal(source) {
+			return nil
+		}
+	}
+	return ErrNotifySourceRefused
+}
+
+// the serial carried in answer section, return false if there is no soa
+func (m *Message) NotifySerial() (uint32, bool) {
+	for _, rrset := range m.Sections[AnswerSection] {
+		if rrset.Type == RR_SOA && len(rrset.Rdatas) > 0 {
+			return rrset.Rdatas[0].(*SOA).Serial, true
+		}
+	}
+	return 0, false
+}
+
+func (m *Message) MakeNotifyResponse(rcode Rcode) *Message {
+	resp := m.MakeResponse()
+	resp.Header.SetFlag(FLAG_RD, false)
+	resp.Header.SetFlag(FLAG_AA, true)
+	resp.Header.Rcode = rcode
+	return resp
+}
+
+// whether resp is the acknowledgement of notify
+func (m *Message) IsNotifyAck(resp *Message) bool {
+	return resp.Header.Id == m.Header.Id &&
+		resp.Header.Opcode == OP_NOTIFY &&
+		resp.Header.GetFlag(FLAG_QR) &&
+		resp.Header.Rcode == R_NOERROR &&
+		resp.Question != nil &&
+		resp.Question.Equals(m.Question)
+}
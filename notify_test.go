@@ -0,0 +1,98 @@
+package g53
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestMakeAndValidateNotify(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	soa, _ := SOAFromString("ns1.example.com. root.example.com. 2019010101 3600 600 86400 300")
+	msg := MakeNotify(zone, &RRset{
+		Name:   zone,
+		Type:   RR_SOA,
+		Class:  CLASS_IN,
+		Ttl:    RRTTL(3600),
+		Rdatas: []Rdata{soa},
+	})
+
+	render := NewMsgRender()
+	msg.Rend(render)
+	msg, err := MessageFromWire(util.NewInputBuffer(render.Data()))
+	Assert(t, err == nil, "parse notify failed:%v", err)
+	Equal(t, msg.Header.Opcode, OP_NOTIFY)
+	serial, ok := msg.NotifySerial()
+	Assert(t, ok, "notify should include soa")
+	Equal(t, serial, uint32(2019010101))
+
+	primary := net.ParseIP("192.0.2.1")
+	Equal(t, msg.ValidateNotify(primary, nil), nil)
+	Equal(t, msg.ValidateNotify(primary, []net.IP{primary}), nil)
+	Equal(t, msg.ValidateNotify(net.ParseIP("192.0.2.2"), []net.IP{primary}), ErrNotifySourceRefused)
+
+	msg.Header.SetFlag(FLAG_AA, false)
+	Equal(t, msg.ValidateNotify(primary, nil), ErrNotifyNoAuthFlag)
+	msg.Header.SetFlag(FLAG_AA, true)
+	msg.Question.Type = RR_A
+	Equal(t, msg.ValidateNotify(primary, nil), ErrNotifyQuestionInvalid)
+	msg.Question.Type = RR_SOA
+
+	resp := msg.MakeNotifyResponse(R_NOERROR)
+	Assert(t, resp.Header.GetFlag(FLAG_AA), "notify response should has aa flag")
+	Equal(t, resp.ValidateNotify(primary, nil), ErrNotifyIsResponse)
+	Assert(t, msg.IsNotifyAck(resp), "response should ack the notify")
+	Assert(t, msg.IsNotifyAck(msg.MakeNotifyResponse(R_REFUSED)) == false, "refused isn't ack")
+
+	_, ok = MakeNotify(zone, nil).NotifySerial()
+	Assert(t, ok == false, "notify without soa has no serial")
+}
+
+func runNotifyReceiver(t *testing.T, dropCount int) string {
+	addr, _ := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	conn, err := net.ListenUDP("udp", addr)
+	Assert(t, err == nil, "listen failed:%v", err)
+
+	go func() {
+		defer conn.Close()
+		buf := make([]byte, 512)
+		for {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, client, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			if dropCount > 0 {
+				dropCount--
+				continue
+			}
+
+			msg, err := MessageFromWire(util.NewInputBuffer(buf[:n]))
+			if err != nil {
+				continue
+			}
+			render := NewMsgRender()
+			msg.MakeNotifyResponse(R_NOERROR).Rend(render)
+			conn.WriteToUDP(render.Data(), client)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNotifierRetry(t *testing.T) {
+	secondary := runNotifyReceiver(t, 2)
+	notifier := NewNotifier([]string{secondary})
+	notifier.Timeout = 100 * time.Millisecond
+	notifier.RetryInterval = 10 * time.Millisecond
+	results := notifier.Notify(NameFromStringUnsafe("example.com."), nil)
+	Equal(t, results[secondary], nil)
+
+	secondary = runNotifyReceiver(t, 10)
+	notifier.Secondaries = []string{secondary}
+	notifier.Retries = 1
+	results = notifier.Notify(NameFromStringUnsafe("example.com."), nil)
+	Assert(t, results[secondary] != nil, "notify without ack should fail")
+}
@@ -0,0 +1,194 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+var (
+	ErrUnsupportedLink = errors.New("unsupported link type")
+	ErrTruncated       = errors.New("packet is truncated")
+	ErrNotUDPOrTCP     = errors.New("packet isn't udp or tcp")
+	ErrFragmented      = errors.New("fragmented ip packet isn't supported")
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+
+	protocolTCP = 6
+	protocolUDP = 17
+
+	tcpFlagFIN = 0x01
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+	tcpFlagPSH = 0x08
+	tcpFlagACK = 0x10
+)
+
+// segment is the transport layer payload of a packet
+type segment struct {
+	srcIP   net.IP
+	dstIP   net.IP
+	srcPort uint16
+	dstPort uint16
+	tcp     bool
+	seq     uint32
+	flags   uint8
+	payload []byte
+	//capture time of the packet, used by tcp reassembly
+	time time.Time
+}
+
+func decodePacket(p *Packet) (*segment, error) {
+	data := p.Data
+	var etherType uint16
+	switch p.LinkType {
+	case LinkTypeEthernet:
+		if len(data) < 14 {
+			return nil, ErrTruncated
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			if len(data) < 4 {
+				return nil, ErrTruncated
+			}
+			etherType, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, ErrTruncated
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case LinkTypeNull:
+		//address family in host byte order of the capturing machine
+		if len(data) < 4 {
+			return nil, ErrTruncated
+		}
+		family := binary.LittleEndian.Uint32(data)
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(data)
+		}
+		if family == 2 {
+			etherType = etherTypeIPv4
+		} else {
+			etherType = etherTypeIPv6
+		}
+		data = data[4:]
+	case LinkTypeRaw:
+		if len(data) == 0 {
+			return nil, ErrTruncated
+		}
+		if data[0]>>4 == 4 {
+			etherType = etherTypeIPv4
+		} else {
+			etherType = etherTypeIPv6
+		}
+	case LinkTypeIPv4:
+		etherType = etherTypeIPv4
+	case LinkTypeIPv6:
+		etherType = etherTypeIPv6
+	default:
+		return nil, ErrUnsupportedLink
+	}
+
+	switch etherType {
+	case etherTypeIPv4:
+		return decodeIPv4(data)
+	case etherTypeIPv6:
+		return decodeIPv6(data)
+	default:
+		return nil, ErrNotUDPOrTCP
+	}
+}
+
+func decodeIPv4(data []byte) (*segment, error) {
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return nil, ErrTruncated
+	}
+	headerLen := int(data[0]&0x0f) * 4
+	totalLen := int(binary.BigEndian.Uint16(data[2:]))
+	if headerLen < 20 || totalLen < headerLen || len(data) < totalLen {
+		return nil, ErrTruncated
+	}
+	//more fragments flag or fragment offset
+	if binary.BigEndian.Uint16(data[6:])&0x3fff != 0 {
+		return nil, ErrFragmented
+	}
+
+	return decodeTransport(data[9], net.IP(data[12:16]), net.IP(data[16:20]), data[headerLen:totalLen])
+}
+
+func decodeIPv6(data []byte) (*segment, error) {
+	if len(data) < 40 || data[0]>>4 != 6 {
+		return nil, ErrTruncated
+	}
+	payloadLen := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 40+payloadLen {
+		return nil, ErrTruncated
+	}
+
+	next, payload := data[6], data[40:40+payloadLen]
+	for {
+		switch next {
+		case 0, 43, 60:
+			//hop-by-hop, routing and destination options
+			if len(payload) < 8 {
+				return nil, ErrTruncated
+			}
+			length := (int(payload[1]) + 1) * 8
+			if len(payload) < length {
+				return nil, ErrTruncated
+			}
+			next, payload = payload[0], payload[length:]
+		case 44:
+			return nil, ErrFragmented
+		default:
+			return decodeTransport(next, net.IP(data[8:24]), net.IP(data[24:40]), payload)
+		}
+	}
+}
+
+func decodeTransport(protocol uint8, src, dst net.IP, data []byte) (*segment, error) {
+	switch protocol {
+	case protocolUDP:
+		if len(data) < 8 {
+			return nil, ErrTruncated
+		}
+		length := int(binary.BigEndian.Uint16(data[4:]))
+		if length < 8 || length > len(data) {
+			return nil, ErrTruncated
+		}
+		return &segment{
+			srcIP:   src,
+			dstIP:   dst,
+			srcPort: binary.BigEndian.Uint16(data),
+			dstPort: binary.BigEndian.Uint16(data[2:]),
+			payload: data[8:length],
+		}, nil
+	case protocolTCP:
+		if len(data) < 20 {
+			return nil, ErrTruncated
+		}
+		offset := int(data[12]>>4) * 4
+		if offset < 20 || offset > len(data) {
+			return nil, ErrTruncated
+		}
+		return &segment{
+			srcIP:   src,
+			dstIP:   dst,
+			srcPort: binary.BigEndian.Uint16(data),
+			dstPort: binary.BigEndian.Uint16(data[2:]),
+			tcp:     true,
+			seq:     binary.BigEndian.Uint32(data[4:]),
+			flags:   data[13],
+			payload: data[offset:],
+		}, nil
+	default:
+		return nil, ErrNotUDPOrTCP
+	}
+}
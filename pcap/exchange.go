@@ -0,0 +1,67 @@
+package pcap
+
+import (
+	"time"
+)
+
+// Exchange is a query and its response, either of them could be nil if
+// it isn't captured
+type Exchange struct {
+	Query    *Message
+	Response *Message
+}
+
+func (e *Exchange) RTT() time.Duration {
+	if e.Query == nil || e.Response == nil {
+		return 0
+	}
+	return e.Response.Time.Sub(e.Query.Time)
+}
+
+type exchangeKey struct {
+	flow flowKey
+	tcp  bool
+	id   uint16
+}
+
+// MatchExchanges pairs queries and responses by endpoints and message
+// id, exchanges are in the order of their first message, messages which
+// can't be parsed are ignored
+func MatchExchanges(msgs []*Message) []*Exchange {
+	var exchanges []*Exchange
+	waiting := make(map[exchangeKey][]*Exchange)
+	for _, m := range msgs {
+		if m.Message == nil {
+			continue
+		}
+
+		if m.IsResponse() == false {
+			key := exchangeKey{
+				flow: makeFlowKey(m.SrcIP, m.SrcPort, m.DstIP, m.DstPort),
+				tcp:  m.TCP,
+				id:   m.Message.Header.Id,
+			}
+			e := &Exchange{Query: m}
+			waiting[key] = append(waiting[key], e)
+			exchanges = append(exchanges, e)
+			continue
+		}
+
+		key := exchangeKey{
+			flow: makeFlowKey(m.DstIP, m.DstPort, m.SrcIP, m.SrcPort),
+			tcp:  m.TCP,
+			id:   m.Message.Header.Id,
+		}
+		if queries := waiting[key]; len(queries) > 0 {
+			queries[0].Response = m
+			if len(queries) == 1 {
+				delete(waiting, key)
+			} else {
+				waiting[key] = queries[1:]
+			}
+		} else {
+			exchanges = append(exchanges, &Exchange{Response: m})
+		}
+	}
+	return exchanges
+}
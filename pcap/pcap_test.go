@@ -0,0 +1,329 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+func makeExchange(name string, id uint16) (*g53.Message, *g53.Message) {
+	query := g53.MakeQuery(g53.NameFromStringUnsafe(name), g53.RR_A, 1232, false)
+	query.Header.Id = id
+	response := query.MakeResponse()
+	response.Edns = query.Edns
+	rrset, _ := g53.RRsetFromString(name + " 300 IN A 192.0.2.1")
+	response.AddRRset(g53.AnswerSection, rrset)
+	response.RecalculateSectionRRCount()
+	return query, response
+}
+
+func renderMessage(msg *g53.Message) []byte {
+	render := g53.NewMsgRender()
+	msg.Rend(render)
+	return append([]byte(nil), render.Data()...)
+}
+
+func TestWriteAndScan(t *testing.T) {
+	client4, server4 := net.ParseIP("192.0.2.10"), net.ParseIP("192.0.2.53")
+	client6, server6 := net.ParseIP("2001:db8::10"), net.ParseIP("2001:db8::53")
+	start := time.Unix(1700000000, 123456789)
+
+	udpQuery, udpResponse := makeExchange("www.example.com.", 100)
+	tcpQuery, tcpResponse := makeExchange("mail.example.com.", 200)
+	msgs := []*Message{
+		{Time: start, SrcIP: client4, DstIP: server4, SrcPort: 40000, DstPort: 53, Message: udpQuery},
+		{Time: start.Add(time.Millisecond), SrcIP: client6, DstIP: server6, SrcPort: 40001, DstPort: 53, TCP: true, Message: tcpQuery},
+		{Time: start.Add(2 * time.Millisecond), SrcIP: server4, DstIP: client4, SrcPort: 53, DstPort: 40000, Message: udpResponse},
+		{Time: start.Add(3 * time.Millisecond), SrcIP: server6, DstIP: client6, SrcPort: 53, DstPort: 40001, TCP: true, Message: tcpResponse},
+	}
+
+	for _, linkType := range []LinkType{LinkTypeEthernet, LinkTypeRaw} {
+		path := filepath.Join(t.TempDir(), "dns.pcap")
+		f, err := os.Create(path)
+		ut.Equal(t, err, nil)
+		w, err := NewWriter(f, linkType)
+		ut.Equal(t, err, nil)
+		for _, m := range msgs {
+			ut.Equal(t, w.WriteMessage(m), nil)
+		}
+		f.Close()
+
+		captured, err := ReadFile(path)
+		ut.Equal(t, err, nil)
+		ut.Equal(t, len(captured), len(msgs))
+		for i, m := range captured {
+			ut.Equal(t, m.Err, nil)
+			ut.Assert(t, m.Time.Equal(msgs[i].Time), "time of message %d isn't correct", i)
+			ut.Assert(t, m.SrcIP.Equal(msgs[i].SrcIP) && m.DstIP.Equal(msgs[i].DstIP), "ip of message %d isn't correct", i)
+			ut.Equal(t, m.SrcPort, msgs[i].SrcPort)
+			ut.Equal(t, m.DstPort, msgs[i].DstPort)
+			ut.Equal(t, m.TCP, msgs[i].TCP)
+			ut.Equal(t, m.Message.String(), msgs[i].Message.String())
+		}
+
+		exchanges := MatchExchanges(captured)
+		ut.Equal(t, len(exchanges), 2)
+		ut.Equal(t, exchanges[0].Query.Message.Header.Id, uint16(100))
+		ut.Equal(t, exchanges[0].Response.Message.Header.Id, uint16(100))
+		ut.Equal(t, exchanges[0].RTT(), 2*time.Millisecond)
+		ut.Equal(t, exchanges[1].Response.TCP, true)
+		ut.Equal(t, exchanges[1].RTT(), 2*time.Millisecond)
+		ut.Equal(t, exchanges[1].Query.SrcAddr().String(), "[2001:db8::10]:40001")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	query, _ := makeExchange("www.example.com.", 1)
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, LinkTypeRaw)
+	src, dst := net.ParseIP("192.0.2.10"), net.ParseIP("192.0.2.53")
+	w.WriteMessage(&Message{Time: time.Now(), SrcIP: src, DstIP: dst, SrcPort: 40000, DstPort: 53, Message: query})
+
+	packet := buf.Bytes()[24+16:]
+	ut.Equal(t, fold(checksumAdd(0, packet[:20])), uint16(0xffff))
+	udp := packet[20:]
+	sum := checksumAdd(0, src.To4())
+	sum = checksumAdd(sum, dst.To4())
+	sum += protocolUDP + uint32(len(udp))
+	ut.Equal(t, fold(checksumAdd(sum, udp)), uint16(0xffff))
+}
+
+func TestTCPReassembly(t *testing.T) {
+	query1, _ := makeExchange("a.example.com.", 1)
+	query2, _ := makeExchange("b.example.com.", 2)
+	var stream []byte
+	for _, q := range []*g53.Message{query1, query2} {
+		data := renderMessage(q)
+		stream = binary.BigEndian.AppendUint16(stream, uint16(len(data)))
+		stream = append(stream, data...)
+	}
+
+	newSegment := func(seq uint32, flags uint8, payload []byte) *segment {
+		return &segment{
+			srcIP:   net.ParseIP("192.0.2.10"),
+			dstIP:   net.ParseIP("192.0.2.53"),
+			srcPort: 40000,
+			dstPort: 53,
+			tcp:     true,
+			seq:     seq,
+			flags:   flags,
+			payload: payload,
+		}
+	}
+
+	//split in the middle of the length, out of order with retransmission
+	a := newTCPAssembler()
+	isn := uint32(0xfffffff0)
+	ut.Equal(t, len(a.add(newSegment(isn, tcpFlagSYN, nil))), 0)
+	ut.Equal(t, len(a.add(newSegment(isn+1+10, tcpFlagACK, stream[10:30]))), 0)
+	ut.Equal(t, len(a.add(newSegment(isn+1, tcpFlagACK, stream[:1]))), 0)
+	ut.Equal(t, len(a.add(newSegment(isn+1, tcpFlagACK, stream[:12]))), 0)
+	msgs := a.add(newSegment(isn+1+30, tcpFlagACK, stream[30:]))
+	ut.Equal(t, len(msgs), 2)
+	ut.Equal(t, msgs[0], renderMessage(query1))
+	ut.Equal(t, msgs[1], renderMessage(query2))
+	ut.Equal(t, len(a.add(newSegment(isn+1, tcpFlagACK, stream[:10]))), 0)
+	ut.Equal(t, len(a.add(newSegment(isn+1+uint32(len(stream)), tcpFlagFIN, nil))), 0)
+	ut.Equal(t, len(a.streams), 0)
+
+	//capture starts in the middle of connection
+	a = newTCPAssembler()
+	msgs = a.add(newSegment(1000, tcpFlagACK|tcpFlagPSH, stream))
+	ut.Equal(t, len(msgs), 2)
+}
+
+func TestTCPReassemblyGap(t *testing.T) {
+	var segments [][]byte
+	for i, name := range []string{"a.example.com.", "b.example.com.", "c.example.com.", "d.example.com."} {
+		query, _ := makeExchange(name, uint16(i))
+		data := renderMessage(query)
+		segments = append(segments, append(binary.BigEndian.AppendUint16(nil, uint16(len(data))), data...))
+	}
+
+	start := time.Unix(1700000000, 0)
+	seq := uint32(1000)
+	newSegment := func(i int, at time.Duration) *segment {
+		s := seq
+		for _, seg := range segments[:i] {
+			s += uint32(len(seg))
+		}
+		return &segment{
+			srcIP:   net.ParseIP("192.0.2.10"),
+			dstIP:   net.ParseIP("192.0.2.53"),
+			srcPort: 40000,
+			dstPort: 53,
+			tcp:     true,
+			seq:     s,
+			flags:   tcpFlagACK,
+			payload: segments[i],
+			time:    start.Add(at),
+		}
+	}
+
+	//the second segment is dropped
+	a := newTCPAssembler()
+	ut.Equal(t, len(a.add(newSegment(0, 0))), 1)
+	ut.Equal(t, len(a.add(newSegment(2, time.Second))), 0)
+	msgs := a.add(newSegment(3, tcpGapTimeout+time.Second))
+	ut.Equal(t, len(msgs), 2)
+	query, _ := makeExchange("c.example.com.", 2)
+	ut.Equal(t, msgs[0], renderMessage(query))
+	//retransmission of the lost segment is ignored
+	ut.Equal(t, len(a.add(newSegment(1, tcpGapTimeout+2*time.Second))), 0)
+
+	//stream without FIN is removed when it's idle
+	lastSeen := tcpGapTimeout + 2*time.Second
+	other := newSegment(0, lastSeen+tcpStreamTimeout/2)
+	other.srcPort = 40001
+	ut.Equal(t, len(a.add(other)), 1)
+	ut.Equal(t, len(a.streams), 2)
+	other = newSegment(1, lastSeen+tcpStreamTimeout+time.Second)
+	other.srcPort = 40001
+	ut.Equal(t, len(a.add(other)), 1)
+	ut.Equal(t, len(a.streams), 1)
+}
+
+func ngBlock(typ uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	length := uint32(12 + len(body))
+	block := binary.LittleEndian.AppendUint32(nil, typ)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	block = append(block, body...)
+	return binary.LittleEndian.AppendUint32(block, length)
+}
+
+func TestPcapng(t *testing.T) {
+	query, response := makeExchange("www.example.com.", 1)
+	var raw bytes.Buffer
+	w, _ := NewWriter(&raw, LinkTypeRaw)
+	src, dst := net.ParseIP("192.0.2.10"), net.ParseIP("192.0.2.53")
+	w.WriteMessage(&Message{SrcIP: src, DstIP: dst, SrcPort: 40000, DstPort: 53, Message: query})
+	queryPacket := append([]byte(nil), raw.Bytes()[24+16:]...)
+	raw.Reset()
+	w.WriteMessage(&Message{SrcIP: dst, DstIP: src, SrcPort: 53, DstPort: 40000, Message: response})
+	responsePacket := raw.Bytes()[16:]
+
+	var file []byte
+	shb := binary.LittleEndian.AppendUint32(nil, byteOrderMagic)
+	shb = binary.LittleEndian.AppendUint16(shb, 1)
+	shb = binary.LittleEndian.AppendUint16(shb, 0)
+	shb = binary.LittleEndian.AppendUint64(shb, 0xffffffffffffffff)
+	file = append(file, ngBlock(blockSectionHeader, shb)...)
+
+	idb := binary.LittleEndian.AppendUint16(nil, uint16(LinkTypeRaw))
+	idb = binary.LittleEndian.AppendUint16(idb, 0)
+	idb = binary.LittleEndian.AppendUint32(idb, 65535)
+	idb = binary.LittleEndian.AppendUint16(idb, optionTimestampResolv)
+	idb = binary.LittleEndian.AppendUint16(idb, 1)
+	idb = append(idb, 9, 0, 0, 0)
+	idb = binary.LittleEndian.AppendUint32(idb, optionEnd)
+	file = append(file, ngBlock(blockInterface, idb)...)
+
+	//unknown block is skipped
+	file = append(file, ngBlock(0x0bad, []byte{1, 2, 3, 4})...)
+
+	ts := uint64(1700000000123456789)
+	epb := binary.LittleEndian.AppendUint32(nil, 0)
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(queryPacket)))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(queryPacket)))
+	epb = append(epb, queryPacket...)
+	file = append(file, ngBlock(blockEnhancedPacket, epb)...)
+
+	spb := binary.LittleEndian.AppendUint32(nil, uint32(len(responsePacket)))
+	spb = append(spb, responsePacket...)
+	file = append(file, ngBlock(blockSimplePacket, spb)...)
+
+	s, err := NewScanner(bytes.NewReader(file))
+	ut.Equal(t, err, nil)
+	m, err := s.Next()
+	ut.Equal(t, err, nil)
+	ut.Assert(t, m.Time.Equal(time.Unix(1700000000, 123456789)), "time with nanosecond resolution isn't correct")
+	ut.Equal(t, m.Message.String(), query.String())
+	m, err = s.Next()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, m.IsResponse(), true)
+	ut.Equal(t, m.Message.String(), response.String())
+	_, err = s.Next()
+	ut.Equal(t, err, io.EOF)
+}
+
+func TestPcapngTimestamp(t *testing.T) {
+	for _, c := range []struct {
+		resolv byte
+		ts     uint64
+	}{
+		{6, 1700000000123456},
+		{9, 1700000000123456789},
+		//picosecond
+		{12, 1700000123456789012},
+		{0x80 | 30, 1700000<<30 | 132560719},
+	} {
+		r := &Reader{byteOrder: binary.LittleEndian}
+		options := binary.LittleEndian.AppendUint16(nil, optionTimestampResolv)
+		options = binary.LittleEndian.AppendUint16(options, 1)
+		options = append(options, c.resolv, 0, 0, 0)
+		i := ngInterface{unit: r.timestampUnit(options)}
+		tm := i.timestamp(c.ts)
+		ut.Equal(t, tm.Unix()%100000, int64(0))
+		ut.Equal(t, tm.Nanosecond()/1000, 123456)
+	}
+}
+
+func TestWriterLinkType(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf, LinkTypeLinuxSLL)
+	ut.Equal(t, err, ErrUnsupportedLink)
+	ut.Equal(t, buf.Len(), 0)
+}
+
+func TestClassicPcapBigEndian(t *testing.T) {
+	query, _ := makeExchange("www.example.com.", 1)
+	var raw bytes.Buffer
+	w, _ := NewWriter(&raw, LinkTypeRaw)
+	w.WriteMessage(&Message{SrcIP: net.ParseIP("192.0.2.10"), DstIP: net.ParseIP("192.0.2.53"), SrcPort: 40000, DstPort: 53, Message: query})
+	packet := raw.Bytes()[24+16:]
+
+	file := binary.BigEndian.AppendUint32(nil, magicMicroseconds)
+	file = binary.BigEndian.AppendUint16(file, 2)
+	file = binary.BigEndian.AppendUint16(file, 4)
+	file = append(file, make([]byte, 8)...)
+	file = binary.BigEndian.AppendUint32(file, 65535)
+	file = binary.BigEndian.AppendUint32(file, uint32(LinkTypeRaw))
+	file = binary.BigEndian.AppendUint32(file, 1700000000)
+	file = binary.BigEndian.AppendUint32(file, 500)
+	file = binary.BigEndian.AppendUint32(file, uint32(len(packet)))
+	file = binary.BigEndian.AppendUint32(file, uint32(len(packet)))
+	file = append(file, packet...)
+	//packet on other port is skipped
+	other := append([]byte(nil), packet...)
+	binary.BigEndian.PutUint16(other[22:], 5353)
+	file = binary.BigEndian.AppendUint32(file, 1700000001)
+	file = binary.BigEndian.AppendUint32(file, 0)
+	file = binary.BigEndian.AppendUint32(file, uint32(len(other)))
+	file = binary.BigEndian.AppendUint32(file, uint32(len(other)))
+	file = append(file, other...)
+
+	s, err := NewScanner(bytes.NewReader(file))
+	ut.Equal(t, err, nil)
+	m, err := s.Next()
+	ut.Equal(t, err, nil)
+	ut.Assert(t, m.Time.Equal(time.Unix(1700000000, 500000)), "time with microsecond resolution isn't correct")
+	_, err = s.Next()
+	ut.Equal(t, err, io.EOF)
+	ut.Equal(t, s.Skipped, 1)
+
+	_, err = NewReader(bytes.NewReader([]byte("not a capture file at all")))
+	ut.Equal(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,149 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	blockSectionHeader    = 0x0a0d0d0a
+	blockInterface        = 1
+	blockSimplePacket     = 3
+	blockEnhancedPacket   = 6
+	byteOrderMagic        = 0x1a2b3c4d
+	optionEnd             = 0
+	optionTimestampResolv = 9
+)
+
+type ngInterface struct {
+	linkType LinkType
+	// time unit in nanoseconds
+	unit float64
+}
+
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, ErrInvalidFile
+		}
+		return 0, nil, err
+	}
+
+	typ := binary.BigEndian.Uint32(header[:])
+	if typ == blockSectionHeader {
+		//byte order of new section is decided by its byte order magic
+		magic, err := r.r.Peek(4)
+		if err != nil {
+			return 0, nil, ErrInvalidFile
+		}
+		if binary.LittleEndian.Uint32(magic) == byteOrderMagic {
+			r.byteOrder = binary.LittleEndian
+		} else if binary.BigEndian.Uint32(magic) == byteOrderMagic {
+			r.byteOrder = binary.BigEndian
+		} else {
+			return 0, nil, ErrInvalidFile
+		}
+		r.interfaces = nil
+	} else if r.byteOrder == nil {
+		return 0, nil, ErrInvalidFile
+	} else {
+		typ = r.byteOrder.Uint32(header[:])
+	}
+
+	length := r.byteOrder.Uint32(header[4:])
+	if length < 12 || length%4 != 0 || length > maxPacketLen {
+		return 0, nil, ErrInvalidFile
+	}
+	//body is followed by the length again
+	body := make([]byte, length-8)
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		return 0, nil, ErrInvalidFile
+	}
+	return typ, body[:len(body)-4], nil
+}
+
+func (r *Reader) readNgPacket() (*Packet, error) {
+	for {
+		typ, body, err := r.readBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case blockInterface:
+			if len(body) < 8 {
+				return nil, ErrInvalidFile
+			}
+			r.interfaces = append(r.interfaces, ngInterface{
+				linkType: LinkType(r.byteOrder.Uint16(body)),
+				unit:     r.timestampUnit(body[8:]),
+			})
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return nil, ErrInvalidFile
+			}
+			id := r.byteOrder.Uint32(body)
+			capLen := r.byteOrder.Uint32(body[12:])
+			if int(id) >= len(r.interfaces) || uint32(len(body)-20) < capLen {
+				return nil, ErrInvalidFile
+			}
+			intf := r.interfaces[id]
+			ts := uint64(r.byteOrder.Uint32(body[4:]))<<32 | uint64(r.byteOrder.Uint32(body[8:]))
+			return &Packet{
+				Time:     intf.timestamp(ts),
+				LinkType: intf.linkType,
+				Data:     body[20 : 20+capLen],
+				Length:   int(r.byteOrder.Uint32(body[16:])),
+			}, nil
+		case blockSimplePacket:
+			//simple packet has no timestamp and belongs to the first interface
+			if len(body) < 4 || len(r.interfaces) == 0 {
+				return nil, ErrInvalidFile
+			}
+			length := r.byteOrder.Uint32(body)
+			data := body[4:]
+			if uint32(len(data)) > length {
+				data = data[:length]
+			}
+			return &Packet{
+				LinkType: r.interfaces[0].linkType,
+				Data:     data,
+				Length:   int(length),
+			}, nil
+		}
+	}
+}
+
+func (r *Reader) timestampUnit(options []byte) float64 {
+	for len(options) >= 4 {
+		code, length := r.byteOrder.Uint16(options), int(r.byteOrder.Uint16(options[2:]))
+		if code == optionEnd || len(options) < 4+length {
+			break
+		}
+		if code == optionTimestampResolv && length == 1 {
+			resolv := options[4]
+			if resolv&0x80 != 0 {
+				return 1e9 / math.Pow(2, float64(resolv&0x7f))
+			}
+			return 1e9 / math.Pow(10, float64(resolv))
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+	return 1e3
+}
+
+func (i ngInterface) timestamp(ts uint64) time.Time {
+	perSecond := uint64(math.Round(1e9 / i.unit))
+	if perSecond == 0 {
+		perSecond = 1
+	}
+	//remainder times 1e9 overflows uint64 with resolution finer than
+	//about 1e-10 second, quotient is less than 1e9 so Div64 won't panic
+	hi, lo := bits.Mul64(ts%perSecond, 1e9)
+	nsec, _ := bits.Div64(hi, lo, perSecond)
+	return time.Unix(int64(ts/perSecond), int64(nsec))
+}
@@ -0,0 +1,127 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown capture file format")
+	ErrInvalidFile   = errors.New("invalid capture file")
+)
+
+type LinkType uint32
+
+const (
+	LinkTypeNull     LinkType = 0
+	LinkTypeEthernet LinkType = 1
+	LinkTypeRaw      LinkType = 101
+	LinkTypeLinuxSLL LinkType = 113
+	LinkTypeIPv4     LinkType = 228
+	LinkTypeIPv6     LinkType = 229
+)
+
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+	magicPcapng       = 0x0a0d0d0a
+
+	// packet larger than this is treated as corruption
+	maxPacketLen = 256 * 1024
+)
+
+// Packet is a captured frame, data may be truncated by the snap length
+type Packet struct {
+	Time     time.Time
+	LinkType LinkType
+	Data     []byte
+	// length of the frame on the wire
+	Length int
+}
+
+// Reader reads packets from classic pcap or pcapng file, the format is
+// detected by the magic number
+type Reader struct {
+	r         *bufio.Reader
+	byteOrder binary.ByteOrder
+	// classic pcap
+	linkType LinkType
+	nano     bool
+	// pcapng
+	ng         bool
+	interfaces []ngInterface
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+	magic, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	if binary.BigEndian.Uint32(magic) == magicPcapng {
+		reader.ng = true
+		return reader, nil
+	}
+
+	var header [24]byte
+	if _, err := io.ReadFull(reader.r, header[:]); err != nil {
+		return nil, ErrInvalidFile
+	}
+	switch binary.LittleEndian.Uint32(header[:]) {
+	case magicMicroseconds:
+		reader.byteOrder = binary.LittleEndian
+	case magicNanoseconds:
+		reader.byteOrder, reader.nano = binary.LittleEndian, true
+	default:
+		switch binary.BigEndian.Uint32(header[:]) {
+		case magicMicroseconds:
+			reader.byteOrder = binary.BigEndian
+		case magicNanoseconds:
+			reader.byteOrder, reader.nano = binary.BigEndian, true
+		default:
+			return nil, ErrUnknownFormat
+		}
+	}
+	reader.linkType = LinkType(reader.byteOrder.Uint32(header[20:]) & 0xffff)
+	return reader, nil
+}
+
+// ReadPacket returns next packet, io.EOF is returned at the end of file
+func (r *Reader) ReadPacket() (*Packet, error) {
+	if r.ng {
+		return r.readNgPacket()
+	}
+
+	var header [16]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidFile
+		}
+		return nil, err
+	}
+
+	sec := r.byteOrder.Uint32(header[0:])
+	frac := r.byteOrder.Uint32(header[4:])
+	capLen := r.byteOrder.Uint32(header[8:])
+	if capLen > maxPacketLen {
+		return nil, ErrInvalidFile
+	}
+	if r.nano == false {
+		frac *= 1000
+	}
+
+	data := make([]byte, capLen)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, ErrInvalidFile
+	}
+	return &Packet{
+		Time:     time.Unix(int64(sec), int64(frac)),
+		LinkType: r.linkType,
+		Data:     data,
+		Length:   int(r.byteOrder.Uint32(header[12:])),
+	}, nil
+}
@@ -0,0 +1,153 @@
+package pcap
+
+import (
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+const DefaultPort = 53
+
+// Message is a dns message captured, for tcp, time is the time of the
+// segment which completes the message
+type Message struct {
+	Time    time.Time
+	SrcIP   net.IP
+	DstIP   net.IP
+	SrcPort uint16
+	DstPort uint16
+	TCP     bool
+	Data    []byte
+	// Message is nil if data can't be parsed, Err is the reason
+	Message *g53.Message
+	Err     error
+}
+
+func (m *Message) IsResponse() bool {
+	return m.Message != nil && m.Message.Header.GetFlag(g53.FLAG_QR)
+}
+
+func (m *Message) SrcAddr() net.Addr {
+	if m.TCP {
+		return &net.TCPAddr{IP: m.SrcIP, Port: int(m.SrcPort)}
+	}
+	return &net.UDPAddr{IP: m.SrcIP, Port: int(m.SrcPort)}
+}
+
+func (m *Message) DstAddr() net.Addr {
+	if m.TCP {
+		return &net.TCPAddr{IP: m.DstIP, Port: int(m.DstPort)}
+	}
+	return &net.UDPAddr{IP: m.DstIP, Port: int(m.DstPort)}
+}
+
+// Scanner extracts dns messages from capture, packets to or from the
+// ports are treated as dns, fragmented ip packets and other packets are
+// skipped
+type Scanner struct {
+	Ports []uint16
+	// count of packets which aren't dns or can't be decoded
+	Skipped int
+
+	r     *Reader
+	tcp   *tcpAssembler
+	queue []*Message
+}
+
+func NewScanner(r io.Reader) (*Scanner, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scanner{
+		Ports: []uint16{DefaultPort},
+		r:     reader,
+		tcp:   newTCPAssembler(),
+	}, nil
+}
+
+// Next returns next dns message, io.EOF is returned at the end of file
+func (s *Scanner) Next() (*Message, error) {
+	for len(s.queue) == 0 {
+		p, err := s.r.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		s.handlePacket(p)
+	}
+
+	m := s.queue[0]
+	s.queue = s.queue[1:]
+	return m, nil
+}
+
+func (s *Scanner) isDNSPort(port uint16) bool {
+	for _, p := range s.Ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) handlePacket(p *Packet) {
+	seg, err := decodePacket(p)
+	if err != nil || (s.isDNSPort(seg.srcPort) == false && s.isDNSPort(seg.dstPort) == false) {
+		s.Skipped += 1
+		return
+	}
+
+	if seg.tcp == false {
+		s.queue = append(s.queue, newMessage(p.Time, seg, seg.payload))
+		return
+	}
+
+	seg.time = p.Time
+	for _, data := range s.tcp.add(seg) {
+		s.queue = append(s.queue, newMessage(p.Time, seg, data))
+	}
+}
+
+func newMessage(t time.Time, seg *segment, data []byte) *Message {
+	m := &Message{
+		Time:    t,
+		SrcIP:   seg.srcIP,
+		DstIP:   seg.dstIP,
+		SrcPort: seg.srcPort,
+		DstPort: seg.dstPort,
+		TCP:     seg.tcp,
+		Data:    data,
+	}
+	m.Message, m.Err = g53.MessageFromWire(util.NewInputBuffer(data))
+	return m
+}
+
+// ReadFile returns all the dns messages in capture file
+func ReadFile(path string) ([]*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := NewScanner(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []*Message
+	for {
+		m, err := s.Next()
+		if err == io.EOF {
+			return msgs, nil
+		} else if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, m)
+	}
+}
@@ -0,0 +1,187 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+const (
+	// out of order segments kept for each stream before the gap is
+	// skipped
+	maxPendingSegments = 64
+	// gap which isn't filled in the time is skipped, the missing segment
+	// is assumed to be lost by capture
+	tcpGapTimeout = 5 * time.Second
+	// stream without segment in the time is removed, connection may be
+	// closed without FIN or RST captured
+	tcpStreamTimeout = 2 * time.Minute
+)
+
+type flowKey struct {
+	src string
+	dst string
+}
+
+func makeFlowKey(srcIP net.IP, srcPort uint16, dstIP net.IP, dstPort uint16) flowKey {
+	return flowKey{
+		src: endpoint(srcIP, srcPort),
+		dst: endpoint(dstIP, dstPort),
+	}
+}
+
+func endpoint(ip net.IP, port uint16) string {
+	buf := make([]byte, 0, net.IPv6len+2)
+	buf = append(buf, ip.To16()...)
+	return string(binary.BigEndian.AppendUint16(buf, port))
+}
+
+// stream reassembles one direction of tcp connection, dns messages in
+// it are prefixed with 2 bytes length
+type stream struct {
+	nextSeq  uint32
+	buf      []byte
+	pending  map[uint32][]byte
+	gapSince time.Time
+	lastSeen time.Time
+}
+
+// time of packets is used instead of wall clock, so the result doesn't
+// depend on how fast capture is read
+type tcpAssembler struct {
+	streams   map[flowKey]*stream
+	lastSweep time.Time
+}
+
+func newTCPAssembler() *tcpAssembler {
+	return &tcpAssembler{
+		streams: make(map[flowKey]*stream),
+	}
+}
+
+// add returns the complete messages after the segment is added, if
+// capture starts in the middle of connection, the first segment seen is
+// assumed to start with a message
+func (a *tcpAssembler) add(seg *segment) [][]byte {
+	a.expire(seg.time)
+
+	key := makeFlowKey(seg.srcIP, seg.srcPort, seg.dstIP, seg.dstPort)
+	s, ok := a.streams[key]
+	if seg.flags&tcpFlagSYN != 0 {
+		s = &stream{nextSeq: seg.seq + 1}
+		a.streams[key] = s
+	} else if ok == false {
+		if len(seg.payload) == 0 {
+			return nil
+		}
+		s = &stream{nextSeq: seg.seq}
+		a.streams[key] = s
+	}
+
+	seq := seg.seq
+	if seg.flags&tcpFlagSYN != 0 {
+		seq += 1
+	}
+	s.lastSeen = seg.time
+	s.addSegment(seq, seg.payload, seg.time)
+	msgs := s.messages()
+
+	if seg.flags&(tcpFlagFIN|tcpFlagRST) != 0 {
+		delete(a.streams, key)
+	}
+	return msgs
+}
+
+// streams are checked once per timeout, so idle stream is removed in
+// twice of the timeout at most
+func (a *tcpAssembler) expire(now time.Time) {
+	if now.Sub(a.lastSweep) < tcpStreamTimeout {
+		return
+	}
+
+	a.lastSweep = now
+	for key, s := range a.streams {
+		if now.Sub(s.lastSeen) >= tcpStreamTimeout {
+			delete(a.streams, key)
+		}
+	}
+}
+
+func (s *stream) addSegment(seq uint32, payload []byte, now time.Time) {
+	if len(payload) == 0 {
+		return
+	}
+
+	if diff := int32(seq - s.nextSeq); diff > 0 {
+		if s.pending == nil {
+			s.pending = make(map[uint32][]byte)
+		}
+		if len(s.pending) == 0 {
+			s.gapSince = now
+		}
+		s.pending[seq] = append([]byte(nil), payload...)
+		if len(s.pending) < maxPendingSegments && now.Sub(s.gapSince) < tcpGapTimeout {
+			return
+		}
+		s.resync()
+	} else {
+		s.append(seq, payload)
+	}
+
+	s.merge()
+	if len(s.pending) > 0 {
+		s.gapSince = now
+	}
+}
+
+// resync skips the gap, data before it can't be completed, and the first
+// pending segment is assumed to start with a message
+func (s *stream) resync() {
+	first := true
+	for seq := range s.pending {
+		if first || int32(seq-s.nextSeq) < 0 {
+			s.nextSeq = seq
+			first = false
+		}
+	}
+	s.buf = nil
+}
+
+func (s *stream) merge() {
+	for merged := true; merged && len(s.pending) > 0; {
+		merged = false
+		for seq, payload := range s.pending {
+			if int32(seq-s.nextSeq) <= 0 {
+				delete(s.pending, seq)
+				s.append(seq, payload)
+				merged = true
+			}
+		}
+	}
+}
+
+// append ignores the part of payload which is already received
+func (s *stream) append(seq uint32, payload []byte) {
+	overlap := int(s.nextSeq - seq)
+	if overlap >= len(payload) {
+		return
+	}
+	s.buf = append(s.buf, payload[overlap:]...)
+	s.nextSeq += uint32(len(payload) - overlap)
+}
+
+func (s *stream) messages() [][]byte {
+	var msgs [][]byte
+	for len(s.buf) >= 2 {
+		length := int(binary.BigEndian.Uint16(s.buf))
+		if len(s.buf) < 2+length {
+			break
+		}
+		msgs = append(msgs, s.buf[2:2+length:2+length])
+		s.buf = s.buf[2+length:]
+	}
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+	return msgs
+}
@@ -0,0 +1,213 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/zdnscloud/g53"
+)
+
+const defaultSnapLen = 262144
+
+// Writer writes classic pcap file with nanosecond timestamp
+type Writer struct {
+	w        io.Writer
+	linkType LinkType
+	tcpSeqs  map[flowKey]uint32
+	ipID     uint16
+}
+
+// NewWriter writes file header, only raw ip and ethernet link types are
+// supported since messages are built as ip packets
+func NewWriter(w io.Writer, linkType LinkType) (*Writer, error) {
+	if linkType != LinkTypeRaw && linkType != LinkTypeEthernet {
+		return nil, ErrUnsupportedLink
+	}
+
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, magicNanoseconds)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], defaultSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], uint32(linkType))
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		w:        w,
+		linkType: linkType,
+		tcpSeqs:  make(map[flowKey]uint32),
+	}, nil
+}
+
+func (w *Writer) WritePacket(p *Packet) error {
+	length := p.Length
+	if length < len(p.Data) {
+		length = len(p.Data)
+	}
+
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header, uint32(p.Time.Unix()))
+	binary.LittleEndian.PutUint32(header[4:], uint32(p.Time.Nanosecond()))
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(p.Data)))
+	binary.LittleEndian.PutUint32(header[12:], uint32(length))
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.w.Write(p.Data)
+	return err
+}
+
+// WriteMessage builds ip packet for message, message over tcp is written
+// as one segment following the previous one of the same direction,
+// handshake isn't generated
+func (w *Writer) WriteMessage(m *Message) error {
+	data := m.Data
+	if data == nil {
+		render := g53.NewMsgRender()
+		m.Message.Rend(render)
+		data = render.Data()
+	}
+
+	var transport []byte
+	var protocol uint8
+	if m.TCP {
+		key := makeFlowKey(m.SrcIP, m.SrcPort, m.DstIP, m.DstPort)
+		seq, ok := w.tcpSeqs[key]
+		if ok == false {
+			seq = 1
+		}
+		payload := binary.BigEndian.AppendUint16(nil, uint16(len(data)))
+		payload = append(payload, data...)
+		reverse := makeFlowKey(m.DstIP, m.DstPort, m.SrcIP, m.SrcPort)
+		ack, ok := w.tcpSeqs[reverse]
+		if ok == false {
+			ack = 1
+		}
+		transport = tcpSegment(m, seq, ack, payload)
+		w.tcpSeqs[key] = seq + uint32(len(payload))
+		protocol = protocolTCP
+	} else {
+		transport = udpDatagram(m, data)
+		protocol = protocolUDP
+	}
+
+	var packet []byte
+	if ip4 := m.SrcIP.To4(); ip4 != nil {
+		w.ipID += 1
+		packet = ipv4Packet(ip4, m.DstIP.To4(), protocol, w.ipID, transport)
+	} else {
+		packet = ipv6Packet(m.SrcIP.To16(), m.DstIP.To16(), protocol, transport)
+	}
+
+	switch w.linkType {
+	case LinkTypeRaw:
+	case LinkTypeEthernet:
+		etherType := uint16(etherTypeIPv6)
+		if m.SrcIP.To4() != nil {
+			etherType = etherTypeIPv4
+		}
+		frame := make([]byte, 14, 14+len(packet))
+		binary.BigEndian.PutUint16(frame[12:], etherType)
+		packet = append(frame, packet...)
+	}
+
+	return w.WritePacket(&Packet{Time: m.Time, LinkType: w.linkType, Data: packet})
+}
+
+// WriteExchange writes query and response which isn't nil
+func (w *Writer) WriteExchange(e *Exchange) error {
+	for _, m := range []*Message{e.Query, e.Response} {
+		if m == nil {
+			continue
+		}
+		if err := w.WriteMessage(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func udpDatagram(m *Message, data []byte) []byte {
+	buf := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint16(buf, m.SrcPort)
+	binary.BigEndian.PutUint16(buf[2:], m.DstPort)
+	binary.BigEndian.PutUint16(buf[4:], uint16(8+len(data)))
+	buf = append(buf, data...)
+	checksum := transportChecksum(m.SrcIP, m.DstIP, protocolUDP, buf)
+	if checksum == 0 {
+		checksum = 0xffff
+	}
+	binary.BigEndian.PutUint16(buf[6:], checksum)
+	return buf
+}
+
+func tcpSegment(m *Message, seq, ack uint32, payload []byte) []byte {
+	buf := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(buf, m.SrcPort)
+	binary.BigEndian.PutUint16(buf[2:], m.DstPort)
+	binary.BigEndian.PutUint32(buf[4:], seq)
+	binary.BigEndian.PutUint32(buf[8:], ack)
+	buf[12] = 5 << 4
+	buf[13] = tcpFlagPSH | tcpFlagACK
+	binary.BigEndian.PutUint16(buf[14:], 65535)
+	buf = append(buf, payload...)
+	binary.BigEndian.PutUint16(buf[16:], transportChecksum(m.SrcIP, m.DstIP, protocolTCP, buf))
+	return buf
+}
+
+func ipv4Packet(src, dst []byte, protocol uint8, id uint16, payload []byte) []byte {
+	buf := make([]byte, 20, 20+len(payload))
+	buf[0] = 0x45
+	binary.BigEndian.PutUint16(buf[2:], uint16(20+len(payload)))
+	binary.BigEndian.PutUint16(buf[4:], id)
+	buf[8] = 64
+	buf[9] = protocol
+	copy(buf[12:], src)
+	copy(buf[16:], dst)
+	binary.BigEndian.PutUint16(buf[10:], ^fold(checksumAdd(0, buf)))
+	return append(buf, payload...)
+}
+
+func ipv6Packet(src, dst []byte, protocol uint8, payload []byte) []byte {
+	buf := make([]byte, 40, 40+len(payload))
+	buf[0] = 0x60
+	binary.BigEndian.PutUint16(buf[4:], uint16(len(payload)))
+	buf[6] = protocol
+	buf[7] = 64
+	copy(buf[8:], src)
+	copy(buf[24:], dst)
+	return append(buf, payload...)
+}
+
+func transportChecksum(src, dst net.IP, protocol uint8, data []byte) uint16 {
+	if ip4 := src.To4(); ip4 != nil {
+		src, dst = ip4, dst.To4()
+	} else {
+		src, dst = src.To16(), dst.To16()
+	}
+	sum := checksumAdd(0, src)
+	sum = checksumAdd(sum, dst)
+	sum += uint32(protocol) + uint32(len(data))
+	sum = checksumAdd(sum, data)
+	return ^fold(sum)
+}
+
+func checksumAdd(sum uint32, data []byte) uint32 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	return sum
+}
+
+func fold(sum uint32) uint16 {
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return uint16(sum)
+}
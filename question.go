@@ -35,6 +35,30 @@ func QuestionFromWire(buf *util.InputBuffer) (*Question, error) {
 	}, nil
 }
 
+// FromWire reuses the storage of question and its name
+func (q *Question) FromWire(buf *util.InputBuffer) error {
+	if q.Name == nil {
+		q.Name = &Name{}
+	}
+	if err := q.Name.FromWire(buf, false); err != nil {
+		return err
+	}
+
+	t, err := TypeFromWire(buf)
+	if err != nil {
+		return err
+	}
+
+	cls, err := ClassFromWire(buf)
+	if err != nil {
+		return err
+	}
+
+	q.Type = t
+	q.Class = cls
+	return nil
+}
+
 func (q *Question) Rend(r *MsgRender) {
 	q.Name.Rend(r)
 	q.Type.Rend(r)
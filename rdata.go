@@ -62,6 +62,54 @@ func RdataFromWire(t RRType, buf *util.InputBuffer) (Rdata, error) {
 		return DSFromWire(buf, rdlen)
 	case RR_HINFO:
 		return HINFOFromWire(buf, rdlen)
+	case RR_CAA:
+		return CAAFromWire(buf, rdlen)
+	case RR_SSHFP:
+		return SSHFPFromWire(buf, rdlen)
+	case RR_TLSA:
+		return TLSAFromWire(buf, rdlen)
+	case RR_SMIMEA:
+		return SMIMEAFromWire(buf, rdlen)
+	case RR_CERT:
+		return CERTFromWire(buf, rdlen)
+	case RR_SVCB:
+		return SVCBFromWire(buf, rdlen)
+	case RR_HTTPS:
+		return HTTPSFromWire(buf, rdlen)
+	case RR_AFSDB:
+		return AFSDBFromWire(buf, rdlen)
+	case RR_RT:
+		return RTFromWire(buf, rdlen)
+	case RR_KX:
+		return KXFromWire(buf, rdlen)
+	case RR_LOC:
+		return LOCFromWire(buf, rdlen)
+	case RR_URI:
+		return URIFromWire(buf, rdlen)
+	case RR_EUI48:
+		return EUI48FromWire(buf, rdlen)
+	case RR_EUI64:
+		return EUI64FromWire(buf, rdlen)
+	case RR_NID:
+		return NIDFromWire(buf, rdlen)
+	case RR_L32:
+		return L32FromWire(buf, rdlen)
+	case RR_L64:
+		return L64FromWire(buf, rdlen)
+	case RR_LP:
+		return LPFromWire(buf, rdlen)
+	case RR_APL:
+		return APLFromWire(buf, rdlen)
+	case RR_DHCID:
+		return DHCIDFromWire(buf, rdlen)
+	case RR_IPSECKEY:
+		return IPSECKEYFromWire(buf, rdlen)
+	case RR_OPENPGPKEY:
+		return OPENPGPKEYFromWire(buf, rdlen)
+	case RR_ZONEMD:
+		return ZONEMDFromWire(buf, rdlen)
+	case RR_CSYNC:
+		return CSYNCFromWire(buf, rdlen)
 	default:
 		return nil, fmt.Errorf("unimplement type: %v", t)
 	}
@@ -105,6 +153,54 @@ func RdataFromString(t RRType, s string) (Rdata, error) {
 		return DSFromString(s)
 	case RR_HINFO:
 		return HINFOFromString(s)
+	case RR_CAA:
+		return CAAFromString(s)
+	case RR_SSHFP:
+		return SSHFPFromString(s)
+	case RR_TLSA:
+		return TLSAFromString(s)
+	case RR_SMIMEA:
+		return SMIMEAFromString(s)
+	case RR_CERT:
+		return CERTFromString(s)
+	case RR_SVCB:
+		return SVCBFromString(s)
+	case RR_HTTPS:
+		return HTTPSFromString(s)
+	case RR_AFSDB:
+		return AFSDBFromString(s)
+	case RR_RT:
+		return RTFromString(s)
+	case RR_KX:
+		return KXFromString(s)
+	case RR_LOC:
+		return LOCFromString(s)
+	case RR_URI:
+		return URIFromString(s)
+	case RR_EUI48:
+		return EUI48FromString(s)
+	case RR_EUI64:
+		return EUI64FromString(s)
+	case RR_NID:
+		return NIDFromString(s)
+	case RR_L32:
+		return L32FromString(s)
+	case RR_L64:
+		return L64FromString(s)
+	case RR_LP:
+		return LPFromString(s)
+	case RR_APL:
+		return APLFromString(s)
+	case RR_DHCID:
+		return DHCIDFromString(s)
+	case RR_IPSECKEY:
+		return IPSECKEYFromString(s)
+	case RR_OPENPGPKEY:
+		return OPENPGPKEYFromString(s)
+	case RR_ZONEMD:
+		return ZONEMDFromString(s)
+	case RR_CSYNC:
+		return CSYNCFromString(s)
 	default:
 		return nil, fmt.Errorf("unimplement type: %v", t)
 	}
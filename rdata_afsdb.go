@@ -0,0 +1,55 @@
+package g53
+
+import (
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// RFC 1183
+type AFSDB struct {
+	Subtype  uint16
+	Hostname *Name
+}
+
+func (a *AFSDB) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, a.Subtype, r)
+	rendField(RDF_C_NAME_UNCOMPRESS, a.Hostname, r)
+}
+
+func (a *AFSDB) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, a.Subtype, buf)
+	fieldToWire(RDF_C_NAME_UNCOMPRESS, a.Hostname, buf)
+}
+
+func (a *AFSDB) Compare(other Rdata) int {
+	otherAFSDB := other.(*AFSDB)
+	order := fieldCompare(RDF_C_UINT16, a.Subtype, otherAFSDB.Subtype)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_NAME_UNCOMPRESS, a.Hostname, otherAFSDB.Hostname)
+}
+
+func (a *AFSDB) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, a.Subtype),
+		fieldToString(RDF_D_NAME, a.Hostname)}, " ")
+}
+
+func AFSDBFromWire(buf *util.InputBuffer, ll uint16) (*AFSDB, error) {
+	d, n, err := uint16NameFromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &AFSDB{d, n}, nil
+}
+
+func AFSDBFromString(s string) (*AFSDB, error) {
+	d, n, err := uint16NameFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &AFSDB{d, n}, nil
+}
@@ -0,0 +1,20 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestAFSDBFromToString(t *testing.T) {
+	afsdb := rdataRoundTrip(t, RR_AFSDB, "1 afs.example.com.", "1 afs.example.com.").(*AFSDB)
+	Equal(t, afsdb.Subtype, uint16(1))
+	NameEqToStr(t, afsdb.Hostname, "afs.example.com.")
+
+	for _, s := range []string{
+		"65536 afs.example.com.",
+		"afs.example.com.",
+		"1 afs.example.com. extra",
+	} {
+		_, err := AFSDBFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
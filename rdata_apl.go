@@ -0,0 +1,218 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrUnknownAPLFamily = errors.New("unknown apl address family")
+	ErrInvalidAPLItem   = errors.New("apl item isn't valid")
+)
+
+const (
+	APL_FAMILY_IPV4 uint16 = 1
+	APL_FAMILY_IPV6 uint16 = 2
+)
+
+type APLItem struct {
+	Negation bool
+	Family   uint16
+	Prefix   uint8
+	Address  net.IP
+}
+
+// RFC 3123
+type APL struct {
+	Items []APLItem
+}
+
+func aplAddressLen(family uint16) (int, error) {
+	switch family {
+	case APL_FAMILY_IPV4:
+		return net.IPv4len, nil
+	case APL_FAMILY_IPV6:
+		return net.IPv6len, nil
+	default:
+		return 0, ErrUnknownAPLFamily
+	}
+}
+
+// trailing zero octets of address are omitted on wire
+func (item *APLItem) afdPart() []byte {
+	addr := item.Address.To16()
+	if item.Family == APL_FAMILY_IPV4 {
+		addr = item.Address.To4()
+	}
+	end := len(addr)
+	for end > 0 && addr[end-1] == 0 {
+		end -= 1
+	}
+	return addr[:end]
+}
+
+func (item *APLItem) toWire(buf *util.OutputBuffer) {
+	afd := item.afdPart()
+	buf.WriteUint16(item.Family)
+	buf.WriteUint8(item.Prefix)
+	l := uint8(len(afd))
+	if item.Negation {
+		l |= 0x80
+	}
+	buf.WriteUint8(l)
+	buf.WriteData(afd)
+}
+
+func (item *APLItem) String() string {
+	var buf bytes.Buffer
+	if item.Negation {
+		buf.WriteByte('!')
+	}
+	buf.WriteString(strconv.Itoa(int(item.Family)))
+	buf.WriteByte(':')
+	if item.Family == APL_FAMILY_IPV6 && item.Address.To4() != nil {
+		buf.WriteString("::ffff:")
+	}
+	buf.WriteString(item.Address.String())
+	buf.WriteByte('/')
+	buf.WriteString(strconv.Itoa(int(item.Prefix)))
+	return buf.String()
+}
+
+func (a *APL) wireData() []byte {
+	buf := util.NewOutputBuffer(32)
+	for i := range a.Items {
+		a.Items[i].toWire(buf)
+	}
+	return buf.Data()
+}
+
+func (a *APL) Rend(r *MsgRender) {
+	rendField(RDF_C_BINARY, a.wireData(), r)
+}
+
+func (a *APL) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_BINARY, a.wireData(), buf)
+}
+
+func (a *APL) Compare(other Rdata) int {
+	return fieldCompare(RDF_C_BINARY, a.wireData(), other.(*APL).wireData())
+}
+
+func (a *APL) String() string {
+	items := make([]string, len(a.Items))
+	for i := range a.Items {
+		items[i] = a.Items[i].String()
+	}
+	return strings.Join(items, " ")
+}
+
+func APLFromWire(buf *util.InputBuffer, ll uint16) (*APL, error) {
+	var items []APLItem
+	var family, prefix, l, afd interface{}
+	var addrLen int
+	var err error
+	for ll > 0 {
+		if family, ll, err = fieldFromWire(RDF_C_UINT16, buf, ll); err != nil {
+			return nil, err
+		}
+
+		if addrLen, err = aplAddressLen(family.(uint16)); err != nil {
+			return nil, err
+		}
+
+		if prefix, ll, err = fieldFromWire(RDF_C_UINT8, buf, ll); err != nil {
+			return nil, err
+		}
+
+		if l, ll, err = fieldFromWire(RDF_C_UINT8, buf, ll); err != nil {
+			return nil, err
+		}
+
+		afdLen := uint16(l.(uint8) & 0x7f)
+		if int(afdLen) > addrLen || int(prefix.(uint8)) > addrLen*8 {
+			return nil, ErrInvalidAPLItem
+		} else if afdLen > ll {
+			return nil, ErrDataIsTooShort
+		}
+
+		if afd, _, err = fieldFromWire(RDF_C_BINARY, buf, afdLen); err != nil {
+			return nil, err
+		}
+		ll -= afdLen
+
+		addr := make(net.IP, addrLen)
+		copy(addr, afd.([]uint8))
+		if afdLen > 0 && addr[afdLen-1] == 0 {
+			return nil, ErrInvalidAPLItem
+		}
+
+		items = append(items, APLItem{
+			Negation: l.(uint8)&0x80 != 0,
+			Family:   family.(uint16),
+			Prefix:   prefix.(uint8),
+			Address:  addr,
+		})
+	}
+	return &APL{items}, nil
+}
+
+func aplItemFromString(s string) (APLItem, error) {
+	var item APLItem
+	if strings.HasPrefix(s, "!") {
+		item.Negation = true
+		s = s[1:]
+	}
+
+	colon := strings.IndexByte(s, ':')
+	slash := strings.LastIndexByte(s, '/')
+	if colon == -1 || slash < colon {
+		return item, ErrInvalidAPLItem
+	}
+
+	family, err := uint16FromString(s[:colon])
+	if err != nil {
+		return item, err
+	}
+	addrLen, err := aplAddressLen(family)
+	if err != nil {
+		return item, err
+	}
+	item.Family = family
+
+	prefix, err := uint8FromString(s[slash+1:])
+	if err != nil || int(prefix) > addrLen*8 {
+		return item, ErrInvalidAPLItem
+	}
+	item.Prefix = prefix
+
+	addr := s[colon+1 : slash]
+	ip := net.ParseIP(addr)
+	if ip == nil || (family == APL_FAMILY_IPV4) == strings.Contains(addr, ":") {
+		return item, ErrInvalidAPLItem
+	}
+	if family == APL_FAMILY_IPV4 {
+		item.Address = ip.To4()
+	} else {
+		item.Address = ip.To16()
+	}
+	return item, nil
+}
+
+func APLFromString(s string) (*APL, error) {
+	var items []APLItem
+	for _, field := range strings.Fields(s) {
+		item, err := aplItemFromString(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", field, err.Error())
+		}
+		items = append(items, item)
+	}
+	return &APL{items}, nil
+}
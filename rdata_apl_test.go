@@ -0,0 +1,53 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// examples from RFC 3123
+func TestAPLFromToString(t *testing.T) {
+	apl := rdataRoundTrip(t, RR_APL, "1:192.168.32.0/21 !1:192.168.38.0/28",
+		"1:192.168.32.0/21 !1:192.168.38.0/28")
+	buf := util.NewOutputBuffer(32)
+	apl.ToWire(buf)
+	wire, _ := util.HexStrToBytes("0001" + "1503c0a820" + "0001" + "1c83c0a826")
+	WireMatch(t, wire, buf.Data())
+
+	rdataRoundTrip(t, RR_APL, "1:224.0.0.0/4 2:FF00:0:0:0:0:0:0:0/8", "1:224.0.0.0/4 2:ff00::/8")
+	rdataRoundTrip(t, RR_APL, "1:0.0.0.0/0 !2:::/0", "1:0.0.0.0/0 !2:::/0")
+
+	apl, err := APLFromString("")
+	Assert(t, err == nil, "empty apl is valid")
+	Equal(t, len(apl.(*APL).Items), 0)
+	apl, err = APLFromWire(util.NewInputBuffer(nil), 0)
+	Assert(t, err == nil, "empty apl is valid")
+	Equal(t, apl.String(), "")
+
+	for _, s := range []string{
+		"3:192.168.32.0/21",
+		"1:192.168.32.0/33",
+		"2:ff00::/129",
+		"1:ff00::/8",
+		"2:192.168.32.0/21",
+		"1:192.168.32.0",
+		"192.168.32.0/21",
+		"!!1:192.168.32.0/21",
+	} {
+		_, err := APLFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+
+	for _, s := range []string{
+		"0003" + "1503c0a820",
+		"0001" + "1505c0a8200001",
+		"0001" + "1504c0a82000",
+		"0001" + "2103c0a820",
+		"0001" + "1503c0a8",
+	} {
+		wire, _ := util.HexStrToBytes(s)
+		_, err := APLFromWire(util.NewInputBuffer(wire), uint16(len(wire)))
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -0,0 +1,139 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrInvalidCAATag   = errors.New("caa tag should be 1 to 15 letters or digits")
+	ErrInvalidCAAValue = errors.New("caa value isn't valid")
+)
+
+const CAAFlagCritical = 128
+
+// RFC 8659
+type CAA struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+func (c *CAA) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT8, c.Flags, r)
+	rendField(RDF_C_BYTE_BINARY, []byte(c.Tag), r)
+	rendField(RDF_C_BINARY, []byte(c.Value), r)
+}
+
+func (c *CAA) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT8, c.Flags, buf)
+	fieldToWire(RDF_C_BYTE_BINARY, []byte(c.Tag), buf)
+	fieldToWire(RDF_C_BINARY, []byte(c.Value), buf)
+}
+
+func (c *CAA) Compare(other Rdata) int {
+	otherCAA := other.(*CAA)
+	order := fieldCompare(RDF_C_UINT8, c.Flags, otherCAA.Flags)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_BYTE_BINARY, []byte(c.Tag), []byte(otherCAA.Tag))
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, []byte(c.Value), []byte(otherCAA.Value))
+}
+
+func (c *CAA) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, c.Flags))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_STR, c.Tag))
+	buf.WriteString(" ")
+	buf.WriteString(quoteCharString(c.Value))
+	return buf.String()
+}
+
+func (c *CAA) IsCritical() bool {
+	return c.Flags&CAAFlagCritical != 0
+}
+
+func validateCAATag(tag string) error {
+	if len(tag) == 0 || len(tag) > 15 {
+		return ErrInvalidCAATag
+	}
+
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		if isDigit(c) == false && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return ErrInvalidCAATag
+		}
+	}
+	return nil
+}
+
+func CAAFromWire(buf *util.InputBuffer, ll uint16) (*CAA, error) {
+	f, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ll, err := fieldFromWire(RDF_C_BYTE_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	tag := string(t.([]uint8))
+	if err := validateCAATag(tag); err != nil {
+		return nil, err
+	}
+
+	v, ll, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	return &CAA{
+		Flags: f.(uint8),
+		Tag:   tag,
+		Value: string(v.([]uint8)),
+	}, nil
+}
+
+var caaRdataTemplate = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s+(.*?)\s*$`)
+
+func CAAFromString(s string) (*CAA, error) {
+	fields := caaRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 4 {
+		return nil, errors.New("short of fields for caa")
+	}
+	fields = fields[1:]
+
+	flags, err := uint8FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCAATag(fields[1]); err != nil {
+		return nil, err
+	}
+
+	value, err := unquoteCharString(fields[2])
+	if err != nil {
+		return nil, ErrInvalidCAAValue
+	}
+
+	return &CAA{
+		Flags: flags,
+		Tag:   fields[1],
+		Value: value,
+	}, nil
+}
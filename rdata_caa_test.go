@@ -0,0 +1,33 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestCAAFromToString(t *testing.T) {
+	caa := rdataRoundTrip(t, RR_CAA, `0 issue "ca.example.net; account=230123"`,
+		`0 issue "ca.example.net; account=230123"`).(*CAA)
+	Equal(t, caa.Tag, "issue")
+	Equal(t, caa.Value, "ca.example.net; account=230123")
+	Equal(t, caa.IsCritical(), false)
+
+	caa = rdataRoundTrip(t, RR_CAA, `128 tbs "Unknown \"quoted\" \\ \009"`,
+		`128 tbs "Unknown \"quoted\" \\ \009"`).(*CAA)
+	Equal(t, caa.Value, "Unknown \"quoted\" \\ \t")
+	Equal(t, caa.IsCritical(), true)
+
+	rdataRoundTrip(t, RR_CAA, `0 iodef mailto:security@example.com`, `0 iodef "mailto:security@example.com"`)
+	rdataRoundTrip(t, RR_CAA, `0 issue ""`, `0 issue ""`)
+
+	for _, s := range []string{
+		`0 issue-wild "ca.example.net"`,
+		`0 abcdefghijklmnop "ca.example.net"`,
+		`256 issue "ca.example.net"`,
+		`0 issue "ca.example.net`,
+		`0 issue ca example`,
+		`0 issue`,
+	} {
+		_, err := CAAFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
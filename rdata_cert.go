@@ -0,0 +1,197 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrReservedCertType = errors.New("cert type is reserved")
+	ErrEmptyCert        = errors.New("cert data is empty")
+)
+
+const (
+	CERT_PKIX    uint16 = 1
+	CERT_SPKI    uint16 = 2
+	CERT_PGP     uint16 = 3
+	CERT_IPKIX   uint16 = 4
+	CERT_ISPKI   uint16 = 5
+	CERT_IPGP    uint16 = 6
+	CERT_ACPKIX  uint16 = 7
+	CERT_IACPKIX uint16 = 8
+	CERT_URI     uint16 = 253
+	CERT_OID     uint16 = 254
+)
+
+var certTypeNameMap = map[uint16]string{
+	CERT_PKIX:    "PKIX",
+	CERT_SPKI:    "SPKI",
+	CERT_PGP:     "PGP",
+	CERT_IPKIX:   "IPKIX",
+	CERT_ISPKI:   "ISPKI",
+	CERT_IPGP:    "IPGP",
+	CERT_ACPKIX:  "ACPKIX",
+	CERT_IACPKIX: "IACPKIX",
+	CERT_URI:     "URI",
+	CERT_OID:     "OID",
+}
+
+// RFC 4398
+type CERT struct {
+	Type        uint16
+	KeyTag      uint16
+	Algorithm   uint8
+	Certificate []byte
+}
+
+func (c *CERT) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, c.Type, r)
+	rendField(RDF_C_UINT16, c.KeyTag, r)
+	rendField(RDF_C_UINT8, c.Algorithm, r)
+	rendField(RDF_C_BINARY, c.Certificate, r)
+}
+
+func (c *CERT) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, c.Type, buf)
+	fieldToWire(RDF_C_UINT16, c.KeyTag, buf)
+	fieldToWire(RDF_C_UINT8, c.Algorithm, buf)
+	fieldToWire(RDF_C_BINARY, c.Certificate, buf)
+}
+
+func (c *CERT) Compare(other Rdata) int {
+	otherCERT := other.(*CERT)
+	order := fieldCompare(RDF_C_UINT16, c.Type, otherCERT.Type)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT16, c.KeyTag, otherCERT.KeyTag)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, c.Algorithm, otherCERT.Algorithm)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, c.Certificate, otherCERT.Certificate)
+}
+
+func (c *CERT) String() string {
+	var buf bytes.Buffer
+	if name, ok := certTypeNameMap[c.Type]; ok {
+		buf.WriteString(name)
+	} else {
+		buf.WriteString(fieldToString(RDF_D_INT, c.Type))
+	}
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, c.KeyTag))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, c.Algorithm))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_B64, c.Certificate))
+	return buf.String()
+}
+
+func (c *CERT) validate() error {
+	if c.Type == 0 || c.Type == 255 || c.Type == 65535 {
+		return ErrReservedCertType
+	}
+
+	if len(c.Certificate) == 0 {
+		return ErrEmptyCert
+	}
+	return nil
+}
+
+func CERTFromWire(buf *util.InputBuffer, ll uint16) (*CERT, error) {
+	t, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	k, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	a, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	d, ll, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	cert := &CERT{
+		Type:        t.(uint16),
+		KeyTag:      k.(uint16),
+		Algorithm:   a.(uint8),
+		Certificate: d.([]uint8),
+	}
+	if err := cert.validate(); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+func certTypeFromString(s string) (uint16, error) {
+	for t, name := range certTypeNameMap {
+		if strings.EqualFold(name, s) {
+			return t, nil
+		}
+	}
+	return uint16FromString(s)
+}
+
+var certRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s+(\S+)\s+(.*?)\s*$`)
+
+func CERTFromString(s string) (*CERT, error) {
+	fields := certRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 5 {
+		return nil, errors.New("short of fields for cert")
+	}
+	fields = fields[1:]
+
+	typ, err := certTypeFromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	keyTag, err := uint16FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := uint8FromString(fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64FromString(fields[3])
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &CERT{
+		Type:        typ,
+		KeyTag:      keyTag,
+		Algorithm:   algorithm,
+		Certificate: data,
+	}
+	if err := cert.validate(); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
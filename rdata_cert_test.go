@@ -0,0 +1,25 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestCERTFromToString(t *testing.T) {
+	cert := rdataRoundTrip(t, RR_CERT, "pkix 12345 8 MIIBIjANBgkqhkiG9w0B AQEFAAOCAQ8AMIIBCgKC",
+		"PKIX 12345 8 MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKC").(*CERT)
+	Equal(t, cert.Type, CERT_PKIX)
+	Equal(t, cert.KeyTag, uint16(12345))
+	rdataRoundTrip(t, RR_CERT, "1000 0 0 AQID", "1000 0 0 AQID")
+
+	for _, s := range []string{
+		"0 0 0 AQID",
+		"255 0 0 AQID",
+		"PKIX 65536 0 AQID",
+		"PKIX 0 256 AQID",
+		"PKIX 0 0 AQI",
+		"UNKNOWN 0 0 AQID",
+	} {
+		_, err := CERTFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
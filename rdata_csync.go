@@ -0,0 +1,147 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+const (
+	CSYNC_FLAG_IMMEDIATE  uint16 = 0x0001
+	CSYNC_FLAG_SOAMINIMUM uint16 = 0x0002
+)
+
+// RFC 7477, type bitmap has the same format as nsec3, types are kept
+// sorted and unique
+type CSYNC struct {
+	Serial uint32
+	Flags  uint16
+	Types  []RRType
+}
+
+func (c *CSYNC) typeBitmap() []byte {
+	if len(c.Types) == 0 {
+		return nil
+	}
+	return encodeNSEC3Bytes(c.Types)
+}
+
+func (c *CSYNC) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT32, c.Serial, r)
+	rendField(RDF_C_UINT16, c.Flags, r)
+	rendField(RDF_C_BINARY, c.typeBitmap(), r)
+}
+
+func (c *CSYNC) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT32, c.Serial, buf)
+	fieldToWire(RDF_C_UINT16, c.Flags, buf)
+	fieldToWire(RDF_C_BINARY, c.typeBitmap(), buf)
+}
+
+func (c *CSYNC) Compare(other Rdata) int {
+	otherCSYNC := other.(*CSYNC)
+	order := fieldCompare(RDF_C_UINT32, c.Serial, otherCSYNC.Serial)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT16, c.Flags, otherCSYNC.Flags)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, c.typeBitmap(), otherCSYNC.typeBitmap())
+}
+
+func (c *CSYNC) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, c.Serial))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, c.Flags))
+	for _, typ := range c.Types {
+		buf.WriteString(" ")
+		buf.WriteString(typ.String())
+	}
+	return buf.String()
+}
+
+func (c *CSYNC) IsImmediate() bool {
+	return c.Flags&CSYNC_FLAG_IMMEDIATE != 0
+}
+
+func (c *CSYNC) UseSOAMinimum() bool {
+	return c.Flags&CSYNC_FLAG_SOAMINIMUM != 0
+}
+
+func CSYNCFromWire(buf *util.InputBuffer, ll uint16) (*CSYNC, error) {
+	serial, ll, err := fieldFromWire(RDF_C_UINT32, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap, _, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	types, err := decodeNSEC3Types(bitmap.([]byte))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSYNC{
+		Serial: serial.(uint32),
+		Flags:  flags.(uint16),
+		Types:  types,
+	}, nil
+}
+
+var csyncRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s*(.*?)\s*$`)
+
+func CSYNCFromString(s string) (*CSYNC, error) {
+	fields := csyncRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 4 {
+		return nil, errors.New("short of fields for csync")
+	}
+	fields = fields[1:]
+
+	serial, err := uint32FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := uint16FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var types []RRType
+	if len(fields[2]) != 0 {
+		seen := make(map[RRType]struct{})
+		for _, field := range spaceReg.Split(fields[2], -1) {
+			typ, err := TypeFromString(field)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[typ]; ok == false {
+				seen[typ] = struct{}{}
+				types = append(types, typ)
+			}
+		}
+		sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	}
+
+	return &CSYNC{
+		Serial: serial,
+		Flags:  flags,
+		Types:  types,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestCSYNCFromToString(t *testing.T) {
+	// example from RFC 7477
+	csync := rdataRoundTrip(t, RR_CSYNC, "66 3 A NS AAAA", "66 3 A NS AAAA").(*CSYNC)
+	Equal(t, csync.IsImmediate(), true)
+	Equal(t, csync.UseSOAMinimum(), true)
+	buf := util.NewOutputBuffer(16)
+	csync.ToWire(buf)
+	wire, _ := util.HexStrToBytes("00000042" + "0003" + "000460000008")
+	WireMatch(t, wire, buf.Data())
+
+	csync = rdataRoundTrip(t, RR_CSYNC, "4294967295 0 aaaa ns a ns caa", "4294967295 0 A NS AAAA CAA").(*CSYNC)
+	Equal(t, csync.IsImmediate(), false)
+	rdataRoundTrip(t, RR_CSYNC, "1 1", "1 1")
+
+	for _, s := range []string{
+		"66 65536 A NS AAAA",
+		"4294967296 3 A NS AAAA",
+		"66 3 A NS BOGUS",
+		"66",
+	} {
+		_, err := CSYNCFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
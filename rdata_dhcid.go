@@ -0,0 +1,52 @@
+package g53
+
+import (
+	"errors"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrEmptyDHCID = errors.New("dhcid digest is empty")
+
+// RFC 4701, the content is opaque to dns
+type DHCID struct {
+	Digest []byte
+}
+
+func (d *DHCID) Rend(r *MsgRender) {
+	rendField(RDF_C_BINARY, d.Digest, r)
+}
+
+func (d *DHCID) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_BINARY, d.Digest, buf)
+}
+
+func (d *DHCID) Compare(other Rdata) int {
+	return fieldCompare(RDF_C_BINARY, d.Digest, other.(*DHCID).Digest)
+}
+
+func (d *DHCID) String() string {
+	return fieldToString(RDF_D_B64, d.Digest)
+}
+
+func DHCIDFromWire(buf *util.InputBuffer, ll uint16) (*DHCID, error) {
+	if ll == 0 {
+		return nil, ErrEmptyDHCID
+	}
+
+	d, _, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &DHCID{d.([]uint8)}, nil
+}
+
+func DHCIDFromString(s string) (*DHCID, error) {
+	d, err := base64FromString(s)
+	if err != nil {
+		return nil, err
+	} else if len(d) == 0 {
+		return nil, ErrEmptyDHCID
+	}
+	return &DHCID{d}, nil
+}
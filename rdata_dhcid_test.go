@@ -0,0 +1,27 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestDHCIDFromToString(t *testing.T) {
+	// example from RFC 4701
+	rdataRoundTrip(t, RR_DHCID, "AAIBY2/AuCccgoJbsaxcQc9TUapptP69lOjxfNuVAA2kjEA=",
+		"AAIBY2/AuCccgoJbsaxcQc9TUapptP69lOjxfNuVAA2kjEA=")
+	rdataRoundTrip(t, RR_DHCID, "AAIBY2/AuCccgoJbsaxcQc9TUapptP69\n\tlOjxfNuVAA2kjEA=",
+		"AAIBY2/AuCccgoJbsaxcQc9TUapptP69lOjxfNuVAA2kjEA=")
+
+	for _, s := range []string{"", "AAIBY2/AuCcc!", "AAIBY2/AuCcc="} {
+		_, err := DHCIDFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
+
+func TestOPENPGPKEYFromToString(t *testing.T) {
+	rdataRoundTrip(t, RR_OPENPGPKEY, "mQINBFX4 a2VlcGVy ZmFrZQ==", "mQINBFX4a2VlcGVyZmFrZQ==")
+
+	for _, s := range []string{"", "mQINBFX4a2VlcGVyZmFrZQ"} {
+		_, err := OPENPGPKEYFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -97,7 +97,7 @@ func DSFromWire(buf *util.InputBuffer, ll uint16) (*DS, error) {
 	}, nil
 }
 
-var dsRdataTemplate = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s+(\S+)\s+(.*?)\s*$`)
+var dsRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s+(\S+)\s+(.*?)\s*$`)
 var dsDigestTemplate = regexp.MustCompile(`\s+`)
 
 func DSFromString(s string) (*DS, error) {
@@ -35,3 +35,9 @@ func TestDSFromWire(t *testing.T) {
 	ds.Rend(render)
 	WireMatch(t, render.Data(), ds_wire)
 }
+
+func TestDSFromMultiLineString(t *testing.T) {
+	ds, err := DSFromString("30909 8 2 E2D3C916F6DEEAC73294E8268FB5885044A833FC5459588F\n\t4A9184CFC41A5766")
+	Assert(t, err == nil, "parse multi line ds failed:%v", err)
+	Equal(t, strings.ToUpper(ds.Digest), "E2D3C916F6DEEAC73294E8268FB5885044A833FC5459588F4A9184CFC41A5766")
+}
@@ -0,0 +1,124 @@
+package g53
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrInvalidEUI = errors.New("eui address should be hex pairs separated by hyphen")
+
+// RFC 7043
+type EUI48 struct {
+	Address [6]byte
+}
+
+type EUI64 struct {
+	Address [8]byte
+}
+
+func euiToString(addr []byte) string {
+	groups := make([]string, len(addr))
+	for i, b := range addr {
+		groups[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(groups, "-")
+}
+
+func euiFromString(s string, addr []byte) error {
+	groups := strings.Split(strings.TrimSpace(s), "-")
+	if len(groups) != len(addr) {
+		return ErrInvalidEUI
+	}
+
+	for i, g := range groups {
+		if len(g) != 2 {
+			return ErrInvalidEUI
+		}
+		b, err := hex.DecodeString(g)
+		if err != nil {
+			return ErrInvalidEUI
+		}
+		addr[i] = b[0]
+	}
+	return nil
+}
+
+func euiFromWire(buf *util.InputBuffer, ll uint16, addr []byte) error {
+	if int(ll) != len(addr) {
+		return errors.New("eui rdata length isn't valid")
+	}
+
+	d, _, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return err
+	}
+	copy(addr, d.([]uint8))
+	return nil
+}
+
+func (e *EUI48) Rend(r *MsgRender) {
+	rendField(RDF_C_BINARY, e.Address[:], r)
+}
+
+func (e *EUI48) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_BINARY, e.Address[:], buf)
+}
+
+func (e *EUI48) Compare(other Rdata) int {
+	return fieldCompare(RDF_C_BINARY, e.Address[:], other.(*EUI48).Address[:])
+}
+
+func (e *EUI48) String() string {
+	return euiToString(e.Address[:])
+}
+
+func EUI48FromWire(buf *util.InputBuffer, ll uint16) (*EUI48, error) {
+	var e EUI48
+	if err := euiFromWire(buf, ll, e.Address[:]); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func EUI48FromString(s string) (*EUI48, error) {
+	var e EUI48
+	if err := euiFromString(s, e.Address[:]); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (e *EUI64) Rend(r *MsgRender) {
+	rendField(RDF_C_BINARY, e.Address[:], r)
+}
+
+func (e *EUI64) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_BINARY, e.Address[:], buf)
+}
+
+func (e *EUI64) Compare(other Rdata) int {
+	return fieldCompare(RDF_C_BINARY, e.Address[:], other.(*EUI64).Address[:])
+}
+
+func (e *EUI64) String() string {
+	return euiToString(e.Address[:])
+}
+
+func EUI64FromWire(buf *util.InputBuffer, ll uint16) (*EUI64, error) {
+	var e EUI64
+	if err := euiFromWire(buf, ll, e.Address[:]); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func EUI64FromString(s string) (*EUI64, error) {
+	var e EUI64
+	if err := euiFromString(s, e.Address[:]); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
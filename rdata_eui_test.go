@@ -0,0 +1,25 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestEUIFromToString(t *testing.T) {
+	rdataRoundTrip(t, RR_EUI48, "00-00-5e-00-53-2a", "00-00-5e-00-53-2a")
+	rdataRoundTrip(t, RR_EUI48, "00-00-5E-00-53-2A", "00-00-5e-00-53-2a")
+	rdataRoundTrip(t, RR_EUI64, "00-00-5e-ef-10-00-00-2a", "00-00-5e-ef-10-00-00-2a")
+
+	for _, s := range []string{
+		"00-00-5e-00-53",
+		"00-00-5e-00-53-2a-00",
+		"00:00:5e:00:53:2a",
+		"00-00-5e-00-53-2g",
+		"000-00-5e-00-53-2",
+	} {
+		_, err := EUI48FromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+
+	_, err := EUI64FromString("00-00-5e-00-53-2a")
+	Assert(t, err != nil, "eui48 address isn't valid eui64")
+}
@@ -0,0 +1,244 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"regexp"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrUnknownIPSECKEYGateway = errors.New("unknown ipseckey gateway type")
+	ErrInvalidIPSECKEYGateway = errors.New("ipseckey gateway doesn't match gateway type")
+)
+
+const (
+	IPSECKEY_GATEWAY_NONE uint8 = 0
+	IPSECKEY_GATEWAY_IPV4 uint8 = 1
+	IPSECKEY_GATEWAY_IPV6 uint8 = 2
+	IPSECKEY_GATEWAY_NAME uint8 = 3
+
+	IPSECKEY_ALG_NONE uint8 = 0
+	IPSECKEY_ALG_DSA  uint8 = 1
+	IPSECKEY_ALG_RSA  uint8 = 2
+)
+
+// RFC 4025, according to GatewayType, only one of GatewayAddr and
+// GatewayName is set, neither is set when there is no gateway
+type IPSECKEY struct {
+	Precedence  uint8
+	GatewayType uint8
+	Algorithm   uint8
+	GatewayAddr net.IP
+	GatewayName *Name
+	PublicKey   []byte
+}
+
+func (k *IPSECKEY) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT8, k.Precedence, r)
+	rendField(RDF_C_UINT8, k.GatewayType, r)
+	rendField(RDF_C_UINT8, k.Algorithm, r)
+	switch k.GatewayType {
+	case IPSECKEY_GATEWAY_IPV4:
+		rendField(RDF_C_IPV4, k.GatewayAddr, r)
+	case IPSECKEY_GATEWAY_IPV6:
+		rendField(RDF_C_IPV6, k.GatewayAddr, r)
+	case IPSECKEY_GATEWAY_NAME:
+		rendField(RDF_C_NAME_UNCOMPRESS, k.GatewayName, r)
+	}
+	rendField(RDF_C_BINARY, k.PublicKey, r)
+}
+
+func (k *IPSECKEY) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT8, k.Precedence, buf)
+	fieldToWire(RDF_C_UINT8, k.GatewayType, buf)
+	fieldToWire(RDF_C_UINT8, k.Algorithm, buf)
+	switch k.GatewayType {
+	case IPSECKEY_GATEWAY_IPV4:
+		fieldToWire(RDF_C_IPV4, k.GatewayAddr, buf)
+	case IPSECKEY_GATEWAY_IPV6:
+		fieldToWire(RDF_C_IPV6, k.GatewayAddr, buf)
+	case IPSECKEY_GATEWAY_NAME:
+		fieldToWire(RDF_C_NAME_UNCOMPRESS, k.GatewayName, buf)
+	}
+	fieldToWire(RDF_C_BINARY, k.PublicKey, buf)
+}
+
+func (k *IPSECKEY) Compare(other Rdata) int {
+	otherKey := other.(*IPSECKEY)
+	order := fieldCompare(RDF_C_UINT8, k.Precedence, otherKey.Precedence)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, k.GatewayType, otherKey.GatewayType)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, k.Algorithm, otherKey.Algorithm)
+	if order != 0 {
+		return order
+	}
+
+	switch k.GatewayType {
+	case IPSECKEY_GATEWAY_IPV4:
+		order = fieldCompare(RDF_C_IPV4, k.GatewayAddr, otherKey.GatewayAddr)
+	case IPSECKEY_GATEWAY_IPV6:
+		order = fieldCompare(RDF_C_IPV6, k.GatewayAddr, otherKey.GatewayAddr)
+	case IPSECKEY_GATEWAY_NAME:
+		order = fieldCompare(RDF_C_NAME_UNCOMPRESS, k.GatewayName, otherKey.GatewayName)
+	}
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, k.PublicKey, otherKey.PublicKey)
+}
+
+func (k *IPSECKEY) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, k.Precedence))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, k.GatewayType))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, k.Algorithm))
+	buf.WriteString(" ")
+	switch k.GatewayType {
+	case IPSECKEY_GATEWAY_IPV4, IPSECKEY_GATEWAY_IPV6:
+		buf.WriteString(fieldToString(RDF_D_IP, k.GatewayAddr))
+	case IPSECKEY_GATEWAY_NAME:
+		buf.WriteString(fieldToString(RDF_D_NAME, k.GatewayName))
+	default:
+		buf.WriteString(".")
+	}
+	if len(k.PublicKey) != 0 {
+		buf.WriteString(" ")
+		buf.WriteString(fieldToString(RDF_D_B64, k.PublicKey))
+	}
+	return buf.String()
+}
+
+func IPSECKEYFromWire(buf *util.InputBuffer, ll uint16) (*IPSECKEY, error) {
+	p, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	a, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &IPSECKEY{
+		Precedence:  p.(uint8),
+		GatewayType: t.(uint8),
+		Algorithm:   a.(uint8),
+	}
+
+	var gateway interface{}
+	switch key.GatewayType {
+	case IPSECKEY_GATEWAY_NONE:
+	case IPSECKEY_GATEWAY_IPV4:
+		gateway, ll, err = fieldFromWire(RDF_C_IPV4, buf, ll)
+	case IPSECKEY_GATEWAY_IPV6:
+		gateway, ll, err = fieldFromWire(RDF_C_IPV6, buf, ll)
+	case IPSECKEY_GATEWAY_NAME:
+		gateway, ll, err = fieldFromWire(RDF_C_NAME_UNCOMPRESS, buf, ll)
+	default:
+		return nil, ErrUnknownIPSECKEYGateway
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ip, ok := gateway.(net.IP); ok {
+		key.GatewayAddr = ip
+	} else if name, ok := gateway.(*Name); ok {
+		key.GatewayName = name
+	}
+
+	pk, _, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	if len(pk.([]uint8)) != 0 {
+		key.PublicKey = pk.([]uint8)
+	}
+	return key, nil
+}
+
+var ipseckeyRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s+(\S+)\s+(\S+)\s*(.*?)\s*$`)
+
+func IPSECKEYFromString(s string) (*IPSECKEY, error) {
+	fields := ipseckeyRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 6 {
+		return nil, errors.New("short of fields for ipseckey")
+	}
+	fields = fields[1:]
+
+	precedence, err := uint8FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	gatewayType, err := uint8FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := uint8FromString(fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	key := &IPSECKEY{
+		Precedence:  precedence,
+		GatewayType: gatewayType,
+		Algorithm:   algorithm,
+	}
+
+	switch gatewayType {
+	case IPSECKEY_GATEWAY_NONE:
+		if fields[3] != "." {
+			return nil, ErrInvalidIPSECKEYGateway
+		}
+	case IPSECKEY_GATEWAY_IPV4, IPSECKEY_GATEWAY_IPV6:
+		ip := net.ParseIP(fields[3])
+		if ip == nil {
+			return nil, ErrInvalidIPSECKEYGateway
+		}
+		if ip4 := ip.To4(); gatewayType == IPSECKEY_GATEWAY_IPV4 {
+			if ip4 == nil {
+				return nil, ErrInvalidIPSECKEYGateway
+			}
+			ip = ip4
+		} else if ip4 != nil {
+			return nil, ErrInvalidIPSECKEYGateway
+		}
+		key.GatewayAddr = ip
+	case IPSECKEY_GATEWAY_NAME:
+		name, err := NameFromString(fields[3])
+		if err != nil {
+			return nil, err
+		}
+		key.GatewayName = name
+	default:
+		return nil, ErrUnknownIPSECKEYGateway
+	}
+
+	if len(fields[4]) != 0 {
+		pk, err := base64FromString(fields[4])
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = pk
+	}
+	return key, nil
+}
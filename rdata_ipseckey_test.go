@@ -0,0 +1,35 @@
+package g53
+
+import (
+	"testing"
+)
+
+// examples from RFC 4025
+func TestIPSECKEYFromToString(t *testing.T) {
+	key := "AQNRU3mG7TVTO2BkR47usntb102uFJtugbo6BSGvgqt4AQ=="
+	ipseckey := rdataRoundTrip(t, RR_IPSECKEY, "10 1 2 192.0.2.38 "+key, "10 1 2 192.0.2.38 "+key).(*IPSECKEY)
+	Equal(t, ipseckey.GatewayAddr.String(), "192.0.2.38")
+	Assert(t, ipseckey.GatewayName == nil, "ipv4 gateway has no name")
+
+	rdataRoundTrip(t, RR_IPSECKEY, "10 0 2 . "+key, "10 0 2 . "+key)
+	rdataRoundTrip(t, RR_IPSECKEY, "10 1 2 192.0.2.3 AQNRU3mG7TVTO2BkR47usntb1\n02uFJtugbo6BSGvgqt4AQ==",
+		"10 1 2 192.0.2.3 "+key)
+	ipseckey = rdataRoundTrip(t, RR_IPSECKEY, "10 3 2 mygateway.example.com. "+key,
+		"10 3 2 mygateway.example.com. "+key).(*IPSECKEY)
+	NameEqToStr(t, ipseckey.GatewayName, "mygateway.example.com.")
+	rdataRoundTrip(t, RR_IPSECKEY, "10 2 2 2001:0DB8:0:8002::2000:1 "+key, "10 2 2 2001:db8:0:8002::2000:1 "+key)
+	rdataRoundTrip(t, RR_IPSECKEY, "10 1 0 192.0.2.3", "10 1 0 192.0.2.3")
+
+	for _, s := range []string{
+		"10 0 2 192.0.2.38 " + key,
+		"10 1 2 2001:db8::1 " + key,
+		"10 2 2 192.0.2.38 " + key,
+		"10 4 2 192.0.2.38 " + key,
+		"10 1 2 192.0.2.38 AQNRU3mG7T!",
+		"256 1 2 192.0.2.38 " + key,
+		"10 1 2",
+	} {
+		_, err := IPSECKEYFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -0,0 +1,55 @@
+package g53
+
+import (
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// RFC 2230
+type KX struct {
+	Preference uint16
+	Exchanger  *Name
+}
+
+func (k *KX) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, k.Preference, r)
+	rendField(RDF_C_NAME_UNCOMPRESS, k.Exchanger, r)
+}
+
+func (k *KX) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, k.Preference, buf)
+	fieldToWire(RDF_C_NAME_UNCOMPRESS, k.Exchanger, buf)
+}
+
+func (k *KX) Compare(other Rdata) int {
+	otherKX := other.(*KX)
+	order := fieldCompare(RDF_C_UINT16, k.Preference, otherKX.Preference)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_NAME_UNCOMPRESS, k.Exchanger, otherKX.Exchanger)
+}
+
+func (k *KX) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, k.Preference),
+		fieldToString(RDF_D_NAME, k.Exchanger)}, " ")
+}
+
+func KXFromWire(buf *util.InputBuffer, ll uint16) (*KX, error) {
+	d, n, err := uint16NameFromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &KX{d, n}, nil
+}
+
+func KXFromString(s string) (*KX, error) {
+	d, n, err := uint16NameFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &KX{d, n}, nil
+}
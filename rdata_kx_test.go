@@ -0,0 +1,33 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestKXFromToString(t *testing.T) {
+	kx := rdataRoundTrip(t, RR_KX, "10 kx.example.com.", "10 kx.example.com.").(*KX)
+	Equal(t, kx.Preference, uint16(10))
+	NameEqToStr(t, kx.Exchanger, "kx.example.com.")
+	rdataRoundTrip(t, RR_KX, "65535 KX.Example.com.", "65535 kx.example.com.")
+
+	//exchanger isn't compressed even if it has been rendered
+	render := NewMsgRender()
+	kx.Exchanger.Rend(render)
+	pos := render.Len()
+	kx.Rend(render)
+	wire, _ := util.HexStrToBytes("000a" + "026b78" + "076578616d706c6503636f6d00")
+	WireMatch(t, wire, render.Data()[pos:])
+
+	for _, s := range []string{
+		"65536 kx.example.com.",
+		"-1 kx.example.com.",
+		"kx.example.com.",
+		"10",
+		"10 kx.example.com. extra",
+	} {
+		_, err := KXFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
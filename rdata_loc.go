@@ -0,0 +1,342 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrUnknownLOCVersion = errors.New("loc version isn't 0")
+	ErrInvalidLOCSize    = errors.New("loc size or precision isn't valid")
+	ErrInvalidLOCLat     = errors.New("loc latitude isn't valid")
+	ErrInvalidLOCLon     = errors.New("loc longitude isn't valid")
+	ErrInvalidLOCAlt     = errors.New("loc altitude isn't valid")
+)
+
+const (
+	locEquator      uint32 = 1 << 31
+	locAltBase      int64  = 10000000
+	locMaxLatitude  int64  = 90 * 3600000
+	locMaxLongitude int64  = 180 * 3600000
+
+	// default size and precisions in centimeter
+	locDefaultSize     = 100
+	locDefaultHorizPre = 1000000
+	locDefaultVertPre  = 1000
+)
+
+// RFC 1876, size and precisions are in the mantissa and exponent
+// form of centimeters, latitude and longitude are thousandths of
+// an arc second offset by 2^31, altitude is centimeters offset by
+// 100000m below the reference spheroid
+type LOC struct {
+	Version   uint8
+	Size      uint8
+	HorizPre  uint8
+	VertPre   uint8
+	Latitude  uint32
+	Longitude uint32
+	Altitude  uint32
+}
+
+func (l *LOC) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT8, l.Version, r)
+	rendField(RDF_C_UINT8, l.Size, r)
+	rendField(RDF_C_UINT8, l.HorizPre, r)
+	rendField(RDF_C_UINT8, l.VertPre, r)
+	rendField(RDF_C_UINT32, l.Latitude, r)
+	rendField(RDF_C_UINT32, l.Longitude, r)
+	rendField(RDF_C_UINT32, l.Altitude, r)
+}
+
+func (l *LOC) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT8, l.Version, buf)
+	fieldToWire(RDF_C_UINT8, l.Size, buf)
+	fieldToWire(RDF_C_UINT8, l.HorizPre, buf)
+	fieldToWire(RDF_C_UINT8, l.VertPre, buf)
+	fieldToWire(RDF_C_UINT32, l.Latitude, buf)
+	fieldToWire(RDF_C_UINT32, l.Longitude, buf)
+	fieldToWire(RDF_C_UINT32, l.Altitude, buf)
+}
+
+func (l *LOC) Compare(other Rdata) int {
+	otherLOC := other.(*LOC)
+	for _, pair := range [][2]uint8{
+		{l.Version, otherLOC.Version},
+		{l.Size, otherLOC.Size},
+		{l.HorizPre, otherLOC.HorizPre},
+		{l.VertPre, otherLOC.VertPre},
+	} {
+		if order := fieldCompare(RDF_C_UINT8, pair[0], pair[1]); order != 0 {
+			return order
+		}
+	}
+
+	for _, pair := range [][2]uint32{
+		{l.Latitude, otherLOC.Latitude},
+		{l.Longitude, otherLOC.Longitude},
+		{l.Altitude, otherLOC.Altitude},
+	} {
+		if order := fieldCompare(RDF_C_UINT32, pair[0], pair[1]); order != 0 {
+			return order
+		}
+	}
+	return 0
+}
+
+func (l *LOC) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(locDegreeToString(l.Latitude, "N", "S"))
+	buf.WriteString(" ")
+	buf.WriteString(locDegreeToString(l.Longitude, "E", "W"))
+	buf.WriteString(" ")
+	alt := int64(l.Altitude) - locAltBase
+	sign := ""
+	if alt < 0 {
+		sign = "-"
+		alt = -alt
+	}
+	buf.WriteString(fmt.Sprintf("%s%d.%02dm", sign, alt/100, alt%100))
+	for _, v := range []uint8{l.Size, l.HorizPre, l.VertPre} {
+		buf.WriteString(" ")
+		buf.WriteString(locSizeToString(v))
+	}
+	return buf.String()
+}
+
+// Meters returns the size, horizontal and vertical precision in meter
+func (l *LOC) Meters() (size, horizPre, vertPre float64) {
+	return float64(locSizeToCM(l.Size)) / 100,
+		float64(locSizeToCM(l.HorizPre)) / 100,
+		float64(locSizeToCM(l.VertPre)) / 100
+}
+
+func (l *LOC) validate() error {
+	if l.Version != 0 {
+		return ErrUnknownLOCVersion
+	}
+
+	for _, v := range []uint8{l.Size, l.HorizPre, l.VertPre} {
+		if v>>4 > 9 || v&0x0f > 9 {
+			return ErrInvalidLOCSize
+		}
+	}
+
+	if lat := int64(l.Latitude) - int64(locEquator); lat > locMaxLatitude || lat < -locMaxLatitude {
+		return ErrInvalidLOCLat
+	}
+
+	if lon := int64(l.Longitude) - int64(locEquator); lon > locMaxLongitude || lon < -locMaxLongitude {
+		return ErrInvalidLOCLon
+	}
+	return nil
+}
+
+func locSizeToCM(v uint8) uint64 {
+	cm := uint64(v >> 4)
+	for i := uint8(0); i < v&0x0f; i++ {
+		cm *= 10
+	}
+	return cm
+}
+
+func locSizeFromCM(cm uint64) uint8 {
+	var exp uint8
+	for cm >= 10 {
+		cm /= 10
+		exp += 1
+	}
+	return uint8(cm)<<4 | exp
+}
+
+func locSizeToString(v uint8) string {
+	cm := locSizeToCM(v)
+	if cm%100 == 0 {
+		return fmt.Sprintf("%dm", cm/100)
+	}
+	return fmt.Sprintf("%d.%02dm", cm/100, cm%100)
+}
+
+func locDegreeToString(v uint32, positive, negative string) string {
+	d := int64(v) - int64(locEquator)
+	hemisphere := positive
+	if d < 0 {
+		hemisphere = negative
+		d = -d
+	}
+	msec := d % 60000
+	d /= 60000
+	return fmt.Sprintf("%d %d %d.%03d %s", d/60, d%60, msec/1000, msec%1000, hemisphere)
+}
+
+// parse decimal with at most digits fraction digits, return it in
+// the unit of the last fraction digit
+func locDecimalFromString(s string, digits int) (int64, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	sign := int64(1)
+	if s[0] == '-' {
+		sign = -1
+		s = s[1:]
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	v, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	frac := ""
+	if len(parts) == 2 {
+		frac = parts[1]
+		if len(frac) == 0 || len(frac) > digits {
+			return 0, false
+		}
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+	f, err := strconv.ParseUint(frac, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return sign * (int64(v)*int64(math.Pow10(digits)) + int64(f)), true
+}
+
+func locSizeFromString(s string) (uint8, error) {
+	cm, ok := locDecimalFromString(strings.TrimSuffix(s, "m"), 2)
+	if ok == false || cm < 0 || cm > 9000000000 {
+		return 0, ErrInvalidLOCSize
+	}
+	return locSizeFromCM(uint64(cm)), nil
+}
+
+func locAltitudeFromString(s string) (uint32, error) {
+	cm, ok := locDecimalFromString(strings.TrimSuffix(s, "m"), 2)
+	if ok == false {
+		return 0, ErrInvalidLOCAlt
+	}
+	cm += locAltBase
+	if cm < 0 || cm > math.MaxUint32 {
+		return 0, ErrInvalidLOCAlt
+	}
+	return uint32(cm), nil
+}
+
+// parse "d [m [s.sss]] hemisphere" from the head of fields, return the
+// offset from the equator or the prime meridian and the fields left
+func locDegreeFromString(fields []string, positive, negative string, max int64, invalid error) (uint32, []string, error) {
+	var values [3]int64
+	i := 0
+	for ; i < len(fields) && i < 3; i++ {
+		if strings.EqualFold(fields[i], positive) || strings.EqualFold(fields[i], negative) {
+			break
+		}
+
+		if i < 2 {
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil || (i == 1 && v >= 60) {
+				return 0, nil, invalid
+			}
+			values[i] = int64(v)
+		} else {
+			msec, ok := locDecimalFromString(fields[i], 3)
+			if ok == false || msec < 0 || msec >= 60000 {
+				return 0, nil, invalid
+			}
+			values[i] = msec
+		}
+	}
+
+	if i == 0 || i == len(fields) {
+		return 0, nil, invalid
+	}
+
+	sign := int64(1)
+	if strings.EqualFold(fields[i], negative) {
+		sign = -1
+	} else if strings.EqualFold(fields[i], positive) == false {
+		return 0, nil, invalid
+	}
+
+	v := values[0]*3600000 + values[1]*60000 + values[2]
+	if v > max {
+		return 0, nil, invalid
+	}
+	return uint32(int64(locEquator) + sign*v), fields[i+1:], nil
+}
+
+func LOCFromWire(buf *util.InputBuffer, ll uint16) (*LOC, error) {
+	var loc LOC
+	var d interface{}
+	var err error
+	for _, v := range []*uint8{&loc.Version, &loc.Size, &loc.HorizPre, &loc.VertPre} {
+		if d, ll, err = fieldFromWire(RDF_C_UINT8, buf, ll); err != nil {
+			return nil, err
+		}
+		*v = d.(uint8)
+	}
+
+	for _, v := range []*uint32{&loc.Latitude, &loc.Longitude, &loc.Altitude} {
+		if d, ll, err = fieldFromWire(RDF_C_UINT32, buf, ll); err != nil {
+			return nil, err
+		}
+		*v = d.(uint32)
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	if err := loc.validate(); err != nil {
+		return nil, err
+	}
+	return &loc, nil
+}
+
+func LOCFromString(s string) (*LOC, error) {
+	fields := strings.Fields(s)
+	lat, fields, err := locDegreeFromString(fields, "N", "S", locMaxLatitude, ErrInvalidLOCLat)
+	if err != nil {
+		return nil, err
+	}
+
+	lon, fields, err := locDegreeFromString(fields, "E", "W", locMaxLongitude, ErrInvalidLOCLon)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 || len(fields) > 4 {
+		return nil, errors.New("loc altitude is missing or too many fields")
+	}
+
+	alt, err := locAltitudeFromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := []uint8{
+		locSizeFromCM(locDefaultSize),
+		locSizeFromCM(locDefaultHorizPre),
+		locSizeFromCM(locDefaultVertPre),
+	}
+	for i, f := range fields[1:] {
+		if sizes[i], err = locSizeFromString(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return &LOC{
+		Size:      sizes[0],
+		HorizPre:  sizes[1],
+		VertPre:   sizes[2],
+		Latitude:  lat,
+		Longitude: lon,
+		Altitude:  alt,
+	}, nil
+}
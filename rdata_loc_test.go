@@ -0,0 +1,70 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestLOCFromToString(t *testing.T) {
+	cases := []struct {
+		s      string
+		expect string
+		wire   string
+	}{
+		// example from RFC 1876
+		{"42 21 54 N 71 06 18 W -24m 30m", "42 21 54.000 N 71 6 18.000 W -24.00m 30m 10000m 10m",
+			"00331613" + "89172dd0" + "70be15f0" + "00988d20"},
+		{"33 52 s 151 12 59.123 e 12.34 0.5m 1000 3m",
+			"33 52 0.000 S 151 12 59.123 E 12.34m 0.50m 1000m 3m",
+			"00511532" + "78bba600" + "a07290f3" + "00989b52"},
+		{"90 N 180 W 0m", "90 0 0.000 N 180 0 0.000 W 0.00m 1m 10000m 10m", ""},
+		{"0 S 0 E -100000m 90000000m 0m 0m", "0 0 0.000 N 0 0 0.000 E -100000.00m 90000000m 0m 0m", ""},
+	}
+
+	for _, c := range cases {
+		loc := rdataRoundTrip(t, RR_LOC, c.s, c.expect)
+		if c.wire != "" {
+			buf := util.NewOutputBuffer(16)
+			loc.ToWire(buf)
+			wire, _ := util.HexStrToBytes(c.wire)
+			WireMatch(t, wire, buf.Data())
+		}
+	}
+
+	size, horizPre, vertPre := rdataRoundTrip(t, RR_LOC, "42 21 54 N 71 06 18 W -24m 30m",
+		"42 21 54.000 N 71 6 18.000 W -24.00m 30m 10000m 10m").(*LOC).Meters()
+	Equal(t, size, 30.0)
+	Equal(t, horizPre, 10000.0)
+	Equal(t, vertPre, 10.0)
+
+	for _, s := range []string{
+		"91 N 71 06 18 W -24m",
+		"42 60 N 71 06 18 W -24m",
+		"42 21 60 N 71 06 18 W -24m",
+		"42 21 54.1234 N 71 06 18 W -24m",
+		"42 21 54 E 71 06 18 W -24m",
+		"42 21 54 N 181 W -24m",
+		"42 21 54 N 71 06 18 N -24m",
+		"42 21 54 N 71 06 18 W",
+		"42 21 54 N 71 06 18 W -100000.01m",
+		"42 21 54 N 71 06 18 W 42849673m",
+		"42 21 54 N 71 06 18 W 0m 90000001m",
+		"42 21 54 N 71 06 18 W 0m 1m 1m 1m 1m",
+		"42 21 54 N 71 06 18 W 0m -1m",
+	} {
+		_, err := LOCFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+
+	for _, s := range []string{
+		"0100161389172dd070be15f000988d20",
+		"00a0161389172dd070be15f000988d20",
+		"00331613934fd90170be15f000988d20",
+		"0033161389172dd070be15f000988d2000",
+	} {
+		wire, _ := util.HexStrToBytes(s)
+		_, err := LOCFromWire(util.NewInputBuffer(wire), uint16(len(wire)))
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
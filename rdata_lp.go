@@ -0,0 +1,55 @@
+package g53
+
+import (
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// RFC 6742
+type LP struct {
+	Preference uint16
+	FQDN       *Name
+}
+
+func (l *LP) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, l.Preference, r)
+	rendField(RDF_C_NAME_UNCOMPRESS, l.FQDN, r)
+}
+
+func (l *LP) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, l.Preference, buf)
+	fieldToWire(RDF_C_NAME_UNCOMPRESS, l.FQDN, buf)
+}
+
+func (l *LP) Compare(other Rdata) int {
+	otherLP := other.(*LP)
+	order := fieldCompare(RDF_C_UINT16, l.Preference, otherLP.Preference)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_NAME_UNCOMPRESS, l.FQDN, otherLP.FQDN)
+}
+
+func (l *LP) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, l.Preference),
+		fieldToString(RDF_D_NAME, l.FQDN)}, " ")
+}
+
+func LPFromWire(buf *util.InputBuffer, ll uint16) (*LP, error) {
+	d, n, err := uint16NameFromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &LP{d, n}, nil
+}
+
+func LPFromString(s string) (*LP, error) {
+	d, n, err := uint16NameFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &LP{d, n}, nil
+}
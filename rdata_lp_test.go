@@ -0,0 +1,33 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestLPFromToString(t *testing.T) {
+	lp := rdataRoundTrip(t, RR_LP, "10 l64-subnet1.example.com.", "10 l64-subnet1.example.com.").(*LP)
+	Equal(t, lp.Preference, uint16(10))
+	NameEqToStr(t, lp.FQDN, "l64-subnet1.example.com.")
+	rdataRoundTrip(t, RR_LP, "65535 L64-Subnet1.Example.com.", "65535 l64-subnet1.example.com.")
+
+	//fqdn isn't compressed even if it has been rendered
+	render := NewMsgRender()
+	lp.FQDN.Rend(render)
+	pos := render.Len()
+	lp.Rend(render)
+	wire, _ := util.HexStrToBytes("000a" + "0b6c36342d7375626e657431" + "076578616d706c6503636f6d00")
+	WireMatch(t, wire, render.Data()[pos:])
+
+	for _, s := range []string{
+		"65536 l64-subnet1.example.com.",
+		"-1 l64-subnet1.example.com.",
+		"l64-subnet1.example.com.",
+		"10",
+		"10 l64-subnet1.example.com. extra",
+	} {
+		_, err := LPFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
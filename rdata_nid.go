@@ -0,0 +1,250 @@
+package g53
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrInvalidILNPLocator = errors.New("ilnp 64 bit value should be four groups of 4 hex digits")
+
+// RFC 6742, NID and L64 share the same format
+type NID struct {
+	Preference uint16
+	NodeID     uint64
+}
+
+type L64 struct {
+	Preference uint16
+	Locator64  uint64
+}
+
+// RFC 6742
+type L32 struct {
+	Preference uint16
+	Locator32  net.IP
+}
+
+func ilnp64ToWire(v uint64) []byte {
+	return []byte{byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
+		byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func ilnp64ToString(v uint64) string {
+	return fmt.Sprintf("%04x:%04x:%04x:%04x", v>>48, (v>>32)&0xffff, (v>>16)&0xffff, v&0xffff)
+}
+
+func ilnp64FromString(s string) (uint64, error) {
+	groups := strings.Split(s, ":")
+	if len(groups) != 4 {
+		return 0, ErrInvalidILNPLocator
+	}
+
+	var v uint64
+	for _, g := range groups {
+		if len(g) != 4 {
+			return 0, ErrInvalidILNPLocator
+		}
+		d, err := strconv.ParseUint(g, 16, 16)
+		if err != nil {
+			return 0, ErrInvalidILNPLocator
+		}
+		v = v<<16 | d
+	}
+	return v, nil
+}
+
+func ilnp64FromWire(buf *util.InputBuffer, ll uint16) (uint16, uint64, error) {
+	p, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	high, ll, err := fieldFromWire(RDF_C_UINT32, buf, ll)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	low, ll, err := fieldFromWire(RDF_C_UINT32, buf, ll)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if ll != 0 {
+		return 0, 0, errors.New("extra data in rdata part")
+	}
+	return p.(uint16), uint64(high.(uint32))<<32 | uint64(low.(uint32)), nil
+}
+
+var ilnpRdataTemplate = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s*$`)
+
+func ilnp64FromRdataString(s string) (uint16, uint64, error) {
+	fields := ilnpRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 3 {
+		return 0, 0, errors.New("fields count isn't 2")
+	}
+
+	p, err := uint16FromString(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	v, err := ilnp64FromString(fields[2])
+	if err != nil {
+		return 0, 0, err
+	}
+	return p, v, nil
+}
+
+func (n *NID) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, n.Preference, r)
+	rendField(RDF_C_BINARY, ilnp64ToWire(n.NodeID), r)
+}
+
+func (n *NID) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, n.Preference, buf)
+	fieldToWire(RDF_C_BINARY, ilnp64ToWire(n.NodeID), buf)
+}
+
+func (n *NID) Compare(other Rdata) int {
+	otherNID := other.(*NID)
+	order := fieldCompare(RDF_C_UINT16, n.Preference, otherNID.Preference)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, ilnp64ToWire(n.NodeID), ilnp64ToWire(otherNID.NodeID))
+}
+
+func (n *NID) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, n.Preference),
+		ilnp64ToString(n.NodeID)}, " ")
+}
+
+func NIDFromWire(buf *util.InputBuffer, ll uint16) (*NID, error) {
+	p, v, err := ilnp64FromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &NID{p, v}, nil
+}
+
+func NIDFromString(s string) (*NID, error) {
+	p, v, err := ilnp64FromRdataString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &NID{p, v}, nil
+}
+
+func (l *L64) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, l.Preference, r)
+	rendField(RDF_C_BINARY, ilnp64ToWire(l.Locator64), r)
+}
+
+func (l *L64) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, l.Preference, buf)
+	fieldToWire(RDF_C_BINARY, ilnp64ToWire(l.Locator64), buf)
+}
+
+func (l *L64) Compare(other Rdata) int {
+	otherL64 := other.(*L64)
+	order := fieldCompare(RDF_C_UINT16, l.Preference, otherL64.Preference)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, ilnp64ToWire(l.Locator64), ilnp64ToWire(otherL64.Locator64))
+}
+
+func (l *L64) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, l.Preference),
+		ilnp64ToString(l.Locator64)}, " ")
+}
+
+func L64FromWire(buf *util.InputBuffer, ll uint16) (*L64, error) {
+	p, v, err := ilnp64FromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &L64{p, v}, nil
+}
+
+func L64FromString(s string) (*L64, error) {
+	p, v, err := ilnp64FromRdataString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &L64{p, v}, nil
+}
+
+func (l *L32) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, l.Preference, r)
+	rendField(RDF_C_IPV4, l.Locator32, r)
+}
+
+func (l *L32) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, l.Preference, buf)
+	fieldToWire(RDF_C_IPV4, l.Locator32, buf)
+}
+
+func (l *L32) Compare(other Rdata) int {
+	otherL32 := other.(*L32)
+	order := fieldCompare(RDF_C_UINT16, l.Preference, otherL32.Preference)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_IPV4, l.Locator32, otherL32.Locator32)
+}
+
+func (l *L32) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, l.Preference),
+		fieldToString(RDF_D_IP, l.Locator32)}, " ")
+}
+
+func L32FromWire(buf *util.InputBuffer, ll uint16) (*L32, error) {
+	p, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, ll, err := fieldFromWire(RDF_C_IPV4, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+	return &L32{p.(uint16), ip.(net.IP)}, nil
+}
+
+func L32FromString(s string) (*L32, error) {
+	fields := ilnpRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 3 {
+		return nil, errors.New("fields count for l32 isn't 2")
+	}
+
+	p, err := uint16FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := fieldFromString(RDF_D_IP, fields[2])
+	if err != nil {
+		return nil, err
+	}
+	if ip.(net.IP).To4() == nil {
+		return nil, ErrInvalidIPAddr
+	}
+	return &L32{p, ip.(net.IP).To4()}, nil
+}
@@ -0,0 +1,36 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestILNPFromToString(t *testing.T) {
+	nid := rdataRoundTrip(t, RR_NID, "10 0014:4fff:ff20:ee64", "10 0014:4fff:ff20:ee64").(*NID)
+	Equal(t, nid.NodeID, uint64(0x00144fffff20ee64))
+	l64 := rdataRoundTrip(t, RR_L64, "10 2001:0DB8:1140:1000", "10 2001:0db8:1140:1000").(*L64)
+	Equal(t, l64.Locator64, uint64(0x20010db811401000))
+	rdataRoundTrip(t, RR_L32, "10 10.1.2.0", "10 10.1.2.0")
+
+	for _, s := range []string{
+		"10 0014:4fff:ff20",
+		"10 0014:4fff:ff20:ee64:0000",
+		"10 14:4fff:ff20:ee64",
+		"10 0014:4fff:ff20:ee6g",
+		"65536 0014:4fff:ff20:ee64",
+		"10",
+	} {
+		_, err := NIDFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+		_, err = L64FromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+
+	for _, s := range []string{
+		"10 2001:db8::1",
+		"10 10.1.2",
+		"10",
+	} {
+		_, err := L32FromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -0,0 +1,52 @@
+package g53
+
+import (
+	"errors"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrEmptyOpenPGPKey = errors.New("openpgp key is empty")
+
+// RFC 7929
+type OPENPGPKEY struct {
+	PublicKey []byte
+}
+
+func (o *OPENPGPKEY) Rend(r *MsgRender) {
+	rendField(RDF_C_BINARY, o.PublicKey, r)
+}
+
+func (o *OPENPGPKEY) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_BINARY, o.PublicKey, buf)
+}
+
+func (o *OPENPGPKEY) Compare(other Rdata) int {
+	return fieldCompare(RDF_C_BINARY, o.PublicKey, other.(*OPENPGPKEY).PublicKey)
+}
+
+func (o *OPENPGPKEY) String() string {
+	return fieldToString(RDF_D_B64, o.PublicKey)
+}
+
+func OPENPGPKEYFromWire(buf *util.InputBuffer, ll uint16) (*OPENPGPKEY, error) {
+	if ll == 0 {
+		return nil, ErrEmptyOpenPGPKey
+	}
+
+	d, _, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &OPENPGPKEY{d.([]uint8)}, nil
+}
+
+func OPENPGPKEYFromString(s string) (*OPENPGPKEY, error) {
+	d, err := base64FromString(s)
+	if err != nil {
+		return nil, err
+	} else if len(d) == 0 {
+		return nil, ErrEmptyOpenPGPKey
+	}
+	return &OPENPGPKEY{d}, nil
+}
@@ -0,0 +1,55 @@
+package g53
+
+import (
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// RFC 1183
+type RT struct {
+	Preference uint16
+	Host       *Name
+}
+
+func (rt *RT) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, rt.Preference, r)
+	rendField(RDF_C_NAME_UNCOMPRESS, rt.Host, r)
+}
+
+func (rt *RT) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, rt.Preference, buf)
+	fieldToWire(RDF_C_NAME_UNCOMPRESS, rt.Host, buf)
+}
+
+func (rt *RT) Compare(other Rdata) int {
+	otherRT := other.(*RT)
+	order := fieldCompare(RDF_C_UINT16, rt.Preference, otherRT.Preference)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_NAME_UNCOMPRESS, rt.Host, otherRT.Host)
+}
+
+func (rt *RT) String() string {
+	return strings.Join([]string{
+		fieldToString(RDF_D_INT, rt.Preference),
+		fieldToString(RDF_D_NAME, rt.Host)}, " ")
+}
+
+func RTFromWire(buf *util.InputBuffer, ll uint16) (*RT, error) {
+	d, n, err := uint16NameFromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return &RT{d, n}, nil
+}
+
+func RTFromString(s string) (*RT, error) {
+	d, n, err := uint16NameFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &RT{d, n}, nil
+}
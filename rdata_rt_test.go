@@ -0,0 +1,33 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+func TestRTFromToString(t *testing.T) {
+	rt := rdataRoundTrip(t, RR_RT, "10 relay.example.com.", "10 relay.example.com.").(*RT)
+	Equal(t, rt.Preference, uint16(10))
+	NameEqToStr(t, rt.Host, "relay.example.com.")
+	rdataRoundTrip(t, RR_RT, "65535 Relay.Example.com.", "65535 relay.example.com.")
+
+	//host isn't compressed even if it has been rendered
+	render := NewMsgRender()
+	rt.Host.Rend(render)
+	pos := render.Len()
+	rt.Rend(render)
+	wire, _ := util.HexStrToBytes("000a" + "0572656c6179" + "076578616d706c6503636f6d00")
+	WireMatch(t, wire, render.Data()[pos:])
+
+	for _, s := range []string{
+		"65536 relay.example.com.",
+		"-1 relay.example.com.",
+		"relay.example.com.",
+		"10",
+		"10 relay.example.com. extra",
+	} {
+		_, err := RTFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
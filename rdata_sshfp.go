@@ -0,0 +1,158 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrUnknownSSHFPAlgorithm = errors.New("unknown sshfp algorithm")
+	ErrUnknownSSHFPType      = errors.New("unknown sshfp fingerprint type")
+	ErrSSHFPFingerprintLen   = errors.New("sshfp fingerprint length doesn't match its type")
+)
+
+const (
+	SSHFP_ALG_RSA     uint8 = 1
+	SSHFP_ALG_DSA     uint8 = 2
+	SSHFP_ALG_ECDSA   uint8 = 3
+	SSHFP_ALG_ED25519 uint8 = 4
+	SSHFP_ALG_ED448   uint8 = 6
+
+	SSHFP_TYPE_SHA1   uint8 = 1
+	SSHFP_TYPE_SHA256 uint8 = 2
+)
+
+// RFC 4255, RFC 6594, RFC 7479, RFC 8709
+type SSHFP struct {
+	Algorithm   uint8
+	FpType      uint8
+	Fingerprint []byte
+}
+
+func (s *SSHFP) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT8, s.Algorithm, r)
+	rendField(RDF_C_UINT8, s.FpType, r)
+	rendField(RDF_C_BINARY, s.Fingerprint, r)
+}
+
+func (s *SSHFP) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT8, s.Algorithm, buf)
+	fieldToWire(RDF_C_UINT8, s.FpType, buf)
+	fieldToWire(RDF_C_BINARY, s.Fingerprint, buf)
+}
+
+func (s *SSHFP) Compare(other Rdata) int {
+	otherSSHFP := other.(*SSHFP)
+	order := fieldCompare(RDF_C_UINT8, s.Algorithm, otherSSHFP.Algorithm)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, s.FpType, otherSSHFP.FpType)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, s.Fingerprint, otherSSHFP.Fingerprint)
+}
+
+func (s *SSHFP) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, s.Algorithm))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, s.FpType))
+	buf.WriteString(" ")
+	buf.WriteString(hexToString(s.Fingerprint))
+	return buf.String()
+}
+
+func (s *SSHFP) validate() error {
+	switch s.Algorithm {
+	case SSHFP_ALG_RSA, SSHFP_ALG_DSA, SSHFP_ALG_ECDSA, SSHFP_ALG_ED25519, SSHFP_ALG_ED448:
+	default:
+		return ErrUnknownSSHFPAlgorithm
+	}
+
+	switch s.FpType {
+	case SSHFP_TYPE_SHA1:
+		if len(s.Fingerprint) != 20 {
+			return ErrSSHFPFingerprintLen
+		}
+	case SSHFP_TYPE_SHA256:
+		if len(s.Fingerprint) != 32 {
+			return ErrSSHFPFingerprintLen
+		}
+	default:
+		return ErrUnknownSSHFPType
+	}
+	return nil
+}
+
+func SSHFPFromWire(buf *util.InputBuffer, ll uint16) (*SSHFP, error) {
+	a, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ll, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	sshfp := &SSHFP{
+		Algorithm:   a.(uint8),
+		FpType:      t.(uint8),
+		Fingerprint: f.([]uint8),
+	}
+	if err := sshfp.validate(); err != nil {
+		return nil, err
+	}
+	return sshfp, nil
+}
+
+var sshfpRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s+(.*?)\s*$`)
+
+func SSHFPFromString(s string) (*SSHFP, error) {
+	fields := sshfpRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 4 {
+		return nil, errors.New("short of fields for sshfp")
+	}
+	fields = fields[1:]
+
+	algorithm, err := uint8FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	typ, err := uint8FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := hexFromString(fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	sshfp := &SSHFP{
+		Algorithm:   algorithm,
+		FpType:      typ,
+		Fingerprint: fingerprint,
+	}
+	if err := sshfp.validate(); err != nil {
+		return nil, err
+	}
+	return sshfp, nil
+}
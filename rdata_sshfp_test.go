@@ -0,0 +1,22 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestSSHFPFromToString(t *testing.T) {
+	rdataRoundTrip(t, RR_SSHFP, "2 1 123456789abcdef67890123456789abcdef67890",
+		"2 1 123456789ABCDEF67890123456789ABCDEF67890")
+	rdataRoundTrip(t, RR_SSHFP, "4 2 a87f1b687ac0e57d2a081a2f28267237 34d90ed316d2b818ca9580ea384d9240",
+		"4 2 A87F1B687AC0E57D2A081A2F2826723734D90ED316D2B818CA9580EA384D9240")
+
+	for _, s := range []string{
+		"5 1 123456789abcdef67890123456789abcdef67890",
+		"2 3 123456789abcdef67890123456789abcdef67890",
+		"2 2 123456789abcdef67890123456789abcdef67890",
+		"2 1 123456789abcdef67890123456789abcdef6789g",
+	} {
+		_, err := SSHFPFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -0,0 +1,695 @@
+package g53
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrUnknownSvcParamKey       = errors.New("unknown svc param key")
+	ErrInvalidSvcParamValue     = errors.New("svc param value isn't valid")
+	ErrSvcParamKeyNotInOrder    = errors.New("svc param keys aren't in increasing order")
+	ErrDuplicateSvcParamKey     = errors.New("duplicate svc param key")
+	ErrSvcParamInAliasMode      = errors.New("svc params aren't allowed in alias mode")
+	ErrMandatoryKeyMissing      = errors.New("mandatory svc param key is missing")
+	ErrNoDefaultALPNWithoutALPN = errors.New("no-default-alpn is specified without alpn")
+)
+
+type SvcParamKey uint16
+
+const (
+	SVC_KEY_MANDATORY       SvcParamKey = 0
+	SVC_KEY_ALPN            SvcParamKey = 1
+	SVC_KEY_NO_DEFAULT_ALPN SvcParamKey = 2
+	SVC_KEY_PORT            SvcParamKey = 3
+	SVC_KEY_IPV4HINT        SvcParamKey = 4
+	SVC_KEY_ECH             SvcParamKey = 5
+	SVC_KEY_IPV6HINT        SvcParamKey = 6
+	SVC_KEY_INVALID         SvcParamKey = 65535
+)
+
+var svcParamKeyNameMap = map[SvcParamKey]string{
+	SVC_KEY_MANDATORY:       "mandatory",
+	SVC_KEY_ALPN:            "alpn",
+	SVC_KEY_NO_DEFAULT_ALPN: "no-default-alpn",
+	SVC_KEY_PORT:            "port",
+	SVC_KEY_IPV4HINT:        "ipv4hint",
+	SVC_KEY_ECH:             "ech",
+	SVC_KEY_IPV6HINT:        "ipv6hint",
+}
+
+func (k SvcParamKey) String() string {
+	if name, ok := svcParamKeyNameMap[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("key%d", k)
+}
+
+func SvcParamKeyFromString(s string) (SvcParamKey, error) {
+	for k, name := range svcParamKeyNameMap {
+		if name == s {
+			return k, nil
+		}
+	}
+
+	if strings.HasPrefix(s, "key") {
+		d, err := strconv.ParseUint(s[3:], 10, 16)
+		if err == nil && SvcParamKey(d) != SVC_KEY_INVALID {
+			return SvcParamKey(d), nil
+		}
+	}
+	return SVC_KEY_INVALID, ErrUnknownSvcParamKey
+}
+
+// SvcParam is a key value pair, Value returns the wire format of the value
+// and String returns the presentation format of the value
+type SvcParam interface {
+	Key() SvcParamKey
+	Value() []byte
+	String() string
+}
+
+type SvcMandatory struct {
+	Keys []SvcParamKey
+}
+
+type SvcALPN struct {
+	IDs []string
+}
+
+type SvcNoDefaultALPN struct{}
+
+type SvcPort struct {
+	Port uint16
+}
+
+type SvcIPv4Hint struct {
+	Hints []net.IP
+}
+
+type SvcECH struct {
+	Config []byte
+}
+
+type SvcIPv6Hint struct {
+	Hints []net.IP
+}
+
+type SvcUnknown struct {
+	Code SvcParamKey
+	Data []byte
+}
+
+func (p *SvcMandatory) Key() SvcParamKey { return SVC_KEY_MANDATORY }
+
+func (p *SvcMandatory) Value() []byte {
+	buf := util.NewOutputBuffer(uint(2 * len(p.Keys)))
+	for _, k := range p.Keys {
+		buf.WriteUint16(uint16(k))
+	}
+	return buf.Data()
+}
+
+func (p *SvcMandatory) String() string {
+	keys := make([]string, 0, len(p.Keys))
+	for _, k := range p.Keys {
+		keys = append(keys, k.String())
+	}
+	return strings.Join(keys, ",")
+}
+
+func (p *SvcALPN) Key() SvcParamKey { return SVC_KEY_ALPN }
+
+func (p *SvcALPN) Value() []byte {
+	buf := util.NewOutputBuffer(32)
+	for _, id := range p.IDs {
+		fieldToWire(RDF_C_BYTE_BINARY, []byte(id), buf)
+	}
+	return buf.Data()
+}
+
+// comma and backslash in alpn id are escaped, then the whole list is
+// escaped again as character string, RFC 9460 appendix A.1
+func (p *SvcALPN) String() string {
+	ids := make([]string, 0, len(p.IDs))
+	for _, id := range p.IDs {
+		id = strings.Replace(id, "\\", "\\\\", -1)
+		ids = append(ids, strings.Replace(id, ",", "\\,", -1))
+	}
+	return quoteCharString(strings.Join(ids, ","))
+}
+
+func (p *SvcNoDefaultALPN) Key() SvcParamKey { return SVC_KEY_NO_DEFAULT_ALPN }
+func (p *SvcNoDefaultALPN) Value() []byte    { return nil }
+func (p *SvcNoDefaultALPN) String() string   { return "" }
+
+func (p *SvcPort) Key() SvcParamKey { return SVC_KEY_PORT }
+func (p *SvcPort) Value() []byte    { return []byte{byte(p.Port >> 8), byte(p.Port)} }
+func (p *SvcPort) String() string   { return strconv.Itoa(int(p.Port)) }
+
+func (p *SvcIPv4Hint) Key() SvcParamKey { return SVC_KEY_IPV4HINT }
+func (p *SvcIPv4Hint) Value() []byte    { return ipsToWire(p.Hints, net.IPv4len) }
+func (p *SvcIPv4Hint) String() string   { return ipsToString(p.Hints) }
+
+func (p *SvcECH) Key() SvcParamKey { return SVC_KEY_ECH }
+func (p *SvcECH) Value() []byte    { return p.Config }
+func (p *SvcECH) String() string   { return base64.StdEncoding.EncodeToString(p.Config) }
+
+func (p *SvcIPv6Hint) Key() SvcParamKey { return SVC_KEY_IPV6HINT }
+func (p *SvcIPv6Hint) Value() []byte    { return ipsToWire(p.Hints, net.IPv6len) }
+func (p *SvcIPv6Hint) String() string   { return ipsToString(p.Hints) }
+
+func (p *SvcUnknown) Key() SvcParamKey { return p.Code }
+func (p *SvcUnknown) Value() []byte    { return p.Data }
+
+func (p *SvcUnknown) String() string {
+	if len(p.Data) == 0 {
+		return ""
+	}
+	return quoteCharString(string(p.Data))
+}
+
+func ipsToWire(ips []net.IP, l int) []byte {
+	var d []byte
+	for _, ip := range ips {
+		if l == net.IPv4len {
+			d = append(d, ip.To4()...)
+		} else {
+			d = append(d, ip.To16()...)
+		}
+	}
+	return d
+}
+
+func ipsToString(ips []net.IP) string {
+	ss := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ss = append(ss, ip.String())
+	}
+	return strings.Join(ss, ",")
+}
+
+func svcParamFromWire(key SvcParamKey, d []byte) (SvcParam, error) {
+	switch key {
+	case SVC_KEY_MANDATORY:
+		if len(d) == 0 || len(d)%2 != 0 {
+			return nil, ErrInvalidSvcParamValue
+		}
+		p := &SvcMandatory{}
+		for i := 0; i < len(d); i += 2 {
+			k := SvcParamKey(uint16(d[i])<<8 | uint16(d[i+1]))
+			if k == SVC_KEY_MANDATORY {
+				return nil, ErrInvalidSvcParamValue
+			}
+			if len(p.Keys) > 0 && k <= p.Keys[len(p.Keys)-1] {
+				return nil, ErrSvcParamKeyNotInOrder
+			}
+			p.Keys = append(p.Keys, k)
+		}
+		return p, nil
+
+	case SVC_KEY_ALPN:
+		p := &SvcALPN{}
+		buf := util.NewInputBuffer(d)
+		ll := uint16(len(d))
+		for ll > 0 {
+			id, l, err := fieldFromWire(RDF_C_BYTE_BINARY, buf, ll)
+			if err != nil {
+				return nil, err
+			}
+			if len(id.([]uint8)) == 0 {
+				return nil, ErrInvalidSvcParamValue
+			}
+			p.IDs = append(p.IDs, string(id.([]uint8)))
+			ll = l
+		}
+		if len(p.IDs) == 0 {
+			return nil, ErrInvalidSvcParamValue
+		}
+		return p, nil
+
+	case SVC_KEY_NO_DEFAULT_ALPN:
+		if len(d) != 0 {
+			return nil, ErrInvalidSvcParamValue
+		}
+		return &SvcNoDefaultALPN{}, nil
+
+	case SVC_KEY_PORT:
+		if len(d) != 2 {
+			return nil, ErrInvalidSvcParamValue
+		}
+		return &SvcPort{Port: uint16(d[0])<<8 | uint16(d[1])}, nil
+
+	case SVC_KEY_IPV4HINT, SVC_KEY_IPV6HINT:
+		l := net.IPv4len
+		if key == SVC_KEY_IPV6HINT {
+			l = net.IPv6len
+		}
+		if len(d) == 0 || len(d)%l != 0 {
+			return nil, ErrInvalidSvcParamValue
+		}
+		var hints []net.IP
+		for i := 0; i < len(d); i += l {
+			ip := make(net.IP, l)
+			copy(ip, d[i:i+l])
+			hints = append(hints, ip)
+		}
+		if key == SVC_KEY_IPV4HINT {
+			return &SvcIPv4Hint{Hints: hints}, nil
+		} else {
+			return &SvcIPv6Hint{Hints: hints}, nil
+		}
+
+	case SVC_KEY_ECH:
+		if len(d) == 0 {
+			return nil, ErrInvalidSvcParamValue
+		}
+		return &SvcECH{Config: d}, nil
+
+	case SVC_KEY_INVALID:
+		return nil, ErrUnknownSvcParamKey
+
+	default:
+		return &SvcUnknown{Code: key, Data: d}, nil
+	}
+}
+
+// split value list by unescaped comma, RFC 9460 appendix A.1
+func splitSvcValueList(s string) []string {
+	var items []string
+	var item bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			item.WriteByte(s[i+1])
+			i += 1
+		} else if c == ',' {
+			items = append(items, item.String())
+			item.Reset()
+		} else {
+			item.WriteByte(c)
+		}
+	}
+	return append(items, item.String())
+}
+
+func svcParamFromString(key SvcParamKey, s string, hasValue bool) (SvcParam, error) {
+	if key == SVC_KEY_NO_DEFAULT_ALPN {
+		if hasValue {
+			return nil, ErrInvalidSvcParamValue
+		}
+		return &SvcNoDefaultALPN{}, nil
+	}
+
+	if hasValue == false {
+		if _, ok := svcParamKeyNameMap[key]; ok {
+			return nil, ErrInvalidSvcParamValue
+		}
+		return &SvcUnknown{Code: key}, nil
+	}
+
+	var p SvcParam
+	switch key {
+	case SVC_KEY_MANDATORY:
+		mandatory := &SvcMandatory{}
+		for _, name := range strings.Split(s, ",") {
+			k, err := SvcParamKeyFromString(name)
+			if err != nil {
+				return nil, err
+			}
+			mandatory.Keys = append(mandatory.Keys, k)
+		}
+		sort.Slice(mandatory.Keys, func(i, j int) bool { return mandatory.Keys[i] < mandatory.Keys[j] })
+		p = mandatory
+
+	case SVC_KEY_ALPN:
+		p = &SvcALPN{IDs: splitSvcValueList(s)}
+
+	case SVC_KEY_PORT:
+		port, err := uint16FromString(s)
+		if err != nil {
+			return nil, err
+		}
+		p = &SvcPort{Port: port}
+
+	case SVC_KEY_IPV4HINT, SVC_KEY_IPV6HINT:
+		var hints []net.IP
+		for _, addr := range strings.Split(s, ",") {
+			ip := net.ParseIP(addr)
+			if ip == nil || (ip.To4() != nil) != (key == SVC_KEY_IPV4HINT) {
+				return nil, ErrInvalidIPAddr
+			}
+			hints = append(hints, ip)
+		}
+		if key == SVC_KEY_IPV4HINT {
+			p = &SvcIPv4Hint{Hints: hints}
+		} else {
+			p = &SvcIPv6Hint{Hints: hints}
+		}
+
+	case SVC_KEY_ECH:
+		config, err := base64FromString(s)
+		if err != nil {
+			return nil, err
+		}
+		p = &SvcECH{Config: config}
+
+	default:
+		p = &SvcUnknown{Code: key, Data: []byte(s)}
+	}
+
+	// share the validation of wire format
+	return svcParamFromWire(p.Key(), p.Value())
+}
+
+// RFC 9460, priority 0 means alias mode
+type SVCB struct {
+	Priority uint16
+	Target   *Name
+	Params   []SvcParam
+}
+
+// HTTPS has the same format as SVCB
+type HTTPS SVCB
+
+func (s *SVCB) IsAliasMode() bool {
+	return s.Priority == 0
+}
+
+func (s *SVCB) GetParam(key SvcParamKey) SvcParam {
+	for _, p := range s.Params {
+		if p.Key() == key {
+			return p
+		}
+	}
+	return nil
+}
+
+// params should be in increasing order of key on wire
+func (s *SVCB) sortedParams() []SvcParam {
+	params := make([]SvcParam, len(s.Params))
+	copy(params, s.Params)
+	sort.SliceStable(params, func(i, j int) bool { return params[i].Key() < params[j].Key() })
+	return params
+}
+
+func (s *SVCB) paramsToWire() []byte {
+	buf := util.NewOutputBuffer(64)
+	for _, p := range s.sortedParams() {
+		v := p.Value()
+		buf.WriteUint16(uint16(p.Key()))
+		buf.WriteUint16(uint16(len(v)))
+		buf.WriteData(v)
+	}
+	return buf.Data()
+}
+
+func (s *SVCB) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, s.Priority, r)
+	rendField(RDF_C_NAME_UNCOMPRESS, s.Target, r)
+	rendField(RDF_C_BINARY, s.paramsToWire(), r)
+}
+
+func (s *SVCB) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, s.Priority, buf)
+	fieldToWire(RDF_C_NAME_UNCOMPRESS, s.Target, buf)
+	fieldToWire(RDF_C_BINARY, s.paramsToWire(), buf)
+}
+
+func (s *SVCB) Compare(other Rdata) int {
+	return s.compare(other.(*SVCB))
+}
+
+func (s *SVCB) compare(other *SVCB) int {
+	order := fieldCompare(RDF_C_UINT16, s.Priority, other.Priority)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_NAME_UNCOMPRESS, s.Target, other.Target)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, s.paramsToWire(), other.paramsToWire())
+}
+
+func (s *SVCB) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, s.Priority))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_NAME, s.Target))
+	for _, p := range s.sortedParams() {
+		buf.WriteString(" ")
+		buf.WriteString(p.Key().String())
+		if v := p.String(); v != "" {
+			buf.WriteString("=")
+			buf.WriteString(v)
+		}
+	}
+	return buf.String()
+}
+
+// Validate check the params, RFC 9460 section 2.4 and section 8, params
+// in alias mode is only rejected when record is built, from wire they are
+// dropped
+func (s *SVCB) Validate() error {
+	params := s.sortedParams()
+	for i := 1; i < len(params); i++ {
+		if params[i].Key() == params[i-1].Key() {
+			return ErrDuplicateSvcParamKey
+		}
+	}
+
+	if s.IsAliasMode() {
+		if len(params) != 0 {
+			return ErrSvcParamInAliasMode
+		}
+		return nil
+	}
+
+	if p := s.GetParam(SVC_KEY_MANDATORY); p != nil {
+		for _, k := range p.(*SvcMandatory).Keys {
+			if k == SVC_KEY_MANDATORY || s.GetParam(k) == nil {
+				return ErrMandatoryKeyMissing
+			}
+		}
+	}
+
+	if s.GetParam(SVC_KEY_NO_DEFAULT_ALPN) != nil && s.GetParam(SVC_KEY_ALPN) == nil {
+		return ErrNoDefaultALPNWithoutALPN
+	}
+	return nil
+}
+
+func SVCBFromWire(buf *util.InputBuffer, ll uint16) (*SVCB, error) {
+	p, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ll, err := fieldFromWire(RDF_C_NAME_UNCOMPRESS, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	svcb := &SVCB{
+		Priority: p.(uint16),
+		Target:   t.(*Name),
+	}
+	//RFC 9460 2.4.2, params in alias mode must be ignored by recipients
+	if svcb.IsAliasMode() {
+		if _, _, err := fieldFromWire(RDF_C_BINARY, buf, ll); err != nil {
+			return nil, err
+		}
+		return svcb, nil
+	}
+
+	for ll > 0 {
+		k, l, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+		if err != nil {
+			return nil, err
+		}
+
+		vl, l, err := fieldFromWire(RDF_C_UINT16, buf, l)
+		if err != nil {
+			return nil, err
+		}
+		if vl.(uint16) > l {
+			return nil, ErrDataIsTooShort
+		}
+
+		v, _, err := fieldFromWire(RDF_C_BINARY, buf, vl.(uint16))
+		if err != nil {
+			return nil, err
+		}
+		ll = l - vl.(uint16)
+
+		key := SvcParamKey(k.(uint16))
+		if len(svcb.Params) > 0 && key <= svcb.Params[len(svcb.Params)-1].Key() {
+			return nil, ErrSvcParamKeyNotInOrder
+		}
+
+		param, err := svcParamFromWire(key, v.([]uint8))
+		if err != nil {
+			return nil, err
+		}
+		svcb.Params = append(svcb.Params, param)
+	}
+
+	if err := svcb.Validate(); err != nil {
+		return nil, err
+	}
+	return svcb, nil
+}
+
+var svcbRdataTemplate = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s*(.*?)\s*$`)
+
+// split key=value pairs, value may be quoted and include space
+func splitSvcParams(s string) ([][3]string, error) {
+	var params [][3]string
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+		key := s[start:i]
+		if i == len(s) || s[i] != '=' {
+			params = append(params, [3]string{key, "", ""})
+			continue
+		}
+
+		i++
+		start = i
+		if i < len(s) && s[i] == '"' {
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(s) {
+				return nil, ErrInvalidCharString
+			}
+			i++
+		} else {
+			for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				i++
+			}
+		}
+		params = append(params, [3]string{key, "=", s[start:i]})
+	}
+	return params, nil
+}
+
+func SVCBFromString(s string) (*SVCB, error) {
+	fields := svcbRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 4 {
+		return nil, errors.New("short of fields for svcb")
+	}
+	fields = fields[1:]
+
+	priority, err := uint16FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := fieldFromString(RDF_D_NAME, fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	svcb := &SVCB{
+		Priority: priority,
+		Target:   target.(*Name),
+	}
+
+	pairs, err := splitSvcParams(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		key, err := SvcParamKeyFromString(pair[0])
+		if err != nil {
+			return nil, err
+		}
+
+		value := pair[2]
+		if value != "" && value != `""` {
+			if value, err = unquoteCharString(value); err != nil {
+				return nil, err
+			}
+		} else {
+			value = ""
+		}
+
+		param, err := svcParamFromString(key, value, pair[1] == "=")
+		if err != nil {
+			return nil, err
+		}
+		svcb.Params = append(svcb.Params, param)
+	}
+
+	if err := svcb.Validate(); err != nil {
+		return nil, err
+	}
+	svcb.Params = svcb.sortedParams()
+	return svcb, nil
+}
+
+func (h *HTTPS) IsAliasMode() bool {
+	return (*SVCB)(h).IsAliasMode()
+}
+
+func (h *HTTPS) GetParam(key SvcParamKey) SvcParam {
+	return (*SVCB)(h).GetParam(key)
+}
+
+func (h *HTTPS) Validate() error {
+	return (*SVCB)(h).Validate()
+}
+
+func (h *HTTPS) Rend(r *MsgRender) {
+	(*SVCB)(h).Rend(r)
+}
+
+func (h *HTTPS) ToWire(buf *util.OutputBuffer) {
+	(*SVCB)(h).ToWire(buf)
+}
+
+func (h *HTTPS) Compare(other Rdata) int {
+	return (*SVCB)(h).compare((*SVCB)(other.(*HTTPS)))
+}
+
+func (h *HTTPS) String() string {
+	return (*SVCB)(h).String()
+}
+
+func HTTPSFromWire(buf *util.InputBuffer, ll uint16) (*HTTPS, error) {
+	svcb, err := SVCBFromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return (*HTTPS)(svcb), nil
+}
+
+func HTTPSFromString(s string) (*HTTPS, error) {
+	svcb, err := SVCBFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return (*HTTPS)(svcb), nil
+}
@@ -0,0 +1,84 @@
+package g53
+
+import (
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// test vectors from RFC 9460 appendix D
+func TestSVCBFromToString(t *testing.T) {
+	cases := []struct {
+		s      string
+		expect string
+		wire   string
+	}{
+		{`0 foo.example.com.`, `0 foo.example.com.`,
+			"0000" + "03666f6f076578616d706c6503636f6d00"},
+		{`1 .`, `1 .`, "000100"},
+		{`16 foo.example.com. port=53`, `16 foo.example.com. port=53`,
+			"0010" + "03666f6f076578616d706c6503636f6d00" + "000300020035"},
+		{`1 foo.example.com. key667=hello`, `1 foo.example.com. key667="hello"`,
+			"0001" + "03666f6f076578616d706c6503636f6d00" + "029b000568656c6c6f"},
+		{`1 foo.example.com. key667="hello\210qoo"`, `1 foo.example.com. key667="hello\210qoo"`,
+			"0001" + "03666f6f076578616d706c6503636f6d00" + "029b000968656c6c6fd2716f6f"},
+		{`1 foo.example.com. ipv6hint="2001:db8::1,2001:db8::53:1"`, `1 foo.example.com. ipv6hint=2001:db8::1,2001:db8::53:1`,
+			"0001" + "03666f6f076578616d706c6503636f6d00" + "00060020" +
+				"20010db8000000000000000000000001" + "20010db8000000000000000000530001"},
+		{`16 foo.example.org. alpn=h2,h3-19 mandatory=ipv4hint,alpn ipv4hint=192.0.2.1`,
+			`16 foo.example.org. mandatory=alpn,ipv4hint alpn="h2,h3-19" ipv4hint=192.0.2.1`,
+			"0010" + "03666f6f076578616d706c65036f726700" + "0000000400010004" +
+				"000100090268320568332d3139" + "00040004c0000201"},
+		{`16 foo.example.org. alpn="f\\\\oo\\,bar,h2"`, `16 foo.example.org. alpn="f\\\\oo\\,bar,h2"`,
+			"0010" + "03666f6f076578616d706c65036f726700" + "0001000c08665c6f6f2c626172026832"},
+		{`1 foo.example.com. alpn=h2 no-default-alpn ech="AEP+DQA/" key123`,
+			`1 foo.example.com. alpn="h2" no-default-alpn ech=AEP+DQA/ key123`,
+			"0001" + "03666f6f076578616d706c6503636f6d00" + "000100030268320002000000050006" + "0043fe0d003f" + "007b0000"},
+	}
+
+	for _, c := range cases {
+		svcb := rdataRoundTrip(t, RR_SVCB, c.s, c.expect)
+		buf := util.NewOutputBuffer(64)
+		svcb.ToWire(buf)
+		wire, _ := util.HexStrToBytes(c.wire)
+		WireMatch(t, wire, buf.Data())
+
+		https := rdataRoundTrip(t, RR_HTTPS, c.s, c.expect)
+		Equal(t, https.(*HTTPS).IsAliasMode(), svcb.(*SVCB).IsAliasMode())
+	}
+
+	for _, s := range []string{
+		`0 foo.example.com. port=53`,
+		`1 foo.example.com. port=53 port=54`,
+		`1 foo.example.com. mandatory=key123`,
+		`1 foo.example.com. mandatory=mandatory`,
+		`1 foo.example.com. mandatory=port,port port=53`,
+		`1 foo.example.com. no-default-alpn`,
+		`1 foo.example.com. alpn=h2 no-default-alpn=abc`,
+		`1 foo.example.com. port`,
+		`1 foo.example.com. port=65536`,
+		`1 foo.example.com. ipv4hint=2001:db8::1`,
+		`1 foo.example.com. ipv6hint=192.0.2.1`,
+		`1 foo.example.com. alpn=h2,,h3`,
+		`1 foo.example.com. key65535=abc`,
+		`1 foo.example.com. unknown=abc`,
+		`1 foo.example.com. alpn="h2`,
+	} {
+		_, err := SVCBFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+
+	// keys aren't in increasing order
+	wire, _ := util.HexStrToBytes("0010" + "001600" + "0003000201bb" + "00010003026832")
+	_, err := RdataFromWire(RR_SVCB, util.NewInputBuffer(wire))
+	Equal(t, err, ErrSvcParamKeyNotInOrder)
+
+	// params in alias mode are ignored
+	wire, _ = util.HexStrToBytes("0019" + "0000" + "03666f6f076578616d706c6503636f6d00" + "000300020035" + "00ff")
+	buf := util.NewInputBuffer(wire)
+	rdata, err := RdataFromWire(RR_HTTPS, buf)
+	Assert(t, err == nil, "params in alias mode should be ignored")
+	Equal(t, rdata.String(), "0 foo.example.com.")
+	Equal(t, len(rdata.(*HTTPS).Params), 0)
+	Equal(t, buf.Position(), uint(len(wire)-2))
+}
@@ -124,3 +124,28 @@ func TestTxtParse(t *testing.T) {
 		}
 	}
 }
+
+// parse rdata from string, render it to wire and parse it back, the
+// string of the rdata should be same with expect
+func rdataRoundTrip(t *testing.T, typ RRType, s string, expect string) Rdata {
+	rdata, err := RdataFromString(typ, s)
+	Assert(t, err == nil, "parse %s failed:%v", s, err)
+	Equal(t, rdata.String(), expect)
+
+	render := NewMsgRender()
+	render.WriteUint16(0)
+	rdata.Rend(render)
+	render.WriteUint16At(uint16(render.Len()-2), 0)
+	wire := render.Data()
+
+	parsed, err := RdataFromWire(typ, util.NewInputBuffer(wire))
+	Assert(t, err == nil, "parse wire of %s failed:%v", s, err)
+	Equal(t, parsed.Compare(rdata), 0)
+	Equal(t, parsed.String(), expect)
+
+	buf := util.NewOutputBuffer(uint(len(wire)))
+	buf.WriteUint16(uint16(len(wire) - 2))
+	parsed.ToWire(buf)
+	WireMatch(t, wire, buf.Data())
+	return parsed
+}
@@ -0,0 +1,228 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrUnknownTLSAUsage        = errors.New("unknown tlsa certificate usage")
+	ErrUnknownTLSASelector     = errors.New("unknown tlsa selector")
+	ErrUnknownTLSAMatchingType = errors.New("unknown tlsa matching type")
+	ErrTLSADataLen             = errors.New("tlsa data length doesn't match matching type")
+)
+
+const (
+	TLSA_USAGE_PKIX_TA uint8 = 0
+	TLSA_USAGE_PKIX_EE uint8 = 1
+	TLSA_USAGE_DANE_TA uint8 = 2
+	TLSA_USAGE_DANE_EE uint8 = 3
+
+	TLSA_SELECTOR_CERT uint8 = 0
+	TLSA_SELECTOR_SPKI uint8 = 1
+
+	TLSA_MATCH_FULL   uint8 = 0
+	TLSA_MATCH_SHA256 uint8 = 1
+	TLSA_MATCH_SHA512 uint8 = 2
+)
+
+// RFC 6698
+type TLSA struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// SMIMEA has the same format as TLSA, RFC 8162
+type SMIMEA TLSA
+
+func (t *TLSA) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT8, t.Usage, r)
+	rendField(RDF_C_UINT8, t.Selector, r)
+	rendField(RDF_C_UINT8, t.MatchingType, r)
+	rendField(RDF_C_BINARY, t.Data, r)
+}
+
+func (t *TLSA) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT8, t.Usage, buf)
+	fieldToWire(RDF_C_UINT8, t.Selector, buf)
+	fieldToWire(RDF_C_UINT8, t.MatchingType, buf)
+	fieldToWire(RDF_C_BINARY, t.Data, buf)
+}
+
+func (t *TLSA) Compare(other Rdata) int {
+	return t.compare(other.(*TLSA))
+}
+
+func (t *TLSA) compare(other *TLSA) int {
+	order := fieldCompare(RDF_C_UINT8, t.Usage, other.Usage)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, t.Selector, other.Selector)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, t.MatchingType, other.MatchingType)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, t.Data, other.Data)
+}
+
+func (t *TLSA) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, t.Usage))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, t.Selector))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, t.MatchingType))
+	buf.WriteString(" ")
+	buf.WriteString(hexToString(t.Data))
+	return buf.String()
+}
+
+func (t *TLSA) validate() error {
+	if t.Usage > TLSA_USAGE_DANE_EE {
+		return ErrUnknownTLSAUsage
+	}
+
+	if t.Selector > TLSA_SELECTOR_SPKI {
+		return ErrUnknownTLSASelector
+	}
+
+	switch t.MatchingType {
+	case TLSA_MATCH_FULL:
+		if len(t.Data) == 0 {
+			return ErrTLSADataLen
+		}
+	case TLSA_MATCH_SHA256:
+		if len(t.Data) != 32 {
+			return ErrTLSADataLen
+		}
+	case TLSA_MATCH_SHA512:
+		if len(t.Data) != 64 {
+			return ErrTLSADataLen
+		}
+	default:
+		return ErrUnknownTLSAMatchingType
+	}
+	return nil
+}
+
+func TLSAFromWire(buf *util.InputBuffer, ll uint16) (*TLSA, error) {
+	u, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	d, ll, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	tlsa := &TLSA{
+		Usage:        u.(uint8),
+		Selector:     s.(uint8),
+		MatchingType: m.(uint8),
+		Data:         d.([]uint8),
+	}
+	if err := tlsa.validate(); err != nil {
+		return nil, err
+	}
+	return tlsa, nil
+}
+
+var tlsaRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s+(\S+)\s+(.*?)\s*$`)
+
+func TLSAFromString(s string) (*TLSA, error) {
+	fields := tlsaRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 5 {
+		return nil, errors.New("short of fields for tlsa")
+	}
+	fields = fields[1:]
+
+	usage, err := uint8FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := uint8FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	matchingType, err := uint8FromString(fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := hexFromString(fields[3])
+	if err != nil {
+		return nil, err
+	}
+
+	tlsa := &TLSA{
+		Usage:        usage,
+		Selector:     selector,
+		MatchingType: matchingType,
+		Data:         data,
+	}
+	if err := tlsa.validate(); err != nil {
+		return nil, err
+	}
+	return tlsa, nil
+}
+
+func (s *SMIMEA) Rend(r *MsgRender) {
+	(*TLSA)(s).Rend(r)
+}
+
+func (s *SMIMEA) ToWire(buf *util.OutputBuffer) {
+	(*TLSA)(s).ToWire(buf)
+}
+
+func (s *SMIMEA) Compare(other Rdata) int {
+	return (*TLSA)(s).compare((*TLSA)(other.(*SMIMEA)))
+}
+
+func (s *SMIMEA) String() string {
+	return (*TLSA)(s).String()
+}
+
+func SMIMEAFromWire(buf *util.InputBuffer, ll uint16) (*SMIMEA, error) {
+	tlsa, err := TLSAFromWire(buf, ll)
+	if err != nil {
+		return nil, err
+	}
+	return (*SMIMEA)(tlsa), nil
+}
+
+func SMIMEAFromString(s string) (*SMIMEA, error) {
+	tlsa, err := TLSAFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return (*SMIMEA)(tlsa), nil
+}
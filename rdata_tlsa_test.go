@@ -0,0 +1,29 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestTLSAFromToString(t *testing.T) {
+	digest := "D2ABDE240D7CD3EE6B4B28C54DF034B97983A1D16E8A410E4561CB106618E971"
+	tlsa := rdataRoundTrip(t, RR_TLSA, "3 1 1 "+digest[:32]+" "+digest[32:], "3 1 1 "+digest).(*TLSA)
+	Equal(t, tlsa.Usage, TLSA_USAGE_DANE_EE)
+	Equal(t, tlsa.Selector, TLSA_SELECTOR_SPKI)
+	Equal(t, tlsa.MatchingType, TLSA_MATCH_SHA256)
+	rdataRoundTrip(t, RR_TLSA, "0 0 0 30820307", "0 0 0 30820307")
+
+	smimea := rdataRoundTrip(t, RR_SMIMEA, "3 0 1 "+digest, "3 0 1 "+digest).(*SMIMEA)
+	Equal(t, smimea.Usage, TLSA_USAGE_DANE_EE)
+
+	for _, s := range []string{
+		"4 1 1 " + digest,
+		"3 2 1 " + digest,
+		"3 1 3 " + digest,
+		"3 1 2 " + digest,
+		"3 1 0",
+		"3 1 0 abc",
+	} {
+		_, err := TLSAFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
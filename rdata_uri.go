@@ -0,0 +1,121 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrEmptyURITarget = errors.New("uri target is empty")
+
+// RFC 7553
+type URI struct {
+	Priority uint16
+	Weight   uint16
+	Target   string
+}
+
+func (u *URI) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT16, u.Priority, r)
+	rendField(RDF_C_UINT16, u.Weight, r)
+	rendField(RDF_C_BINARY, []byte(u.Target), r)
+}
+
+func (u *URI) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT16, u.Priority, buf)
+	fieldToWire(RDF_C_UINT16, u.Weight, buf)
+	fieldToWire(RDF_C_BINARY, []byte(u.Target), buf)
+}
+
+func (u *URI) Compare(other Rdata) int {
+	otherURI := other.(*URI)
+	order := fieldCompare(RDF_C_UINT16, u.Priority, otherURI.Priority)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT16, u.Weight, otherURI.Weight)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, []byte(u.Target), []byte(otherURI.Target))
+}
+
+func (u *URI) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, u.Priority))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, u.Weight))
+	buf.WriteString(" ")
+	buf.WriteString(quoteCharString(u.Target))
+	return buf.String()
+}
+
+func URIFromWire(buf *util.InputBuffer, ll uint16) (*URI, error) {
+	p, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	w, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ll, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	target := string(t.([]uint8))
+	if len(target) == 0 {
+		return nil, ErrEmptyURITarget
+	}
+
+	return &URI{
+		Priority: p.(uint16),
+		Weight:   w.(uint16),
+		Target:   target,
+	}, nil
+}
+
+var uriRdataTemplate = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s+(.*?)\s*$`)
+
+func URIFromString(s string) (*URI, error) {
+	fields := uriRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 4 {
+		return nil, errors.New("short of fields for uri")
+	}
+	fields = fields[1:]
+
+	priority, err := uint16FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	weight, err := uint16FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := unquoteCharString(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	if len(target) == 0 {
+		return nil, ErrEmptyURITarget
+	}
+
+	return &URI{
+		Priority: priority,
+		Weight:   weight,
+		Target:   target,
+	}, nil
+}
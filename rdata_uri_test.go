@@ -0,0 +1,27 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestURIFromToString(t *testing.T) {
+	uri := rdataRoundTrip(t, RR_URI, `10 1 "ftp://ftp1.example.com/public"`,
+		`10 1 "ftp://ftp1.example.com/public"`).(*URI)
+	Equal(t, uri.Priority, uint16(10))
+	Equal(t, uri.Weight, uint16(1))
+	Equal(t, uri.Target, "ftp://ftp1.example.com/public")
+
+	rdataRoundTrip(t, RR_URI, `10 1 http://www.example.com/path`, `10 1 "http://www.example.com/path"`)
+	rdataRoundTrip(t, RR_URI, `65535 0 "http://a b/\"q\""`, `65535 0 "http://a b/\"q\""`)
+
+	for _, s := range []string{
+		`10 1 ""`,
+		`10 "ftp://ftp1.example.com/public"`,
+		`65536 1 "ftp://ftp1.example.com/public"`,
+		`10 1 "ftp://ftp1.example.com/public`,
+		`10 1 ftp://ftp1.example.com/ public`,
+	} {
+		_, err := URIFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -0,0 +1,167 @@
+package g53
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var ErrZONEMDDigestLen = errors.New("zonemd digest length doesn't match hash algorithm")
+
+const (
+	ZONEMD_SCHEME_SIMPLE uint8 = 1
+
+	ZONEMD_HASH_SHA384 uint8 = 1
+	ZONEMD_HASH_SHA512 uint8 = 2
+
+	// digest shorter than this is invalid whatever the hash algorithm is
+	zonemdMinDigestLen = 12
+)
+
+// RFC 8976
+type ZONEMD struct {
+	Serial        uint32
+	Scheme        uint8
+	HashAlgorithm uint8
+	Digest        []byte
+}
+
+func (z *ZONEMD) Rend(r *MsgRender) {
+	rendField(RDF_C_UINT32, z.Serial, r)
+	rendField(RDF_C_UINT8, z.Scheme, r)
+	rendField(RDF_C_UINT8, z.HashAlgorithm, r)
+	rendField(RDF_C_BINARY, z.Digest, r)
+}
+
+func (z *ZONEMD) ToWire(buf *util.OutputBuffer) {
+	fieldToWire(RDF_C_UINT32, z.Serial, buf)
+	fieldToWire(RDF_C_UINT8, z.Scheme, buf)
+	fieldToWire(RDF_C_UINT8, z.HashAlgorithm, buf)
+	fieldToWire(RDF_C_BINARY, z.Digest, buf)
+}
+
+func (z *ZONEMD) Compare(other Rdata) int {
+	otherZONEMD := other.(*ZONEMD)
+	order := fieldCompare(RDF_C_UINT32, z.Serial, otherZONEMD.Serial)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, z.Scheme, otherZONEMD.Scheme)
+	if order != 0 {
+		return order
+	}
+
+	order = fieldCompare(RDF_C_UINT8, z.HashAlgorithm, otherZONEMD.HashAlgorithm)
+	if order != 0 {
+		return order
+	}
+
+	return fieldCompare(RDF_C_BINARY, z.Digest, otherZONEMD.Digest)
+}
+
+func (z *ZONEMD) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fieldToString(RDF_D_INT, z.Serial))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, z.Scheme))
+	buf.WriteString(" ")
+	buf.WriteString(fieldToString(RDF_D_INT, z.HashAlgorithm))
+	buf.WriteString(" ")
+	buf.WriteString(hexToString(z.Digest))
+	return buf.String()
+}
+
+// unknown scheme and hash algorithm are allowed, since verifier
+// should just ignore them
+func (z *ZONEMD) validate() error {
+	switch {
+	case len(z.Digest) < zonemdMinDigestLen:
+		return ErrZONEMDDigestLen
+	case z.HashAlgorithm == ZONEMD_HASH_SHA384 && len(z.Digest) != 48:
+		return ErrZONEMDDigestLen
+	case z.HashAlgorithm == ZONEMD_HASH_SHA512 && len(z.Digest) != 64:
+		return ErrZONEMDDigestLen
+	}
+	return nil
+}
+
+func ZONEMDFromWire(buf *util.InputBuffer, ll uint16) (*ZONEMD, error) {
+	serial, ll, err := fieldFromWire(RDF_C_UINT32, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, ll, err := fieldFromWire(RDF_C_UINT8, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, ll, err := fieldFromWire(RDF_C_BINARY, buf, ll)
+	if err != nil {
+		return nil, err
+	}
+
+	if ll != 0 {
+		return nil, errors.New("extra data in rdata part")
+	}
+
+	zonemd := &ZONEMD{
+		Serial:        serial.(uint32),
+		Scheme:        scheme.(uint8),
+		HashAlgorithm: alg.(uint8),
+		Digest:        digest.([]uint8),
+	}
+	if err := zonemd.validate(); err != nil {
+		return nil, err
+	}
+	return zonemd, nil
+}
+
+var zonemdRdataTemplate = regexp.MustCompile(`(?s)^\s*(\S+)\s+(\S+)\s+(\S+)\s+(.*?)\s*$`)
+
+func ZONEMDFromString(s string) (*ZONEMD, error) {
+	fields := zonemdRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 5 {
+		return nil, errors.New("short of fields for zonemd")
+	}
+	fields = fields[1:]
+
+	serial, err := uint32FromString(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, err := uint8FromString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := uint8FromString(fields[2])
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := hexFromString(fields[3])
+	if err != nil {
+		return nil, err
+	}
+
+	zonemd := &ZONEMD{
+		Serial:        serial,
+		Scheme:        scheme,
+		HashAlgorithm: alg,
+		Digest:        digest,
+	}
+	if err := zonemd.validate(); err != nil {
+		return nil, err
+	}
+	return zonemd, nil
+}
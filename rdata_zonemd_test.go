@@ -0,0 +1,31 @@
+package g53
+
+import (
+	"testing"
+)
+
+func TestZONEMDFromToString(t *testing.T) {
+	// example from RFC 8976 appendix A.1
+	zonemd := rdataRoundTrip(t, RR_ZONEMD, `2018031900 1 1
+		c68090d90a7aed716bc459f9340e3d7c
+		1370d4d24b7e2fc3a1ddc0b9a87153b9
+		a9713b3c9ae5cc27777f98b8e730044c`,
+		"2018031900 1 1 C68090D90A7AED716BC459F9340E3D7C1370D4D24B7E2FC3A1DDC0B9A87153B9A9713B3C9AE5CC27777F98B8E730044C").(*ZONEMD)
+	Equal(t, zonemd.Serial, uint32(2018031900))
+	Equal(t, zonemd.Scheme, ZONEMD_SCHEME_SIMPLE)
+	Equal(t, zonemd.HashAlgorithm, ZONEMD_HASH_SHA384)
+
+	rdataRoundTrip(t, RR_ZONEMD, "4294967295 240 241 00112233445566778899aabb",
+		"4294967295 240 241 00112233445566778899AABB")
+
+	for _, s := range []string{
+		"2018031900 1 1 c68090d90a7aed716bc459f9340e3d7c",
+		"2018031900 1 2 c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c",
+		"2018031900 240 241 00112233445566778899aa",
+		"4294967296 1 1 c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c",
+		"2018031900 1 1",
+	} {
+		_, err := ZONEMDFromString(s)
+		Assert(t, err != nil, "%s should be invalid", s)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/base32"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"regexp"
 	"strconv"
@@ -21,6 +23,7 @@ var (
 	ErrDataIsTooShort        = errors.New("raw data isn't long enough")
 	ErrOutOfRange            = errors.New("data out of range")
 	ErrInvalidTXT            = errors.New("txt record is not valid")
+	ErrInvalidCharString     = errors.New("character string isn't valid")
 )
 
 type RDFCodingType uint8
@@ -456,3 +459,149 @@ func txtStringParse(txt string) ([]string, error) {
 		return strs, nil
 	}
 }
+
+func uint8FromString(s string) (uint8, error) {
+	d, err := fieldFromString(RDF_D_INT, s)
+	if err != nil {
+		return 0, err
+	}
+	if v, _ := d.(int); v < 0 || v > math.MaxUint8 {
+		return 0, ErrOutOfRange
+	} else {
+		return uint8(v), nil
+	}
+}
+
+func uint16FromString(s string) (uint16, error) {
+	d, err := fieldFromString(RDF_D_INT, s)
+	if err != nil {
+		return 0, err
+	}
+	if v, _ := d.(int); v < 0 || v > math.MaxUint16 {
+		return 0, ErrOutOfRange
+	} else {
+		return uint16(v), nil
+	}
+}
+
+func uint32FromString(s string) (uint32, error) {
+	d, err := fieldFromString(RDF_D_INT, s)
+	if err != nil {
+		return 0, err
+	}
+	if v, _ := d.(int); v < 0 || v > math.MaxUint32 {
+		return 0, ErrOutOfRange
+	} else {
+		return uint32(v), nil
+	}
+}
+
+// binary data in hex or base64 could be split by whitespace
+func hexFromString(s string) ([]byte, error) {
+	return hex.DecodeString(spaceReg.ReplaceAllString(s, ""))
+}
+
+func hexToString(d []byte) string {
+	return strings.ToUpper(hex.EncodeToString(d))
+}
+
+func base64FromString(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(spaceReg.ReplaceAllString(s, ""))
+}
+
+// character string is quoted, quote and backslash are escaped, non
+// printable character is in \DDD format
+func quoteCharString(v string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			buf.WriteString(fmt.Sprintf("\\%03d", c))
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func unquoteCharString(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	} else if spaceReg.MatchString(s) || len(s) == 0 {
+		return "", ErrInvalidCharString
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' {
+			return "", ErrInvalidCharString
+		} else if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		if i+1 == len(s) {
+			return "", ErrInvalidCharString
+		}
+		if isDigit(s[i+1]) {
+			if i+3 >= len(s) {
+				return "", ErrInvalidCharString
+			}
+			d, err := strconv.Atoi(s[i+1 : i+4])
+			if err != nil || d > 255 {
+				return "", ErrInvalidCharString
+			}
+			buf.WriteByte(byte(d))
+			i += 3
+		} else {
+			buf.WriteByte(s[i+1])
+			i += 1
+		}
+	}
+	return buf.String(), nil
+}
+
+// rdata with a 16 bit integer followed by a domain name, like kx and rt
+func uint16NameFromWire(buf *util.InputBuffer, ll uint16) (uint16, *Name, error) {
+	d, ll, err := fieldFromWire(RDF_C_UINT16, buf, ll)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	n, ll, err := fieldFromWire(RDF_C_NAME_UNCOMPRESS, buf, ll)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if ll != 0 {
+		return 0, nil, errors.New("extra data in rdata part")
+	}
+	return d.(uint16), n.(*Name), nil
+}
+
+var uint16NameRdataTemplate = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s*$`)
+
+func uint16NameFromString(s string) (uint16, *Name, error) {
+	fields := uint16NameRdataTemplate.FindStringSubmatch(s)
+	if len(fields) != 3 {
+		return 0, nil, errors.New("fields count isn't 2")
+	}
+
+	d, err := uint16FromString(fields[1])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	n, err := fieldFromString(RDF_D_NAME, fields[2])
+	if err != nil {
+		return 0, nil, err
+	}
+	return d, n.(*Name), nil
+}
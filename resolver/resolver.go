@@ -0,0 +1,414 @@
+package resolver
+
+import (
+	"errors"
+	"net"
+
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrQueryBudgetExceeded = errors.New("query budget is exhausted")
+	ErrNoServerAvailable   = errors.New("no name server gives valid response")
+	ErrCNAMEChainTooLong   = errors.New("cname chain is too long")
+	ErrNestTooDeep         = errors.New("name server resolution is nested too deep")
+	ErrResponseMismatch    = util.ErrResponseMismatch
+)
+
+const (
+	DefaultQueryBudget   = 64
+	DefaultMaxCNAMEChain = 8
+	DefaultMaxDepth      = 4
+
+	queryUDPSize = 1232
+)
+
+// ipv4 address of a to m root servers
+var DefaultRootServers = []net.IP{
+	net.ParseIP("198.41.0.4"),
+	net.ParseIP("170.247.170.2"),
+	net.ParseIP("192.33.4.12"),
+	net.ParseIP("199.7.91.13"),
+	net.ParseIP("192.203.230.10"),
+	net.ParseIP("192.5.5.241"),
+	net.ParseIP("192.112.36.4"),
+	net.ParseIP("198.97.190.53"),
+	net.ParseIP("192.36.148.17"),
+	net.ParseIP("192.58.128.30"),
+	net.ParseIP("193.0.14.129"),
+	net.ParseIP("199.7.83.42"),
+	net.ParseIP("202.12.27.33"),
+}
+
+// Resolver resolve name iteratively from root servers, nothing is cached,
+// each Resolve starts from root
+type Resolver struct {
+	roots     []net.IP
+	transport Transport
+
+	// send minimised qname to servers of ancestor zones, RFC 9156
+	QnameMinimisation bool
+	// max queries sent for one Resolve, including queries to resolve
+	// address of glueless name servers
+	QueryBudget   int
+	MaxCNAMEChain int
+	// max nest level of resolving glueless name servers
+	MaxDepth int
+}
+
+func New(roots []net.IP, transport Transport) *Resolver {
+	if len(roots) == 0 {
+		roots = DefaultRootServers
+	}
+	if transport == nil {
+		transport = NewNetTransport()
+	}
+	return &Resolver{
+		roots:         roots,
+		transport:     transport,
+		QueryBudget:   DefaultQueryBudget,
+		MaxCNAMEChain: DefaultMaxCNAMEChain,
+		MaxDepth:      DefaultMaxDepth,
+	}
+}
+
+// Result is the final answer, answer section includes the cname and dname
+// chain, authority section holds the soa for negative answer
+type Result struct {
+	Rcode     g53.Rcode
+	Answer    []*g53.RRset
+	Authority []*g53.RRset
+	// queries sent to get the result
+	Queries int
+}
+
+type nameServer struct {
+	name     *g53.Name
+	addrs    []net.IP
+	resolved bool
+}
+
+type delegation struct {
+	zone    *g53.Name
+	servers []*nameServer
+}
+
+type resolveState struct {
+	queries int
+}
+
+func (r *Resolver) Resolve(name *g53.Name, typ g53.RRType) (*Result, error) {
+	state := &resolveState{}
+	result, err := r.resolve(state, name, typ, 0)
+	if err != nil {
+		return nil, err
+	}
+	result.Queries = state.queries
+	return result, nil
+}
+
+func (r *Resolver) resolve(state *resolveState, name *g53.Name, typ g53.RRType, depth int) (*Result, error) {
+	if depth > r.MaxDepth {
+		return nil, ErrNestTooDeep
+	}
+
+	result := &Result{Rcode: g53.R_NOERROR}
+	qname := name
+	for {
+		partial, next, err := r.iterate(state, qname, typ, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Rcode = partial.Rcode
+		result.Answer = append(result.Answer, partial.Answer...)
+		result.Authority = partial.Authority
+		if next == nil {
+			return result, nil
+		}
+
+		if cnameCount(result.Answer) > r.MaxCNAMEChain {
+			return nil, ErrCNAMEChainTooLong
+		}
+		qname = next
+	}
+}
+
+func cnameCount(rrsets []*g53.RRset) int {
+	count := 0
+	for _, rrset := range rrsets {
+		if rrset.Type == g53.RR_CNAME {
+			count += 1
+		}
+	}
+	return count
+}
+
+func (r *Resolver) rootDelegation() *delegation {
+	ns := &nameServer{resolved: true}
+	ns.addrs = append(ns.addrs, r.roots...)
+	return &delegation{
+		zone:    g53.Root,
+		servers: []*nameServer{ns},
+	}
+}
+
+// iterate follows referrals from root until an answer is got, if the
+// answer ends with a cname or dname whose target isn't answered, the
+// target is returned for caller to resolve from root again
+func (r *Resolver) iterate(state *resolveState, qname *g53.Name, typ g53.RRType, depth int) (*Result, *g53.Name, error) {
+	d := r.rootDelegation()
+	labels := d.zone.LabelCount() + 1
+	for {
+		qn, qt := qname, typ
+		minimised := false
+		if r.QnameMinimisation && labels < qname.LabelCount() {
+			qn, _ = qname.Parent(qname.LabelCount() - labels)
+			qt = g53.RR_A
+			minimised = true
+		}
+
+		resp, err := r.query(state, d, qn, qt, depth)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if child := referral(resp, d.zone, qn); child != nil {
+			d = child
+			labels = d.zone.LabelCount() + 1
+			continue
+		}
+
+		if minimised {
+			// RFC 8020, nothing exists under nxdomain
+			if resp.Header.Rcode == g53.R_NXDOMAIN {
+				return &Result{
+					Rcode:     g53.R_NXDOMAIN,
+					Authority: soaInZone(resp, d.zone),
+				}, nil, nil
+			}
+			labels += 1
+			continue
+		}
+
+		result, next := answer(resp, d.zone, qname, typ)
+		return result, next, nil
+	}
+}
+
+// query send the question to the servers of the delegation one by one until
+// a valid response is got, servers with glue are tried before glueless ones
+func (r *Resolver) query(state *resolveState, d *delegation, qname *g53.Name, typ g53.RRType, depth int) (*g53.Message, error) {
+	for _, resolveGlueless := range []bool{false, true} {
+		for _, ns := range d.servers {
+			if resolveGlueless && ns.resolved == false {
+				ns.resolved = true
+				// name server inside the zone without glue can't be reached
+				if ns.name.IsSubDomain(d.zone) == false {
+					addrs, err := r.resolveAddrs(state, ns.name, depth+1)
+					if err == ErrQueryBudgetExceeded {
+						return nil, err
+					}
+					ns.addrs = addrs
+				}
+			} else if resolveGlueless != (len(ns.addrs) == 0) {
+				continue
+			}
+
+			for _, addr := range ns.addrs {
+				resp, err := r.exchange(state, addr, qname, typ)
+				if err == ErrQueryBudgetExceeded {
+					return nil, err
+				}
+				if err == nil && isValidResponse(resp, d.zone, qname) {
+					return resp, nil
+				}
+			}
+		}
+	}
+	return nil, ErrNoServerAvailable
+}
+
+func (r *Resolver) resolveAddrs(state *resolveState, name *g53.Name, depth int) ([]net.IP, error) {
+	result, err := r.resolve(state, name, g53.RR_A, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []net.IP
+	for _, rrset := range result.Answer {
+		if rrset.Type == g53.RR_A {
+			for _, rdata := range rrset.Rdatas {
+				addrs = append(addrs, rdata.(*g53.A).Host)
+			}
+		}
+	}
+	return addrs, nil
+}
+
+func (r *Resolver) exchange(state *resolveState, server net.IP, qname *g53.Name, typ g53.RRType) (*g53.Message, error) {
+	if state.queries >= r.QueryBudget {
+		return nil, ErrQueryBudgetExceeded
+	}
+	state.queries += 1
+
+	query := g53.MakeQuery(qname, typ, queryUDPSize, false)
+	query.Header.SetFlag(g53.FLAG_RD, false)
+	resp, err := r.transport.Exchange(server, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Header.Id != query.Header.Id || resp.Question == nil ||
+		resp.Question.Name.Equals(qname) == false || resp.Question.Type != typ {
+		return nil, ErrResponseMismatch
+	}
+	return resp, nil
+}
+
+// response is valid if it's an answer, a negative answer or a referral to
+// child zone, others like refused or upward referral means the server is lame
+func isValidResponse(resp *g53.Message, zone, qname *g53.Name) bool {
+	switch resp.Header.Rcode {
+	case g53.R_NXDOMAIN:
+		return true
+	case g53.R_NOERROR:
+		if resp.Header.GetFlag(g53.FLAG_AA) {
+			return true
+		}
+		return referral(resp, zone, qname) != nil
+	default:
+		return false
+	}
+}
+
+// rrsets outside the zone of the server are ignored to avoid poisoning
+func inBailiwick(rrset *g53.RRset, zone *g53.Name) bool {
+	return rrset.Class == g53.CLASS_IN && rrset.Name.IsSubDomain(zone)
+}
+
+func referral(resp *g53.Message, zone, qname *g53.Name) *delegation {
+	if resp.Header.Rcode != g53.R_NOERROR || len(resp.Sections[g53.AnswerSection]) != 0 {
+		return nil
+	}
+
+	var child *delegation
+	for _, rrset := range resp.Sections[g53.AuthSection] {
+		if rrset.Type == g53.RR_NS && inBailiwick(rrset, zone) &&
+			rrset.Name.Equals(zone) == false && qname.IsSubDomain(rrset.Name) {
+			child = &delegation{zone: rrset.Name}
+			for _, rdata := range rrset.Rdatas {
+				child.servers = append(child.servers, &nameServer{name: rdata.(*g53.NS).Name})
+			}
+			break
+		}
+	}
+	if child == nil {
+		return nil
+	}
+
+	for _, rrset := range resp.Sections[g53.AdditionalSection] {
+		if (rrset.Type != g53.RR_A && rrset.Type != g53.RR_AAAA) || inBailiwick(rrset, zone) == false {
+			continue
+		}
+		for _, ns := range child.servers {
+			if ns.name.Equals(rrset.Name) {
+				for _, rdata := range rrset.Rdatas {
+					if a, ok := rdata.(*g53.A); ok {
+						ns.addrs = append(ns.addrs, a.Host)
+					} else {
+						ns.addrs = append(ns.addrs, rdata.(*g53.AAAA).Host)
+					}
+				}
+				ns.resolved = true
+			}
+		}
+	}
+	return child
+}
+
+func soaInZone(resp *g53.Message, zone *g53.Name) []*g53.RRset {
+	var soa []*g53.RRset
+	for _, rrset := range resp.Sections[g53.AuthSection] {
+		if rrset.Type == g53.RR_SOA && inBailiwick(rrset, zone) {
+			soa = append(soa, rrset)
+		}
+	}
+	return soa
+}
+
+func findRRset(rrsets []*g53.RRset, name *g53.Name, typ g53.RRType) *g53.RRset {
+	for _, rrset := range rrsets {
+		if rrset.Type == typ && rrset.Name.Equals(name) {
+			return rrset
+		}
+	}
+	return nil
+}
+
+func findDName(rrsets []*g53.RRset, name *g53.Name) *g53.RRset {
+	for _, rrset := range rrsets {
+		if rrset.Type == g53.RR_DNAME && len(rrset.Rdatas) == 1 &&
+			rrset.Name.Equals(name) == false && name.IsSubDomain(rrset.Name) {
+			return rrset
+		}
+	}
+	return nil
+}
+
+// answer follow the cname and dname chain in answer section, if the final
+// name isn't answered, it's returned as the next name to resolve
+func answer(resp *g53.Message, zone, qname *g53.Name, typ g53.RRType) (*Result, *g53.Name) {
+	var rrsets []*g53.RRset
+	for _, rrset := range resp.Sections[g53.AnswerSection] {
+		if inBailiwick(rrset, zone) {
+			rrsets = append(rrsets, rrset)
+		}
+	}
+
+	result := &Result{Rcode: resp.Header.Rcode}
+	current := qname
+	for i := 0; i <= len(rrsets); i++ {
+		if rrset := findRRset(rrsets, current, typ); rrset != nil {
+			result.Rcode = g53.R_NOERROR
+			result.Answer = append(result.Answer, rrset)
+			return result, nil
+		}
+
+		// synthesize cname from dname, RFC 6672
+		if dname := findDName(rrsets, current); dname != nil {
+			prefix, _ := current.Subtract(dname.Name)
+			target, err := prefix.Concat(dname.Rdatas[0].(*g53.DName).Target)
+			if err != nil {
+				// RFC 6672 2.2, name too long after substitution
+				result.Rcode = g53.R_YXDOMAIN
+				result.Answer = append(result.Answer, dname)
+				return result, nil
+			}
+			result.Answer = append(result.Answer, dname, &g53.RRset{
+				Name:   current,
+				Type:   g53.RR_CNAME,
+				Class:  g53.CLASS_IN,
+				Ttl:    dname.Ttl,
+				Rdatas: []g53.Rdata{&g53.CName{Name: target}},
+			})
+			current = target
+			continue
+		}
+
+		if cname := findRRset(rrsets, current, g53.RR_CNAME); cname != nil && len(cname.Rdatas) == 1 {
+			result.Answer = append(result.Answer, cname)
+			current = cname.Rdatas[0].(*g53.CName).Name
+			continue
+		}
+		break
+	}
+
+	if len(result.Answer) != 0 {
+		return result, current
+	}
+
+	result.Authority = soaInZone(resp, zone)
+	return result, nil
+}
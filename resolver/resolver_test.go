@@ -0,0 +1,311 @@
+package resolver
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+type fakeZone struct {
+	origin *g53.Name
+	rrsets []*g53.RRset
+}
+
+func newFakeZone(origin string, rrs []string) *fakeZone {
+	z := &fakeZone{origin: g53.NameFromStringUnsafe(origin)}
+	for _, rr := range rrs {
+		rrset, err := g53.RRsetFromString(rr)
+		if err != nil {
+			panic("invalid rr " + rr)
+		}
+		if old := z.get(rrset.Name, rrset.Type); old != nil {
+			old.AddRdata(rrset.Rdatas[0])
+		} else {
+			z.rrsets = append(z.rrsets, rrset)
+		}
+	}
+	return z
+}
+
+func (z *fakeZone) get(name *g53.Name, typ g53.RRType) *g53.RRset {
+	return findRRset(z.rrsets, name, typ)
+}
+
+func (z *fakeZone) nameExists(name *g53.Name) bool {
+	for _, rrset := range z.rrsets {
+		if rrset.Name.IsSubDomain(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *fakeZone) answer(resp *g53.Message, qname *g53.Name, typ g53.RRType) {
+	soa := z.get(z.origin, g53.RR_SOA)
+	for i := int(qname.LabelCount()-z.origin.LabelCount()) - 1; i >= 0; i-- {
+		name, _ := qname.Parent(uint(i))
+		if ns := z.get(name, g53.RR_NS); ns != nil {
+			resp.AddRRset(g53.AuthSection, ns)
+			for _, rdata := range ns.Rdatas {
+				if glue := z.get(rdata.(*g53.NS).Name, g53.RR_A); glue != nil {
+					resp.AddRRset(g53.AdditionalSection, glue)
+				}
+			}
+			return
+		}
+
+		if dname := z.get(name, g53.RR_DNAME); dname != nil && i > 0 {
+			resp.Header.SetFlag(g53.FLAG_AA, true)
+			prefix, _ := qname.Subtract(name)
+			target, _ := prefix.Concat(dname.Rdatas[0].(*g53.DName).Target)
+			resp.AddRRset(g53.AnswerSection, dname)
+			resp.AddRR(g53.AnswerSection, qname, g53.RR_CNAME, g53.CLASS_IN, dname.Ttl, &g53.CName{Name: target}, false)
+			return
+		}
+	}
+
+	resp.Header.SetFlag(g53.FLAG_AA, true)
+	for i := 0; i < 8; i++ {
+		if rrset := z.get(qname, typ); rrset != nil {
+			resp.AddRRset(g53.AnswerSection, rrset)
+			return
+		}
+		cname := z.get(qname, g53.RR_CNAME)
+		if cname == nil {
+			break
+		}
+		// follow the target even it's out of zone, just like a poisoner
+		resp.AddRRset(g53.AnswerSection, cname)
+		qname = cname.Rdatas[0].(*g53.CName).Name
+	}
+
+	if len(resp.Sections[g53.AnswerSection]) == 0 {
+		if z.nameExists(qname) == false {
+			resp.Header.Rcode = g53.R_NXDOMAIN
+		}
+		resp.AddRRset(g53.AuthSection, soa)
+	}
+}
+
+type fakeNetwork struct {
+	servers map[string][]*fakeZone
+	queries []string
+}
+
+func (n *fakeNetwork) Exchange(server net.IP, query *g53.Message) (*g53.Message, error) {
+	zones, ok := n.servers[server.String()]
+	if ok == false {
+		return nil, errors.New("timeout")
+	}
+
+	query, err := throughWire(query)
+	if err != nil {
+		return nil, err
+	}
+	n.queries = append(n.queries, server.String()+" "+query.Question.Name.String(false))
+
+	resp := query.MakeResponse()
+	var zone *fakeZone
+	for _, z := range zones {
+		if query.Question.Name.IsSubDomain(z.origin) &&
+			(zone == nil || z.origin.LabelCount() > zone.origin.LabelCount()) {
+			zone = z
+		}
+	}
+	if zone == nil {
+		resp.Header.Rcode = g53.R_REFUSED
+	} else {
+		zone.answer(resp, query.Question.Name, query.Question.Type)
+	}
+	return throughWire(resp)
+}
+
+func throughWire(msg *g53.Message) (*g53.Message, error) {
+	msg.RecalculateSectionRRCount()
+	render := g53.NewMsgRender()
+	msg.Rend(render)
+	return g53.MessageFromWire(util.NewInputBuffer(render.Data()))
+}
+
+func newFakeNetwork() *fakeNetwork {
+	root := newFakeZone(".", []string{
+		". 86400 IN SOA a.root-servers.net. nstld.verisign-grs.com. 1 1800 900 604800 86400",
+		". 86400 IN NS a.root-servers.net.",
+		"com. 172800 IN NS a.gtld-servers.net.",
+		"net. 172800 IN NS a.gtld-servers.net.",
+		"org. 172800 IN NS a0.org-servers.org.",
+		"a.gtld-servers.net. 172800 IN A 10.0.0.2",
+		"a0.org-servers.org. 172800 IN A 10.0.0.5",
+	})
+	com := newFakeZone("com.", []string{
+		"com. 900 IN SOA a.gtld-servers.net. nstld.verisign-grs.com. 1 1800 900 604800 86400",
+		"com. 172800 IN NS a.gtld-servers.net.",
+		"example.com. 172800 IN NS ns1.example.net.",
+		"evil.com. 172800 IN NS ns.example.org.",
+		"ns.example.org. 172800 IN A 6.6.6.6",
+	})
+	netZone := newFakeZone("net.", []string{
+		"net. 900 IN SOA a.gtld-servers.net. nstld.verisign-grs.com. 1 1800 900 604800 86400",
+		"net. 172800 IN NS a.gtld-servers.net.",
+		"example.net. 172800 IN NS ns.example.net.",
+		"ns.example.net. 172800 IN A 10.0.0.3",
+	})
+	exampleNet := newFakeZone("example.net.", []string{
+		"example.net. 3600 IN SOA ns.example.net. root.example.net. 1 3600 600 86400 300",
+		"example.net. 3600 IN NS ns.example.net.",
+		"ns.example.net. 3600 IN A 10.0.0.3",
+		"ns1.example.net. 3600 IN A 10.0.0.4",
+	})
+	exampleCom := newFakeZone("example.com.", []string{
+		"example.com. 3600 IN SOA ns1.example.net. root.example.com. 1 3600 600 86400 300",
+		"example.com. 3600 IN NS ns1.example.net.",
+		"www.example.com. 3600 IN A 192.0.2.1",
+		"alias.example.com. 3600 IN CNAME www.example.com.",
+		"ext.example.com. 3600 IN CNAME www.example.org.",
+		"www.example.org. 3600 IN A 6.6.6.6",
+		"dept.example.com. 3600 IN DNAME example.org.",
+		"loop1.example.com. 3600 IN CNAME loop2.example.com.",
+		"loop2.example.com. 3600 IN CNAME loop1.example.com.",
+	})
+	org := newFakeZone("org.", []string{
+		"org. 900 IN SOA a0.org-servers.org. noc.org-servers.org. 1 1800 900 604800 86400",
+		"org. 172800 IN NS a0.org-servers.org.",
+		"a0.org-servers.org. 172800 IN A 10.0.0.5",
+		"example.org. 172800 IN NS ns.example.org.",
+		"ns.example.org. 172800 IN A 10.0.0.6",
+	})
+	exampleOrg := newFakeZone("example.org.", []string{
+		"example.org. 3600 IN SOA ns.example.org. root.example.org. 1 3600 600 86400 300",
+		"example.org. 3600 IN NS ns.example.org.",
+		"ns.example.org. 3600 IN A 10.0.0.6",
+		"www.example.org. 3600 IN A 192.0.2.80",
+		"host.example.org. 3600 IN A 192.0.2.81",
+	})
+	evilCom := newFakeZone("evil.com.", []string{
+		"evil.com. 3600 IN SOA ns.example.org. root.evil.com. 1 3600 600 86400 300",
+		"evil.com. 3600 IN NS ns.example.org.",
+		"www.evil.com. 3600 IN A 192.0.2.66",
+	})
+
+	return &fakeNetwork{
+		servers: map[string][]*fakeZone{
+			"10.0.0.1": []*fakeZone{root},
+			"10.0.0.2": []*fakeZone{com, netZone},
+			"10.0.0.3": []*fakeZone{exampleNet},
+			"10.0.0.4": []*fakeZone{exampleCom},
+			"10.0.0.5": []*fakeZone{org},
+			"10.0.0.6": []*fakeZone{exampleOrg, evilCom},
+		},
+	}
+}
+
+func newTestResolver() (*Resolver, *fakeNetwork) {
+	network := newFakeNetwork()
+	return New([]net.IP{net.ParseIP("10.0.0.1")}, network), network
+}
+
+func resolveStrings(t *testing.T, r *Resolver, name string, typ g53.RRType) (g53.Rcode, []string) {
+	result, err := r.Resolve(g53.NameFromStringUnsafe(name), typ)
+	ut.Assert(t, err == nil, "resolve %s failed:%v", name, err)
+	var rrs []string
+	for _, rrset := range result.Answer {
+		for _, rdata := range rrset.Rdatas {
+			rrs = append(rrs, rrset.Name.String(false)+" "+rrset.Type.String()+" "+rdata.String())
+		}
+	}
+	return result.Rcode, rrs
+}
+
+func TestResolveReferral(t *testing.T) {
+	r, network := newTestResolver()
+	rcode, rrs := resolveStrings(t, r, "www.example.com.", g53.RR_A)
+	ut.Equal(t, rcode, g53.R_NOERROR)
+	ut.Equal(t, rrs, []string{"www.example.com. A 192.0.2.1"})
+	// root, com, then ns1.example.net from root, net, example.net
+	// and finally example.com
+	ut.Equal(t, len(network.queries), 6)
+
+	result, err := r.Resolve(g53.NameFromStringUnsafe("nx.example.com."), g53.RR_A)
+	ut.Assert(t, err == nil, "resolve failed:%v", err)
+	ut.Equal(t, result.Rcode, g53.R_NXDOMAIN)
+	ut.Equal(t, len(result.Authority), 1)
+	ut.Equal(t, result.Authority[0].Type, g53.RR_SOA)
+
+	result, err = r.Resolve(g53.NameFromStringUnsafe("www.example.com."), g53.RR_MX)
+	ut.Assert(t, err == nil, "resolve failed:%v", err)
+	ut.Equal(t, result.Rcode, g53.R_NOERROR)
+	ut.Equal(t, len(result.Answer), 0)
+	ut.Equal(t, len(result.Authority), 1)
+}
+
+func TestResolveCNAMEAndDNAME(t *testing.T) {
+	r, _ := newTestResolver()
+	_, rrs := resolveStrings(t, r, "alias.example.com.", g53.RR_A)
+	ut.Equal(t, rrs, []string{
+		"alias.example.com. CNAME www.example.com.",
+		"www.example.com. A 192.0.2.1",
+	})
+
+	// the out of zone address given by example.com server is ignored
+	_, rrs = resolveStrings(t, r, "ext.example.com.", g53.RR_A)
+	ut.Equal(t, rrs, []string{
+		"ext.example.com. CNAME www.example.org.",
+		"www.example.org. A 192.0.2.80",
+	})
+
+	_, rrs = resolveStrings(t, r, "host.dept.example.com.", g53.RR_A)
+	ut.Equal(t, rrs, []string{
+		"dept.example.com. DNAME example.org.",
+		"host.dept.example.com. CNAME host.example.org.",
+		"host.example.org. A 192.0.2.81",
+	})
+
+	_, err := r.Resolve(g53.NameFromStringUnsafe("loop1.example.com."), g53.RR_A)
+	ut.Equal(t, err, ErrCNAMEChainTooLong)
+}
+
+func TestResolveIgnoreOutOfBailiwickGlue(t *testing.T) {
+	r, network := newTestResolver()
+	_, rrs := resolveStrings(t, r, "www.evil.com.", g53.RR_A)
+	ut.Equal(t, rrs, []string{"www.evil.com. A 192.0.2.66"})
+	for _, q := range network.queries {
+		ut.Assert(t, q[:8] != "6.6.6.6 ", "poisoned glue is used")
+	}
+}
+
+func TestResolveQnameMinimisation(t *testing.T) {
+	r, network := newTestResolver()
+	r.QnameMinimisation = true
+	_, rrs := resolveStrings(t, r, "host.dept.example.com.", g53.RR_A)
+	ut.Equal(t, rrs[len(rrs)-1], "host.example.org. A 192.0.2.81")
+
+	for _, q := range network.queries {
+		switch q[:9] {
+		case "10.0.0.1 ":
+			ut.Assert(t, q == "10.0.0.1 com." || q == "10.0.0.1 net." || q == "10.0.0.1 org.",
+				"root server get unminimised query %s", q)
+		case "10.0.0.2 ":
+			ut.Assert(t, q == "10.0.0.2 example.com." || q == "10.0.0.2 example.net.",
+				"tld server get unminimised query %s", q)
+		}
+	}
+
+	result, err := r.Resolve(g53.NameFromStringUnsafe("a.b.nx.example.com."), g53.RR_A)
+	ut.Assert(t, err == nil, "resolve failed:%v", err)
+	ut.Equal(t, result.Rcode, g53.R_NXDOMAIN)
+}
+
+func TestResolveQueryBudget(t *testing.T) {
+	r, _ := newTestResolver()
+	r.QueryBudget = 3
+	_, err := r.Resolve(g53.NameFromStringUnsafe("www.example.com."), g53.RR_A)
+	ut.Equal(t, err, ErrQueryBudgetExceeded)
+
+	r = New([]net.IP{net.ParseIP("10.0.0.100")}, newFakeNetwork())
+	_, err = r.Resolve(g53.NameFromStringUnsafe("www.example.com."), g53.RR_A)
+	ut.Equal(t, err, ErrNoServerAvailable)
+}
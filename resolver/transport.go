@@ -0,0 +1,53 @@
+package resolver
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+type Transport interface {
+	Exchange(server net.IP, query *g53.Message) (*g53.Message, error)
+}
+
+// NetTransport send query through udp, and retry through tcp if the
+// response is truncated
+type NetTransport struct {
+	Port    int
+	Timeout time.Duration
+}
+
+func NewNetTransport() *NetTransport {
+	return &NetTransport{
+		Port:    53,
+		Timeout: 2 * time.Second,
+	}
+}
+
+func (t *NetTransport) Exchange(server net.IP, query *g53.Message) (*g53.Message, error) {
+	query.RecalculateSectionRRCount()
+	render := g53.NewMsgRender()
+	query.Rend(render)
+	addr := net.JoinHostPort(server.String(), strconv.Itoa(t.Port))
+
+	data, err := util.UDPExchange(addr, render.Data(), t.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g53.MessageFromWire(util.NewInputBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Header.GetFlag(g53.FLAG_TC) {
+		if data, err = util.TCPExchange(addr, render.Data()); err != nil {
+			return nil, err
+		}
+		return g53.MessageFromWire(util.NewInputBuffer(data))
+	}
+	return resp, nil
+}
@@ -0,0 +1,275 @@
+package rpz
+
+import (
+	"net"
+
+	"github.com/zdnscloud/g53"
+)
+
+// Request is the information of a query which triggers depend on
+type Request struct {
+	Question *g53.Question
+	ClientIP net.IP
+	// tcp-only action only truncates response over udp
+	TCP bool
+	// name servers which answer the query, ns records in authority
+	// section of the response and their glue are also checked
+	NSNames []*g53.Name
+	NSIPs   []net.IP
+}
+
+// Match is the policy which applies to a query
+type Match struct {
+	Policy *Policy
+	// for qname trigger, it's the query name or one of the cname
+	// targets in answer section, otherwise it's the query name
+	Name *g53.Name
+	// cnames in answer before Name, they are kept after rewrite
+	cnames []*g53.RRset
+}
+
+// Engine checks policy zones in order, the first zone which has a
+// matched policy wins, in the same zone, triggers are checked in the
+// order of client ip, qname, response ip, nsdname and nsip
+type Engine struct {
+	zones []*Zone
+}
+
+func NewEngine(zones ...*Zone) *Engine {
+	return &Engine{
+		zones: zones,
+	}
+}
+
+// Match finds the policy for the query, response could be nil before the
+// query is resolved, then only client ip and qname triggers are checked
+func (e *Engine) Match(req *Request, response *g53.Message) *Match {
+	if req.Question == nil {
+		return nil
+	}
+
+	names, cnames := cnameChain(req.Question.Name, response)
+	var nsNames []*g53.Name
+	var nsIPs, answerIPs []net.IP
+	if response != nil {
+		answerIPs = addresses(response.Sections[g53.AnswerSection], nil)
+		nsNames, nsIPs = nameServers(req, response)
+	}
+
+	for _, z := range e.zones {
+		if req.ClientIP != nil {
+			if policy, _, ok := z.clientIPs.lookup(req.ClientIP); ok {
+				return &Match{Policy: policy, Name: req.Question.Name}
+			}
+		}
+
+		for i, name := range names {
+			if policy, ok := z.qnames.Policy(name); ok {
+				return &Match{Policy: policy, Name: name, cnames: cnames[:i]}
+			}
+		}
+
+		if policy := lookupIPs(z.responseIP, answerIPs); policy != nil {
+			return &Match{Policy: policy, Name: req.Question.Name}
+		}
+
+		for _, name := range nsNames {
+			if policy, ok := z.nsdnames.Policy(name); ok {
+				return &Match{Policy: policy, Name: req.Question.Name}
+			}
+		}
+
+		if policy := lookupIPs(z.nsIPs, nsIPs); policy != nil {
+			return &Match{Policy: policy, Name: req.Question.Name}
+		}
+	}
+	return nil
+}
+
+// Rewrite applies the matched policy to response, nil is returned if no
+// policy matches. For passthru, response isn't modified, for drop, it's
+// up to caller to discard the response. Like Match, response could be
+// nil, then the match is returned without rewriting
+func (e *Engine) Rewrite(req *Request, response *g53.Message) *Match {
+	m := e.Match(req, response)
+	if m == nil || response == nil {
+		return m
+	}
+
+	switch m.Policy.Action {
+	case ActionPassthru, ActionDrop:
+		return m
+	case ActionTCPOnly:
+		if req.TCP {
+			return m
+		}
+		clearSections(response)
+		response.Header.SetFlag(g53.FLAG_TC, true)
+	case ActionNXDomain:
+		setNegative(response, m, g53.R_NXDOMAIN)
+	case ActionNoData:
+		setNegative(response, m, g53.R_NOERROR)
+	case ActionLocalData:
+		setLocalData(req, response, m)
+	}
+
+	response.Header.SetFlag(g53.FLAG_AD, false)
+	response.RecalculateSectionRRCount()
+	return m
+}
+
+// edns is kept
+func clearSections(response *g53.Message) {
+	for i := 0; i < g53.SectionCount; i++ {
+		response.Sections[i] = nil
+	}
+}
+
+func setNegative(response *g53.Message, m *Match, rcode g53.Rcode) {
+	clearSections(response)
+	response.Header.Rcode = rcode
+	if len(m.cnames) > 0 {
+		response.Sections[g53.AnswerSection] = append(g53.Section(nil), m.cnames...)
+	}
+	if soa := m.Policy.Zone.soa; soa != nil {
+		response.Sections[g53.AuthSection] = g53.Section{soa}
+	}
+}
+
+func setLocalData(req *Request, response *g53.Message, m *Match) {
+	var answer g53.Section
+	for _, rrset := range m.Policy.RRsets {
+		if rrset.Type == g53.RR_CNAME {
+			target := rrset.Rdatas[0].(*g53.CName).Name
+			if target.IsWildCard() {
+				suffix, _ := target.StripLeft(1)
+				target, _ = m.Name.Concat(suffix)
+			}
+			answer = append(answer, &g53.RRset{
+				Name:   m.Name,
+				Type:   g53.RR_CNAME,
+				Class:  rrset.Class,
+				Ttl:    rrset.Ttl,
+				Rdatas: []g53.Rdata{&g53.CName{Name: target}},
+			})
+		} else if rrset.Type == req.Question.Type || req.Question.Type == g53.RR_ANY {
+			answer = append(answer, &g53.RRset{
+				Name:   m.Name,
+				Type:   rrset.Type,
+				Class:  rrset.Class,
+				Ttl:    rrset.Ttl,
+				Rdatas: append([]g53.Rdata(nil), rrset.Rdatas...),
+			})
+		}
+	}
+
+	if len(answer) == 0 {
+		setNegative(response, m, g53.R_NOERROR)
+		return
+	}
+
+	clearSections(response)
+	response.Header.Rcode = g53.R_NOERROR
+	response.Sections[g53.AnswerSection] = append(append(g53.Section(nil), m.cnames...), answer...)
+}
+
+// cnameChain returns the query name and the cname targets in answer
+// section, and the cname rrsets which lead to each of the names
+func cnameChain(qname *g53.Name, response *g53.Message) ([]*g53.Name, []*g53.RRset) {
+	names := []*g53.Name{qname}
+	var cnames []*g53.RRset
+	if response == nil {
+		return names, cnames
+	}
+
+	answer := response.Sections[g53.AnswerSection]
+	for len(cnames) < len(answer) {
+		var cname *g53.RRset
+		for _, rrset := range answer {
+			if rrset.Type == g53.RR_CNAME && rrset.Name.Equals(names[len(names)-1]) {
+				cname = rrset
+				break
+			}
+		}
+		if cname == nil {
+			break
+		}
+		cnames = append(cnames, cname)
+		names = append(names, cname.Rdatas[0].(*g53.CName).Name)
+	}
+	return names, cnames
+}
+
+// addresses of a and aaaa records, if owners isn't nil, only records
+// whose owner is in owners are returned
+func addresses(section g53.Section, owners []*g53.Name) []net.IP {
+	var ips []net.IP
+	for _, rrset := range section {
+		if rrset.Type != g53.RR_A && rrset.Type != g53.RR_AAAA {
+			continue
+		}
+		if owners != nil && containsName(owners, rrset.Name) == false {
+			continue
+		}
+		for _, rdata := range rrset.Rdatas {
+			switch addr := rdata.(type) {
+			case *g53.A:
+				ips = append(ips, addr.Host)
+			case *g53.AAAA:
+				ips = append(ips, addr.Host)
+			}
+		}
+	}
+	return ips
+}
+
+func nameServers(req *Request, response *g53.Message) ([]*g53.Name, []net.IP) {
+	names := append([]*g53.Name(nil), req.NSNames...)
+	var nsInResponse []*g53.Name
+	for _, rrset := range response.Sections[g53.AuthSection] {
+		if rrset.Type != g53.RR_NS {
+			continue
+		}
+		for _, rdata := range rrset.Rdatas {
+			nsInResponse = append(nsInResponse, rdata.(*g53.NS).Name)
+		}
+	}
+	names = append(names, nsInResponse...)
+
+	ips := append([]net.IP(nil), req.NSIPs...)
+	if len(nsInResponse) > 0 {
+		ips = append(ips, addresses(response.Sections[g53.AdditionalSection], nsInResponse)...)
+	}
+	return names, ips
+}
+
+func containsName(names []*g53.Name, name *g53.Name) bool {
+	for _, n := range names {
+		if n.Equals(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// the longest prefix wins, ipv4 prefix is compared as ipv4-mapped ipv6
+// prefix, so its length is added by 96
+func lookupIPs(trie *ipTrie, ips []net.IP) *Policy {
+	var matched *Policy
+	longest := -1
+	for _, ip := range ips {
+		policy, prefixLen, ok := trie.lookup(ip)
+		if ok == false {
+			continue
+		}
+		if ip.To4() != nil {
+			prefixLen += ipv4PrefixOffset
+		}
+		if prefixLen > longest {
+			matched, longest = policy, prefixLen
+		}
+	}
+	return matched
+}
+
+const ipv4PrefixOffset = 96
@@ -0,0 +1,68 @@
+package rpz
+
+import (
+	"net"
+)
+
+// ipTrie is a binary trie of ip trigger prefixes, lookup returns the
+// policy of the longest prefix which contains the address
+type ipTrie struct {
+	v4 ipTrieNode
+	v6 ipTrieNode
+}
+
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	policy   *Policy
+}
+
+func newIPTrie() *ipTrie {
+	return &ipTrie{}
+}
+
+func bitAt(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+func (t *ipTrie) root(ip net.IP) (*ipTrieNode, net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &t.v4, ip4
+	}
+	if ip16 := ip.To16(); ip16 != nil {
+		return &t.v6, ip16
+	}
+	return nil, nil
+}
+
+// prefix is generated by parseIPTrigger, so ip length matches mask
+func (t *ipTrie) insert(prefix *net.IPNet, policy *Policy) {
+	n, ip := t.root(prefix.IP)
+	ones, _ := prefix.Mask.Size()
+	for i := 0; i < ones; i++ {
+		bit := bitAt(ip, i)
+		if n.children[bit] == nil {
+			n.children[bit] = &ipTrieNode{}
+		}
+		n = n.children[bit]
+	}
+	n.policy = policy
+}
+
+func (t *ipTrie) lookup(ip net.IP) (*Policy, int, bool) {
+	n, ip := t.root(ip)
+	if n == nil {
+		return nil, 0, false
+	}
+
+	policy, prefixLen := n.policy, 0
+	for i := 0; i < len(ip)*8; i++ {
+		n = n.children[bitAt(ip, i)]
+		if n == nil {
+			break
+		}
+		if n.policy != nil {
+			policy, prefixLen = n.policy, i+1
+		}
+	}
+	return policy, prefixLen, policy != nil
+}
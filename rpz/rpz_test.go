@@ -0,0 +1,333 @@
+package rpz
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+const testPolicyZone = `
+rpz. 300 IN SOA ns.rpz. admin.rpz. 1 3600 600 86400 60
+rpz. 300 IN NS ns.rpz.
+; qname triggers
+bad.example.com.rpz. 60 IN CNAME .
+*.bad.example.com.rpz. 60 IN CNAME .
+nodata.example.com.rpz. 60 IN CNAME *.
+ok.bad.example.com.rpz. 60 IN CNAME rpz-passthru.
+drop.example.com.rpz. 60 IN CNAME rpz-drop.
+tcp.example.com.rpz. 60 IN CNAME rpz-tcp-only.
+local.example.com.rpz. 60 IN A 10.0.0.1
+local.example.com.rpz. 60 IN A 10.0.0.2
+local.example.com.rpz. 60 IN TXT "blocked"
+*.garden.example.com.rpz. 60 IN CNAME *.walled.example.net.
+redirect.example.com.rpz. 60 IN CNAME walled.example.net.
+; client ip triggers
+32.1.2.0.192.rpz-client-ip.rpz. 60 IN CNAME rpz-drop.
+; response ip triggers
+24.0.113.0.203.rpz-ip.rpz. 60 IN CNAME .
+32.5.113.0.203.rpz-ip.rpz. 60 IN CNAME rpz-passthru.
+48.zz.1.db8.2001.rpz-ip.rpz. 60 IN CNAME *.
+; ns triggers
+ns.evil.net.rpz-nsdname.rpz. 60 IN CNAME .
+*.evil.org.rpz-nsdname.rpz. 60 IN CNAME *.
+24.0.100.51.198.rpz-nsip.rpz. 60 IN CNAME .
+`
+
+func rrsetFromString(s string) *g53.RRset {
+	rrset, err := g53.RRsetFromString(s)
+	if err != nil {
+		panic("invalid rrset " + s)
+	}
+	return rrset
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	z, err := LoadZone(g53.NameFromStringUnsafe("rpz"), strings.NewReader(testPolicyZone))
+	ut.Assert(t, err == nil, "load zone failed:%v", err)
+	return NewEngine(z)
+}
+
+func newRequest(name string, typ g53.RRType) *Request {
+	return &Request{
+		Question: &g53.Question{
+			Name:  g53.NameFromStringUnsafe(name),
+			Type:  typ,
+			Class: g53.CLASS_IN,
+		},
+		ClientIP: net.ParseIP("198.51.100.53"),
+	}
+}
+
+func newResponse(req *Request, answers ...string) *g53.Message {
+	query := g53.MakeQuery(req.Question.Name, req.Question.Type, 1232, false)
+	response := query.MakeResponse()
+	response.Edns = query.Edns
+	addRRs(response, g53.AnswerSection, answers...)
+	response.Header.SetFlag(g53.FLAG_AD, true)
+	return response
+}
+
+func addRRs(m *g53.Message, st g53.SectionType, rrs ...string) {
+	for _, rr := range rrs {
+		m.AddRRset(st, rrsetFromString(rr))
+	}
+	m.RecalculateSectionRRCount()
+}
+
+func answerString(m *g53.Message) []string {
+	var rrs []string
+	for _, rrset := range m.Sections[g53.AnswerSection] {
+		for _, rdata := range rrset.Rdatas {
+			rrs = append(rrs, rrset.Name.String(true)+" "+rrset.Type.String()+" "+rdata.String())
+		}
+	}
+	return rrs
+}
+
+func TestParseIPTrigger(t *testing.T) {
+	for _, c := range []struct {
+		trigger string
+		prefix  string
+	}{
+		{"32.1.2.0.192", "192.0.2.1/32"},
+		{"8.0.0.0.10", "10.0.0.0/8"},
+		{"128.1.zz.db8.2001", "2001:db8::1/128"},
+		{"48.zz.1.db8.2001", "2001:db8:1::/48"},
+		{"64.zz.1.db8.2001", "2001:db8:1::/64"},
+		{"128.1.zz", "::1/128"},
+		{"0.zz", "::/0"},
+	} {
+		prefix, err := parseIPTrigger(strings.Split(c.trigger, "."))
+		ut.Equal(t, err, nil)
+		_, expected, _ := net.ParseCIDR(c.prefix)
+		ut.Equal(t, prefix.String(), expected.String())
+	}
+
+	for _, trigger := range []string{"33.1.2.0.192", "24.1.2.0.192", "a.1.2.0.192", "32", "16.zz.1.zz.2001"} {
+		_, err := parseIPTrigger(strings.Split(trigger, "."))
+		ut.Equal(t, err, ErrInvalidIPTrigger)
+	}
+}
+
+func TestLoadInvalidZone(t *testing.T) {
+	origin := g53.NameFromStringUnsafe("rpz")
+	_, err := LoadZone(origin, strings.NewReader("a.rpz. 60 IN CNAME .\na.rpz. 60 IN A 1.1.1.1"))
+	ut.Assert(t, err != nil, "cname with other data should fail")
+	_, err = LoadZone(origin, strings.NewReader("24.1.2.0.192.rpz-ip.rpz. 60 IN CNAME ."))
+	ut.Assert(t, err != nil, "ip trigger with host bits should fail")
+	_, err = LoadZone(origin, strings.NewReader("a.example.com. 60 IN CNAME ."))
+	ut.Assert(t, err != nil, "out of zone record should fail")
+	_, err = LoadZone(origin, strings.NewReader("a.rpz. 60 IN"))
+	ut.Assert(t, err != nil && strings.HasPrefix(err.Error(), "line 1:"), "invalid rr should fail")
+}
+
+func TestLoadZoneMerge(t *testing.T) {
+	origin := g53.NameFromStringUnsafe("rpz")
+	z, err := LoadZone(origin, strings.NewReader(`
+a.rpz. 60 IN A 10.0.0.1
+b.rpz. 60 IN A 10.0.0.3
+A.rpz. 60 IN A 10.0.0.2
+a.rpz. 60 IN TXT "blocked"`))
+	ut.Equal(t, err, nil)
+
+	e := NewEngine(z)
+	req := newRequest("a", g53.RR_A)
+	response := newResponse(req)
+	e.Rewrite(req, response)
+	ut.Equal(t, answerString(response), []string{"a A 10.0.0.1", "a A 10.0.0.2"})
+
+	req = newRequest("b", g53.RR_A)
+	response = newResponse(req)
+	e.Rewrite(req, response)
+	ut.Equal(t, answerString(response), []string{"b A 10.0.0.3"})
+}
+
+func TestQNameTrigger(t *testing.T) {
+	e := newTestEngine(t)
+	for _, c := range []struct {
+		name   string
+		action Action
+		match  bool
+	}{
+		{"bad.example.com", ActionNXDomain, true},
+		{"www.bad.example.com", ActionNXDomain, true},
+		{"ok.bad.example.com", ActionPassthru, true},
+		{"nodata.example.com", ActionNoData, true},
+		{"www.nodata.example.com", 0, false},
+		{"good.example.com", 0, false},
+	} {
+		m := e.Match(newRequest(c.name, g53.RR_A), nil)
+		ut.Equal(t, m != nil, c.match)
+		if c.match {
+			ut.Equal(t, m.Policy.Action, c.action)
+			ut.Equal(t, m.Policy.Trigger, TriggerQName)
+		}
+	}
+}
+
+func TestRewriteNegative(t *testing.T) {
+	e := newTestEngine(t)
+	req := newRequest("www.bad.example.com", g53.RR_A)
+	response := newResponse(req, "www.bad.example.com. 300 IN A 192.0.2.10")
+	m := e.Rewrite(req, response)
+	ut.Equal(t, m.Policy.Action, ActionNXDomain)
+	ut.Equal(t, response.Header.Rcode, g53.R_NXDOMAIN)
+	ut.Equal(t, response.Header.ANCount, uint16(0))
+	ut.Equal(t, response.Header.NSCount, uint16(1))
+	ut.Equal(t, response.Sections[g53.AuthSection][0].Type, g53.RR_SOA)
+	ut.Equal(t, response.Header.GetFlag(g53.FLAG_AD), false)
+	ut.Assert(t, response.Edns != nil, "edns should be kept")
+
+	//cname target triggers the policy, cname before it is kept
+	req = newRequest("alias.example.com", g53.RR_A)
+	response = newResponse(req,
+		"alias.example.com. 300 IN CNAME nodata.example.com.",
+		"nodata.example.com. 300 IN A 192.0.2.10")
+	m = e.Rewrite(req, response)
+	ut.Equal(t, m.Name.String(true), "nodata.example.com")
+	ut.Equal(t, response.Header.Rcode, g53.R_NOERROR)
+	ut.Equal(t, answerString(response), []string{"alias.example.com CNAME nodata.example.com."})
+}
+
+func TestRewriteLocalData(t *testing.T) {
+	e := newTestEngine(t)
+	req := newRequest("local.example.com", g53.RR_A)
+	response := newResponse(req, "local.example.com. 300 IN A 192.0.2.10")
+	m := e.Rewrite(req, response)
+	ut.Equal(t, m.Policy.Action, ActionLocalData)
+	ut.Equal(t, answerString(response), []string{"local.example.com A 10.0.0.1", "local.example.com A 10.0.0.2"})
+	ut.Equal(t, response.Header.ANCount, uint16(2))
+
+	req = newRequest("local.example.com", g53.RR_AAAA)
+	response = newResponse(req)
+	e.Rewrite(req, response)
+	ut.Equal(t, response.Header.Rcode, g53.R_NOERROR)
+	ut.Equal(t, response.Header.ANCount, uint16(0))
+	ut.Equal(t, response.Header.NSCount, uint16(1))
+
+	req = newRequest("a.garden.example.com", g53.RR_A)
+	response = newResponse(req)
+	e.Rewrite(req, response)
+	ut.Equal(t, answerString(response), []string{"a.garden.example.com CNAME a.garden.example.com.walled.example.net."})
+
+	req = newRequest("redirect.example.com", g53.RR_A)
+	response = newResponse(req)
+	e.Rewrite(req, response)
+	ut.Equal(t, answerString(response), []string{"redirect.example.com CNAME walled.example.net."})
+
+	render := g53.NewMsgRender()
+	response.Rend(render)
+	_, err := g53.MessageFromWire(util.NewInputBuffer(render.Data()))
+	ut.Equal(t, err, nil)
+}
+
+func TestRewriteWithoutResponse(t *testing.T) {
+	e := newTestEngine(t)
+	for _, c := range []struct {
+		name   string
+		action Action
+	}{
+		{"bad.example.com", ActionNXDomain},
+		{"nodata.example.com", ActionNoData},
+		{"tcp.example.com", ActionTCPOnly},
+		{"local.example.com", ActionLocalData},
+	} {
+		m := e.Rewrite(newRequest(c.name, g53.RR_A), nil)
+		ut.Equal(t, m.Policy.Action, c.action)
+	}
+	ut.Assert(t, e.Rewrite(newRequest("good.example.com", g53.RR_A), nil) == nil, "good name shouldn't match")
+}
+
+func TestRewriteDropAndTCPOnly(t *testing.T) {
+	e := newTestEngine(t)
+	req := newRequest("drop.example.com", g53.RR_A)
+	response := newResponse(req, "drop.example.com. 300 IN A 192.0.2.10")
+	m := e.Rewrite(req, response)
+	ut.Equal(t, m.Policy.Action, ActionDrop)
+	ut.Equal(t, response.Header.ANCount, uint16(1))
+
+	req = newRequest("tcp.example.com", g53.RR_A)
+	response = newResponse(req, "tcp.example.com. 300 IN A 192.0.2.10")
+	e.Rewrite(req, response)
+	ut.Equal(t, response.Header.GetFlag(g53.FLAG_TC), true)
+	ut.Equal(t, response.Header.ANCount, uint16(0))
+
+	req.TCP = true
+	response = newResponse(req, "tcp.example.com. 300 IN A 192.0.2.10")
+	e.Rewrite(req, response)
+	ut.Equal(t, response.Header.GetFlag(g53.FLAG_TC), false)
+	ut.Equal(t, response.Header.ANCount, uint16(1))
+}
+
+func TestIPTriggers(t *testing.T) {
+	e := newTestEngine(t)
+
+	//client ip trigger has the highest precedence
+	req := newRequest("bad.example.com", g53.RR_A)
+	req.ClientIP = net.ParseIP("192.0.2.1")
+	m := e.Match(req, nil)
+	ut.Equal(t, m.Policy.Trigger, TriggerClientIP)
+	ut.Equal(t, m.Policy.Action, ActionDrop)
+
+	req = newRequest("www.example.com", g53.RR_A)
+	response := newResponse(req, "www.example.com. 300 IN A 203.0.113.1")
+	m = e.Rewrite(req, response)
+	ut.Equal(t, m.Policy.Trigger, TriggerResponseIP)
+	ut.Equal(t, response.Header.Rcode, g53.R_NXDOMAIN)
+
+	//longest prefix wins
+	response = newResponse(req, "www.example.com. 300 IN A 203.0.113.5")
+	m = e.Rewrite(req, response)
+	ut.Equal(t, m.Policy.Action, ActionPassthru)
+	ut.Equal(t, response.Header.ANCount, uint16(1))
+
+	req = newRequest("www.example.com", g53.RR_AAAA)
+	response = newResponse(req, "www.example.com. 300 IN AAAA 2001:db8:1::1")
+	m = e.Rewrite(req, response)
+	ut.Equal(t, m.Policy.Action, ActionNoData)
+
+	response = newResponse(req, "www.example.com. 300 IN AAAA 2001:db8:2::1")
+	ut.Assert(t, e.Rewrite(req, response) == nil, "address out of prefix shouldn't match")
+}
+
+func TestNSTriggers(t *testing.T) {
+	e := newTestEngine(t)
+	req := newRequest("www.example.com", g53.RR_A)
+	response := newResponse(req, "www.example.com. 300 IN A 192.0.2.10")
+	addRRs(response, g53.AuthSection, "example.com. 300 IN NS ns.evil.net.")
+	m := e.Match(req, response)
+	ut.Equal(t, m.Policy.Trigger, TriggerNSDName)
+	ut.Equal(t, m.Policy.Action, ActionNXDomain)
+
+	req.NSNames = []*g53.Name{g53.NameFromStringUnsafe("ns1.evil.org")}
+	response = newResponse(req, "www.example.com. 300 IN A 192.0.2.10")
+	m = e.Match(req, response)
+	ut.Equal(t, m.Policy.Action, ActionNoData)
+
+	req.NSNames = nil
+	response = newResponse(req, "www.example.com. 300 IN A 192.0.2.10")
+	addRRs(response, g53.AuthSection, "example.com. 300 IN NS ns1.example.com.")
+	addRRs(response, g53.AdditionalSection, "ns1.example.com. 300 IN A 198.51.100.1")
+	m = e.Match(req, response)
+	ut.Equal(t, m.Policy.Trigger, TriggerNSIP)
+
+	//qname is checked before ns triggers
+	req = newRequest("local.example.com", g53.RR_A)
+	response = newResponse(req)
+	addRRs(response, g53.AuthSection, "example.com. 300 IN NS ns.evil.net.")
+	m = e.Match(req, response)
+	ut.Equal(t, m.Policy.Trigger, TriggerQName)
+}
+
+func TestZonePrecedence(t *testing.T) {
+	first, _ := LoadZone(g53.NameFromStringUnsafe("first"), strings.NewReader("*.example.com.first. 60 IN CNAME rpz-passthru."))
+	second, _ := LoadZone(g53.NameFromStringUnsafe("second"), strings.NewReader("bad.example.com.second. 60 IN CNAME ."))
+	e := NewEngine(first, second)
+	m := e.Match(newRequest("bad.example.com", g53.RR_A), nil)
+	ut.Equal(t, m.Policy.Zone.Origin().String(true), "first")
+	ut.Equal(t, m.Policy.Action, ActionPassthru)
+}
@@ -0,0 +1,325 @@
+package rpz
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/domaintree"
+)
+
+var (
+	ErrCNAMEWithOtherData = errors.New("cname coexists with other data")
+	ErrInvalidIPTrigger   = errors.New("invalid ip trigger")
+)
+
+type Action int
+
+const (
+	ActionNXDomain Action = iota
+	ActionNoData
+	ActionPassthru
+	ActionDrop
+	ActionTCPOnly
+	// answer with the records of the policy, including cname
+	ActionLocalData
+)
+
+var actionNames = []string{"NXDOMAIN", "NODATA", "PASSTHRU", "DROP", "TCP-ONLY", "LOCAL-DATA"}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// Trigger is sorted by its precedence in the same policy zone
+type Trigger int
+
+const (
+	TriggerClientIP Trigger = iota
+	TriggerQName
+	TriggerResponseIP
+	TriggerNSDName
+	TriggerNSIP
+)
+
+var triggerNames = []string{"CLIENT-IP", "QNAME", "IP", "NSDNAME", "NSIP"}
+
+func (t Trigger) String() string {
+	return triggerNames[t]
+}
+
+const (
+	clientIPLabel = "rpz-client-ip"
+	ipLabel       = "rpz-ip"
+	nsdnameLabel  = "rpz-nsdname"
+	nsipLabel     = "rpz-nsip"
+)
+
+var (
+	nxdomainTarget = g53.Root
+	nodataTarget   = g53.NameFromStringUnsafe("*")
+	passthruTarget = g53.NameFromStringUnsafe("rpz-passthru")
+	dropTarget     = g53.NameFromStringUnsafe("rpz-drop")
+	tcpOnlyTarget  = g53.NameFromStringUnsafe("rpz-tcp-only")
+)
+
+// Policy is the records with the same owner in policy zone
+type Policy struct {
+	Zone    *Zone
+	Owner   *g53.Name
+	Trigger Trigger
+	Action  Action
+	// local data, owner of the rrsets is the policy owner
+	RRsets []*g53.RRset
+}
+
+func (p *Policy) String() string {
+	return fmt.Sprintf("%s %s %s", p.Owner.String(false), p.Trigger.String(), p.Action.String())
+}
+
+// Zone is a loaded policy zone, it's read only once created
+type Zone struct {
+	origin     *g53.Name
+	soa        *g53.RRset
+	qnames     *domaintree.Matcher[*Policy]
+	nsdnames   *domaintree.Matcher[*Policy]
+	clientIPs  *ipTrie
+	responseIP *ipTrie
+	nsIPs      *ipTrie
+}
+
+// NewZone creates policy zone from all the rrsets of the zone, records
+// of the apex are ignored except soa, which is added to negative answer
+func NewZone(origin *g53.Name, rrsets []*g53.RRset) (*Zone, error) {
+	z := &Zone{
+		origin:     origin,
+		qnames:     domaintree.NewMatcher[*Policy](),
+		nsdnames:   domaintree.NewMatcher[*Policy](),
+		clientIPs:  newIPTrie(),
+		responseIP: newIPTrie(),
+		nsIPs:      newIPTrie(),
+	}
+
+	var owners []*g53.Name
+	rrsetsByOwner := make(map[string][]*g53.RRset)
+	for _, rrset := range rrsets {
+		if rrset.Name.Equals(origin) {
+			if rrset.Type == g53.RR_SOA {
+				z.soa = rrset
+			}
+			continue
+		}
+
+		if rrset.Name.Compare(origin, false).Relation != g53.SUBDOMAIN {
+			return nil, fmt.Errorf("%s is out of zone %s", rrset.Name.String(false), origin.String(false))
+		}
+
+		key := rrset.Name.String(false)
+		if _, ok := rrsetsByOwner[key]; ok == false {
+			owners = append(owners, rrset.Name)
+		}
+		rrsetsByOwner[key] = append(rrsetsByOwner[key], rrset)
+	}
+
+	for _, owner := range owners {
+		if err := z.addPolicy(owner, rrsetsByOwner[owner.String(false)]); err != nil {
+			return nil, fmt.Errorf("policy %s is invalid: %s", owner.String(false), err.Error())
+		}
+	}
+	return z, nil
+}
+
+// LoadZone reads one rr per line in the format of g53.RRsetFromString,
+// rrs with same name and type are merged, empty line and line starts
+// with ";" are ignored
+func LoadZone(origin *g53.Name, r io.Reader) (*Zone, error) {
+	var rrsets []*g53.RRset
+	rrsetsByKey := make(map[string]*g53.RRset)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum += 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' {
+			continue
+		}
+
+		rrset, err := g53.RRsetFromString(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s is invalid: %s", lineNum, line, err.Error())
+		}
+
+		key := strings.ToLower(rrset.Name.String(false)) + " " + rrset.Type.String()
+		if existing, ok := rrsetsByKey[key]; ok {
+			existing.AddRdata(rrset.Rdatas[0])
+		} else {
+			rrsetsByKey[key] = rrset
+			rrsets = append(rrsets, rrset)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewZone(origin, rrsets)
+}
+
+func (z *Zone) Origin() *g53.Name {
+	return z.origin
+}
+
+func (z *Zone) addPolicy(owner *g53.Name, rrsets []*g53.RRset) error {
+	policy := &Policy{
+		Zone:   z,
+		Owner:  owner,
+		RRsets: rrsets,
+		Action: ActionLocalData,
+	}
+
+	for _, rrset := range rrsets {
+		if rrset.Type != g53.RR_CNAME {
+			continue
+		}
+		if len(rrsets) != 1 {
+			return ErrCNAMEWithOtherData
+		}
+		policy.Action = cnameAction(rrset.Rdatas[0].(*g53.CName).Name)
+		if policy.Action != ActionLocalData {
+			policy.RRsets = nil
+		}
+	}
+
+	relative, _ := owner.Subtract(z.origin)
+	labels := strings.Split(relative.String(true), ".")
+	switch labels[len(labels)-1] {
+	case clientIPLabel:
+		policy.Trigger = TriggerClientIP
+		return addIPPolicy(z.clientIPs, labels[:len(labels)-1], policy)
+	case ipLabel:
+		policy.Trigger = TriggerResponseIP
+		return addIPPolicy(z.responseIP, labels[:len(labels)-1], policy)
+	case nsipLabel:
+		policy.Trigger = TriggerNSIP
+		return addIPPolicy(z.nsIPs, labels[:len(labels)-1], policy)
+	case nsdnameLabel:
+		policy.Trigger = TriggerNSDName
+		name, _ := relative.StripRight(1)
+		addNamePolicy(z.nsdnames, name, policy)
+	default:
+		policy.Trigger = TriggerQName
+		addNamePolicy(z.qnames, relative, policy)
+	}
+	return nil
+}
+
+func cnameAction(target *g53.Name) Action {
+	switch {
+	case target.Equals(nxdomainTarget):
+		return ActionNXDomain
+	case target.Equals(nodataTarget):
+		return ActionNoData
+	case target.Equals(passthruTarget):
+		return ActionPassthru
+	case target.Equals(dropTarget):
+		return ActionDrop
+	case target.Equals(tcpOnlyTarget):
+		return ActionTCPOnly
+	default:
+		return ActionLocalData
+	}
+}
+
+func addNamePolicy(matcher *domaintree.Matcher[*Policy], name *g53.Name, policy *Policy) {
+	rule := &domaintree.Rule[*Policy]{
+		Name:   name,
+		Type:   domaintree.MatchExact,
+		Policy: policy,
+	}
+	if name.IsWildCard() {
+		rule.Name, _ = name.StripLeft(1)
+		rule.Type = domaintree.MatchSubdomain
+	}
+	matcher.AddRule(rule)
+}
+
+func addIPPolicy(trie *ipTrie, labels []string, policy *Policy) error {
+	prefix, err := parseIPTrigger(labels)
+	if err != nil {
+		return err
+	}
+	trie.insert(prefix, policy)
+	return nil
+}
+
+// ip trigger is prefix length followed by reversed address, ipv4 is in
+// decimal octets, ipv6 is in hex groups with "zz" for the longest run
+// of zero groups, 24.0.2.0.192 is 192.0.2.0/24, 32.zz.db8.2001 is
+// 2001:db8::/32
+func parseIPTrigger(labels []string) (*net.IPNet, error) {
+	if len(labels) < 2 {
+		return nil, ErrInvalidIPTrigger
+	}
+
+	prefixLen, err := strconv.Atoi(labels[0])
+	if err != nil || prefixLen < 0 {
+		return nil, ErrInvalidIPTrigger
+	}
+
+	addr := labels[1:]
+	if len(addr) == net.IPv4len && prefixLen <= net.IPv4len*8 {
+		if ip, ok := parseReversedIPv4(addr); ok {
+			return ipPrefix(ip, prefixLen)
+		}
+	}
+
+	if prefixLen > net.IPv6len*8 {
+		return nil, ErrInvalidIPTrigger
+	}
+
+	groups := make([]string, len(addr))
+	for i, label := range addr {
+		if label == "zz" {
+			label = ""
+		}
+		groups[len(addr)-1-i] = label
+	}
+	s := strings.Join(groups, ":")
+	if s == "" {
+		s = "::"
+	} else if strings.HasPrefix(s, ":") {
+		s = ":" + s
+	}
+	if strings.HasSuffix(s, ":") && strings.HasSuffix(s, "::") == false {
+		s = s + ":"
+	}
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() != nil {
+		return nil, ErrInvalidIPTrigger
+	}
+	return ipPrefix(ip, prefixLen)
+}
+
+func parseReversedIPv4(labels []string) (net.IP, bool) {
+	ip := make(net.IP, net.IPv4len)
+	for i, label := range labels {
+		octet, err := strconv.ParseUint(label, 10, 8)
+		if err != nil {
+			return nil, false
+		}
+		ip[net.IPv4len-1-i] = byte(octet)
+	}
+	return ip, true
+}
+
+func ipPrefix(ip net.IP, prefixLen int) (*net.IPNet, error) {
+	mask := net.CIDRMask(prefixLen, len(ip)*8)
+	if ip.Mask(mask).Equal(ip) == false {
+		return nil, ErrInvalidIPTrigger
+	}
+	return &net.IPNet{IP: ip, Mask: mask}, nil
+}
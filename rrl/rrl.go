@@ -0,0 +1,313 @@
+package rrl
+
+import (
+	"container/list"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/g53"
+)
+
+// Class is the category of response, each category of a client prefix
+// is accounted separately
+type Class uint8
+
+const (
+	ClassAnswer Class = iota
+	ClassNXDomain
+	ClassError
+	ClassReferral
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassAnswer:
+		return "answer"
+	case ClassNXDomain:
+		return "nxdomain"
+	case ClassError:
+		return "error"
+	case ClassReferral:
+		return "referral"
+	default:
+		return "unknown"
+	}
+}
+
+type Action uint8
+
+const (
+	// send the response as is
+	ActionSend Action = iota
+	// drop the response
+	ActionDrop
+	// send a truncated response, so legitimate client could retry over tcp
+	ActionSlip
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionSend:
+		return "send"
+	case ActionDrop:
+		return "drop"
+	case ActionSlip:
+		return "slip"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	DefaultWindow        = 15 * time.Second
+	DefaultSlip          = 2
+	DefaultIPv4PrefixLen = 24
+	DefaultIPv6PrefixLen = 56
+	DefaultMaxEntries    = 100000
+)
+
+// Logger is called for each limited response, in log-only mode, action
+// is what would be done
+type Logger func(client net.IP, query *g53.Message, class Class, action Action)
+
+// Limiter implements response rate limiting, responses to clients in
+// the same prefix are accounted by class and name. Answers are keyed by
+// qname and qtype, nxdomains by the zone in soa, referrals by the
+// delegation, errors by the prefix only. It should only be used for udp
+// responses
+type Limiter struct {
+	// responses per second of each class, 0 means no limit
+	ResponsesPerSecond int
+	NXDomainsPerSecond int
+	ErrorsPerSecond    int
+	ReferralsPerSecond int
+	// how long the debt of a limited client lasts, tokens of a bucket
+	// won't go below -rate*window
+	Window time.Duration
+	// every slip-th limited response is sent truncated instead of
+	// dropped, 0 means always drop, 1 means always slip
+	Slip          int
+	IPv4PrefixLen int
+	IPv6PrefixLen int
+	// least recently used bucket is removed when the table is full
+	MaxEntries int
+	// count and log limited responses but send them anyway
+	LogOnly bool
+	Logger  Logger
+
+	lock    sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+	now     func() time.Time
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	update time.Time
+	limits int
+}
+
+// New creates limiter with rate of all classes set to responsesPerSecond
+func New(responsesPerSecond int) *Limiter {
+	return &Limiter{
+		ResponsesPerSecond: responsesPerSecond,
+		NXDomainsPerSecond: responsesPerSecond,
+		ErrorsPerSecond:    responsesPerSecond,
+		ReferralsPerSecond: responsesPerSecond,
+		Window:             DefaultWindow,
+		Slip:               DefaultSlip,
+		IPv4PrefixLen:      DefaultIPv4PrefixLen,
+		IPv6PrefixLen:      DefaultIPv6PrefixLen,
+		MaxEntries:         DefaultMaxEntries,
+		buckets:            make(map[string]*list.Element),
+		lru:                list.New(),
+		now:                time.Now,
+	}
+}
+
+func (l *Limiter) rate(class Class) int {
+	switch class {
+	case ClassNXDomain:
+		return l.NXDomainsPerSecond
+	case ClassError:
+		return l.ErrorsPerSecond
+	case ClassReferral:
+		return l.ReferralsPerSecond
+	default:
+		return l.ResponsesPerSecond
+	}
+}
+
+// Classify returns the class of response and the name it's accounted by
+func Classify(query, response *g53.Message) (Class, *g53.Name) {
+	switch response.Header.Rcode {
+	case g53.R_NOERROR:
+	case g53.R_NXDOMAIN:
+		if soa := findRRset(response.Sections[g53.AuthSection], g53.RR_SOA); soa != nil {
+			return ClassNXDomain, soa.Name
+		}
+		return ClassNXDomain, questionName(query)
+	default:
+		return ClassError, nil
+	}
+
+	if len(response.Sections[g53.AnswerSection]) == 0 && response.Header.GetFlag(g53.FLAG_AA) == false {
+		if ns := findRRset(response.Sections[g53.AuthSection], g53.RR_NS); ns != nil {
+			return ClassReferral, ns.Name
+		}
+	}
+	return ClassAnswer, questionName(query)
+}
+
+func findRRset(section g53.Section, typ g53.RRType) *g53.RRset {
+	for _, rrset := range section {
+		if rrset.Type == typ {
+			return rrset
+		}
+	}
+	return nil
+}
+
+func questionName(query *g53.Message) *g53.Name {
+	if query.Question == nil {
+		return nil
+	}
+	return query.Question.Name
+}
+
+func (l *Limiter) makeKey(client net.IP, class Class, name *g53.Name, typ g53.RRType) string {
+	var prefix net.IP
+	if ip4 := client.To4(); ip4 != nil {
+		prefix = ip4.Mask(net.CIDRMask(l.IPv4PrefixLen, net.IPv4len*8))
+	} else {
+		prefix = client.To16().Mask(net.CIDRMask(l.IPv6PrefixLen, net.IPv6len*8))
+	}
+
+	var b strings.Builder
+	b.Write(prefix)
+	b.WriteByte(byte(class))
+	if class == ClassAnswer {
+		b.WriteByte(byte(typ >> 8))
+		b.WriteByte(byte(typ))
+	}
+	if name != nil {
+		b.WriteString(strings.ToLower(name.String(false)))
+	}
+	return b.String()
+}
+
+// Check accounts the response and returns what should be done with it,
+// in log-only mode, ActionSend is always returned
+func (l *Limiter) Check(client net.IP, query, response *g53.Message) Action {
+	class, name := Classify(query, response)
+	rate := l.rate(class)
+	if rate <= 0 || client == nil {
+		return ActionSend
+	}
+
+	var typ g53.RRType
+	if query.Question != nil {
+		typ = query.Question.Type
+	}
+	key := l.makeKey(client, class, name, typ)
+
+	l.lock.Lock()
+	action := l.account(key, rate)
+	l.lock.Unlock()
+
+	if action != ActionSend && l.Logger != nil {
+		l.Logger(client, query, class, action)
+	}
+	if l.LogOnly {
+		return ActionSend
+	}
+	return action
+}
+
+// limiter created without New is initialized on first use
+func (l *Limiter) init() {
+	if l.buckets == nil {
+		l.buckets = make(map[string]*list.Element)
+		l.lru = list.New()
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+}
+
+func (l *Limiter) account(key string, rate int) Action {
+	l.init()
+	now := l.now()
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(elem)
+		b = elem.Value.(*bucket)
+		b.tokens += now.Sub(b.update).Seconds() * float64(rate)
+		if b.tokens > float64(rate) {
+			b.tokens = float64(rate)
+		}
+		b.update = now
+	} else {
+		b = &bucket{
+			key:    key,
+			tokens: float64(rate),
+			update: now,
+		}
+		l.buckets[key] = l.lru.PushFront(b)
+		for l.MaxEntries > 0 && len(l.buckets) > l.MaxEntries {
+			oldest := l.lru.Remove(l.lru.Back()).(*bucket)
+			delete(l.buckets, oldest.key)
+		}
+	}
+
+	b.tokens -= 1
+	if b.tokens >= 0 {
+		b.limits = 0
+		return ActionSend
+	}
+	if minTokens := -float64(rate) * l.Window.Seconds(); b.tokens < minTokens {
+		b.tokens = minTokens
+	}
+
+	b.limits += 1
+	if l.Slip > 0 && b.limits%l.Slip == 0 {
+		return ActionSlip
+	}
+	return ActionDrop
+}
+
+// Limit returns the message which should be sent, nil means the
+// response should be dropped
+func (l *Limiter) Limit(client net.IP, query, response *g53.Message) *g53.Message {
+	switch l.Check(client, query, response) {
+	case ActionDrop:
+		return nil
+	case ActionSlip:
+		return MakeSlipResponse(response)
+	default:
+		return response
+	}
+}
+
+// MakeSlipResponse returns a truncated copy of response with all
+// sections removed
+func MakeSlipResponse(response *g53.Message) *g53.Message {
+	slip := &g53.Message{
+		Header:   response.Header,
+		Question: response.Question,
+		Edns:     response.Edns,
+	}
+	slip.Header.SetFlag(g53.FLAG_TC, true)
+	slip.RecalculateSectionRRCount()
+	return slip
+}
+
+// Len returns the number of buckets
+func (l *Limiter) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.buckets)
+}
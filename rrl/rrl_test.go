@@ -0,0 +1,180 @@
+package rrl
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) forward(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(rate int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	l := New(rate)
+	l.now = clock.Now
+	return l, clock
+}
+
+func rrsetFromString(s string) *g53.RRset {
+	rrset, err := g53.RRsetFromString(s)
+	if err != nil {
+		panic("invalid rrset " + s)
+	}
+	return rrset
+}
+
+func makeExchange(name string, typ g53.RRType, rcode g53.Rcode, aa bool, rrs map[g53.SectionType][]string) (*g53.Message, *g53.Message) {
+	query := g53.MakeQuery(g53.NameFromStringUnsafe(name), typ, 1232, false)
+	response := query.MakeResponse()
+	response.Edns = query.Edns
+	response.Header.Rcode = rcode
+	response.Header.SetFlag(g53.FLAG_AA, aa)
+	for st, ss := range rrs {
+		for _, s := range ss {
+			response.AddRRset(st, rrsetFromString(s))
+		}
+	}
+	response.RecalculateSectionRRCount()
+	return query, response
+}
+
+func TestClassify(t *testing.T) {
+	soa := "example.com. 3600 IN SOA ns.example.com. root.example.com. 1 3600 900 86400 300"
+	for _, c := range []struct {
+		rcode g53.Rcode
+		aa    bool
+		rrs   map[g53.SectionType][]string
+		class Class
+		name  string
+	}{
+		{g53.R_NOERROR, true, map[g53.SectionType][]string{g53.AnswerSection: {"www.example.com. 60 IN A 192.0.2.1"}}, ClassAnswer, "www.example.com."},
+		{g53.R_NOERROR, true, map[g53.SectionType][]string{g53.AuthSection: {soa}}, ClassAnswer, "www.example.com."},
+		{g53.R_NXDOMAIN, true, map[g53.SectionType][]string{g53.AuthSection: {soa}}, ClassNXDomain, "example.com."},
+		{g53.R_NXDOMAIN, true, nil, ClassNXDomain, "www.example.com."},
+		{g53.R_NOERROR, false, map[g53.SectionType][]string{g53.AuthSection: {"www.example.com. 3600 IN NS ns.www.example.com."}}, ClassReferral, "www.example.com."},
+		{g53.R_REFUSED, false, nil, ClassError, ""},
+		{g53.R_SERVFAIL, false, nil, ClassError, ""},
+	} {
+		query, response := makeExchange("www.example.com.", g53.RR_A, c.rcode, c.aa, c.rrs)
+		class, name := Classify(query, response)
+		ut.Equal(t, class, c.class)
+		if c.name == "" {
+			ut.Assert(t, name == nil, "error shouldn't be accounted by name")
+		} else {
+			ut.Equal(t, name.String(false), c.name)
+		}
+	}
+}
+
+func TestLimitAndSlip(t *testing.T) {
+	l, clock := newTestLimiter(5)
+	l.Slip = 2
+	client := net.ParseIP("192.0.2.1")
+	query, response := makeExchange("www.example.com.", g53.RR_A, g53.R_NOERROR, true, map[g53.SectionType][]string{
+		g53.AnswerSection: {"www.example.com. 60 IN A 192.0.2.1"},
+	})
+
+	for i := 0; i < 5; i++ {
+		ut.Equal(t, l.Check(client, query, response), ActionSend)
+	}
+	ut.Equal(t, l.Check(client, query, response), ActionDrop)
+	ut.Equal(t, l.Check(client, query, response), ActionSlip)
+	ut.Equal(t, l.Limit(client, query, response) == nil, true)
+	slip := l.Limit(client, query, response)
+	ut.Equal(t, slip.Header.GetFlag(g53.FLAG_TC), true)
+	ut.Equal(t, slip.Header.ANCount, uint16(0))
+	ut.Equal(t, slip.Header.ARCount, uint16(1))
+	ut.Equal(t, len(slip.Sections[g53.AnswerSection]), 0)
+	ut.Equal(t, response.Header.GetFlag(g53.FLAG_TC), false)
+
+	//clients in the same /24 share the bucket, others are not affected
+	ut.Assert(t, l.Check(net.ParseIP("192.0.2.200"), query, response) != ActionSend, "same prefix should be limited")
+	ut.Equal(t, l.Check(net.ParseIP("192.0.3.1"), query, response), ActionSend)
+	ut.Equal(t, l.Check(net.ParseIP("2001:db8:0:ff::1"), query, response), ActionSend)
+
+	//other names and classes are accounted separately
+	query2, response2 := makeExchange("mail.example.com.", g53.RR_A, g53.R_NOERROR, true, nil)
+	ut.Equal(t, l.Check(client, query2, response2), ActionSend)
+	query3, response3 := makeExchange("www.example.com.", g53.RR_A, g53.R_REFUSED, false, nil)
+	ut.Equal(t, l.Check(client, query3, response3), ActionSend)
+
+	//tokens are refilled at rate, but the debt has to be paid first
+	clock.forward(time.Second)
+	ut.Assert(t, l.Check(client, query, response) != ActionSend, "debt should be paid first")
+	clock.forward(l.Window)
+	ut.Equal(t, l.Check(client, query, response), ActionSend)
+}
+
+func TestNXDomainAccountedByZone(t *testing.T) {
+	l, _ := newTestLimiter(100)
+	l.NXDomainsPerSecond = 2
+	l.Slip = 0
+	soa := "example.com. 3600 IN SOA ns.example.com. root.example.com. 1 3600 900 86400 300"
+	client := net.ParseIP("2001:db8::1")
+
+	var actions []Action
+	for _, name := range []string{"a.example.com.", "b.example.com.", "c.example.com.", "d.example.com."} {
+		query, response := makeExchange(name, g53.RR_A, g53.R_NXDOMAIN, true, map[g53.SectionType][]string{
+			g53.AuthSection: {soa},
+		})
+		actions = append(actions, l.Check(client, query, response))
+	}
+	ut.Equal(t, actions, []Action{ActionSend, ActionSend, ActionDrop, ActionDrop})
+
+	//ipv6 clients are aggregated by /56
+	query, response := makeExchange("e.example.com.", g53.RR_A, g53.R_NXDOMAIN, true, map[g53.SectionType][]string{
+		g53.AuthSection: {soa},
+	})
+	ut.Equal(t, l.Check(net.ParseIP("2001:db8:0:ff::1"), query, response), ActionDrop)
+	ut.Equal(t, l.Check(net.ParseIP("2001:db8:0:100::1"), query, response), ActionSend)
+}
+
+func TestLogOnly(t *testing.T) {
+	l, _ := newTestLimiter(1)
+	l.LogOnly = true
+	var logged []Action
+	l.Logger = func(client net.IP, query *g53.Message, class Class, action Action) {
+		ut.Equal(t, class, ClassAnswer)
+		logged = append(logged, action)
+	}
+
+	client := net.ParseIP("192.0.2.1")
+	query, response := makeExchange("www.example.com.", g53.RR_A, g53.R_NOERROR, true, nil)
+	for i := 0; i < 3; i++ {
+		ut.Equal(t, l.Limit(client, query, response), response)
+	}
+	ut.Equal(t, logged, []Action{ActionDrop, ActionSlip})
+}
+
+func TestMaxEntries(t *testing.T) {
+	l, _ := newTestLimiter(10)
+	l.MaxEntries = 2
+	query, response := makeExchange("www.example.com.", g53.RR_A, g53.R_NOERROR, true, nil)
+	for _, ip := range []string{"192.0.2.1", "192.0.3.1", "192.0.4.1"} {
+		l.Check(net.ParseIP(ip), query, response)
+	}
+	ut.Equal(t, l.Len(), 2)
+}
+
+func TestZeroValueLimiter(t *testing.T) {
+	l := &Limiter{ResponsesPerSecond: 1}
+	client := net.ParseIP("192.0.2.1")
+	query, response := makeExchange("www.example.com.", g53.RR_A, g53.R_NOERROR, true, nil)
+	ut.Equal(t, l.Check(client, query, response), ActionSend)
+	ut.Equal(t, l.Check(client, query, response), ActionDrop)
+	ut.Equal(t, l.Len(), 1)
+	ut.Equal(t, (&Limiter{}).Len(), 0)
+}
@@ -129,6 +129,7 @@ const (
 	RR_NSEC3      RRType = 50 /* RFC 5155 */
 	RR_NSEC3PARAM RRType = 51 /* RFC 5155 */
 	RR_TLSA       RRType = 52 /* RFC 6698 */
+	RR_SMIMEA     RRType = 53 /* RFC 8162 */
 
 	RR_HIP RRType = 55 /* RFC 5205 */
 
@@ -141,6 +142,13 @@ const (
 	/** draft-barwood-dnsop-ds-publis */
 	RR_CDS RRType = 59
 
+	RR_OPENPGPKEY RRType = 61 /* RFC 7929 */
+	RR_CSYNC      RRType = 62 /* RFC 7477 */
+	RR_ZONEMD     RRType = 63 /* RFC 8976 */
+
+	RR_SVCB  RRType = 64 /* RFC 9460 */
+	RR_HTTPS RRType = 65 /* RFC 9460 */
+
 	RR_SPF RRType = 99 /* RFC 4408 */
 
 	RR_UINFO  RRType = 100
@@ -227,11 +235,17 @@ var typeNameMap = map[RRType]string{
 	RR_NSEC3:      "nsec3",
 	RR_NSEC3PARAM: "nsec3param",
 	RR_TLSA:       "tlsa",
+	RR_SMIMEA:     "smimea",
 	RR_HIP:        "hip",
 	RR_NINFO:      "ninfo",
 	RR_RKEY:       "pkey",
 	RR_TALINK:     "talink",
 	RR_CDS:        "cds",
+	RR_OPENPGPKEY: "openpgpkey",
+	RR_CSYNC:      "csync",
+	RR_ZONEMD:     "zonemd",
+	RR_SVCB:       "svcb",
+	RR_HTTPS:      "https",
 	RR_SPF:        "spf",
 	RR_UINFO:      "uinfo",
 	RR_UID:        "uid",
@@ -246,6 +246,11 @@ func (t *TSIG) Rend(r *MsgRender) {
 func (t *TSIG) ToWire(buf *util.OutputBuffer) {
 	t.Header.ToWire(buf)
 	pos := buf.Len()
+	t.rdataToWire(buf)
+	buf.WriteUint16At(uint16(buf.Len()-pos), pos-2)
+}
+
+func (t *TSIG) rdataToWire(buf *util.OutputBuffer) {
 	alg, _ := NameFromString(string(t.Algorithm))
 	alg.ToWire(buf)
 	ts1 := uint16((t.TimeSigned & 0x0000ffff00000000) >> 32)
@@ -259,7 +264,6 @@ func (t *TSIG) ToWire(buf *util.OutputBuffer) {
 	buf.WriteUint16(t.Error)
 	buf.WriteUint16(t.OtherLen)
 	buf.WriteData(t.OtherData)
-	buf.WriteUint16At(uint16(buf.Len()-pos), pos-2)
 }
 
 func (t *TSIG) String() string {
@@ -0,0 +1,438 @@
+package g53
+
+import (
+	"strings"
+)
+
+// UpdateStore is the zone data which dynamic update is applied to
+type UpdateStore interface {
+	// return all the rrsets owned by name, nil if name doesn't exist
+	GetRRsets(name *Name) []*RRset
+	// changes should be applied all or nothing, each rrset is the final
+	// content of the name and type, rrset without rdata means it should
+	// be deleted
+	Commit(changes []*RRset) error
+}
+
+// Update is the parsed update message, prerequisites are rrs in answer
+// section and updates are rrs in authority section
+type Update struct {
+	Zone    *Name
+	Class   RRClass
+	Prereqs []*RRset
+	Updates []*RRset
+}
+
+// RFC2136 3.1.1, zone section should have exactly one soa record
+func UpdateFromMessage(m *Message) (*Update, Rcode) {
+	if m.Header.Opcode != OP_UPDATE {
+		return nil, R_FORMERR
+	}
+
+	if m.Header.QDCount != 1 || m.Question == nil || m.Question.Type != RR_SOA {
+		return nil, R_FORMERR
+	}
+
+	return &Update{
+		Zone:    m.Question.Name,
+		Class:   m.Question.Class,
+		Prereqs: m.Sections[AnswerSection],
+		Updates: m.Sections[AuthSection],
+	}, R_NOERROR
+}
+
+func (u *Update) inZone(name *Name) bool {
+	return name.IsSubDomain(u.Zone)
+}
+
+func isMetaType(t RRType) bool {
+	switch t {
+	case RR_ANY, RR_AXFR, RR_IXFR, RR_MAILA, RR_MAILB, RR_OPT, RR_TSIG, RR_TKEY:
+		return true
+	default:
+		return false
+	}
+}
+
+// RFC2136 3.2
+func (u *Update) CheckPrerequisites(store UpdateStore) Rcode {
+	var rrsetsShouldExist []*RRset
+	for _, prereq := range u.Prereqs {
+		if prereq.Ttl != 0 {
+			return R_FORMERR
+		}
+
+		if u.inZone(prereq.Name) == false {
+			return R_NOTZONE
+		}
+
+		switch prereq.Class {
+		case CLASS_ANY:
+			if len(prereq.Rdatas) != 0 {
+				return R_FORMERR
+			}
+			if prereq.Type == RR_ANY {
+				if len(store.GetRRsets(prereq.Name)) == 0 {
+					return R_NXDOMAIN
+				}
+			} else if getRRset(store.GetRRsets(prereq.Name), prereq.Type) == nil {
+				return R_NXRRSET
+			}
+
+		case CLASS_NONE:
+			if len(prereq.Rdatas) != 0 {
+				return R_FORMERR
+			}
+			if prereq.Type == RR_ANY {
+				if len(store.GetRRsets(prereq.Name)) != 0 {
+					return R_YXDOMAIN
+				}
+			} else if getRRset(store.GetRRsets(prereq.Name), prereq.Type) != nil {
+				return R_YXRRSET
+			}
+
+		case u.Class:
+			if isMetaType(prereq.Type) {
+				return R_FORMERR
+			}
+			rrsetsShouldExist = mergeRRset(rrsetsShouldExist, prereq)
+
+		default:
+			return R_FORMERR
+		}
+	}
+
+	for _, expect := range rrsetsShouldExist {
+		rrset := getRRset(store.GetRRsets(expect.Name), expect.Type)
+		if rrset == nil || rrset.Equals(expect) == false {
+			return R_NXRRSET
+		}
+	}
+
+	return R_NOERROR
+}
+
+func mergeRRset(rrsets []*RRset, rrset *RRset) []*RRset {
+	for _, old := range rrsets {
+		if old.IsSameRRset(rrset) {
+			for _, rdata := range rrset.Rdatas {
+				old.AddRdata(rdata)
+			}
+			return rrsets
+		}
+	}
+	return append(rrsets, rrset.Clone())
+}
+
+func getRRset(rrsets []*RRset, typ RRType) *RRset {
+	for _, rrset := range rrsets {
+		if rrset.Type == typ {
+			return rrset
+		}
+	}
+	return nil
+}
+
+// RFC2136 3.4.1
+func (u *Update) Prescan() Rcode {
+	for _, update := range u.Updates {
+		if u.inZone(update.Name) == false {
+			return R_NOTZONE
+		}
+
+		switch update.Class {
+		case u.Class:
+			if isMetaType(update.Type) {
+				return R_FORMERR
+			}
+
+		case CLASS_ANY:
+			if update.Ttl != 0 || len(update.Rdatas) != 0 {
+				return R_FORMERR
+			}
+			if update.Type != RR_ANY && isMetaType(update.Type) {
+				return R_FORMERR
+			}
+
+		case CLASS_NONE:
+			if update.Ttl != 0 || isMetaType(update.Type) {
+				return R_FORMERR
+			}
+
+		default:
+			return R_FORMERR
+		}
+	}
+	return R_NOERROR
+}
+
+// check prerequisites and prescan the updates, then apply the updates to
+// store, if there is any change and soa isn't updated, the serial of soa
+// will be increased by one
+func (u *Update) Apply(store UpdateStore) Rcode {
+	if rcode := u.CheckPrerequisites(store); rcode != R_NOERROR {
+		return rcode
+	}
+
+	if rcode := u.Prescan(); rcode != R_NOERROR {
+		return rcode
+	}
+
+	ws := newUpdateWorkspace(store, u.Class)
+	soaUpdated := false
+	for _, update := range u.Updates {
+		switch update.Class {
+		case u.Class:
+			for _, rdata := range update.Rdatas {
+				if update.Type == RR_SOA {
+					if u.updateSOA(ws, update, rdata) {
+						soaUpdated = true
+					}
+				} else {
+					u.addRdata(ws, update, rdata)
+				}
+			}
+
+		case CLASS_ANY:
+			if update.Type == RR_ANY {
+				u.removeName(ws, update.Name)
+			} else {
+				u.removeRRset(ws, update.Name, update.Type)
+			}
+
+		case CLASS_NONE:
+			for _, rdata := range update.Rdatas {
+				u.removeRdata(ws, update, rdata)
+			}
+		}
+	}
+
+	if ws.changed() == false {
+		return R_NOERROR
+	}
+
+	if soaUpdated == false {
+		u.increaseSerial(ws)
+	}
+
+	if err := store.Commit(ws.changes()); err != nil {
+		return R_SERVFAIL
+	}
+	return R_NOERROR
+}
+
+func (u *Update) isApex(name *Name) bool {
+	return name.Equals(u.Zone)
+}
+
+func (u *Update) updateSOA(ws *updateWorkspace, update *RRset, rdata Rdata) bool {
+	if u.isApex(update.Name) == false {
+		return false
+	}
+
+	old := ws.get(update.Name, RR_SOA)
+	if old == nil || len(old.Rdatas) == 0 {
+		return false
+	}
+
+	if CompareSerial(rdata.(*SOA).Serial, old.Rdatas[0].(*SOA).Serial) <= 0 {
+		return false
+	}
+
+	ws.set(update.Name, &RRset{
+		Name:   update.Name,
+		Type:   RR_SOA,
+		Class:  u.Class,
+		Ttl:    update.Ttl,
+		Rdatas: []Rdata{rdata},
+	})
+	return true
+}
+
+func (u *Update) increaseSerial(ws *updateWorkspace) {
+	old := ws.get(u.Zone, RR_SOA)
+	if old == nil || len(old.Rdatas) == 0 {
+		return
+	}
+
+	soa := *(old.Rdatas[0].(*SOA))
+	soa.Serial += 1
+	rrset := old.Clone()
+	rrset.Rdatas[0] = &soa
+	ws.set(u.Zone, rrset)
+}
+
+// rrsig and nsec could coexist with cname
+func isCNAMECompatible(t RRType) bool {
+	return t == RR_CNAME || t == RR_RRSIG || t == RR_NSEC
+}
+
+func (u *Update) addRdata(ws *updateWorkspace, update *RRset, rdata Rdata) {
+	rrsets := ws.getAll(update.Name)
+	if update.Type == RR_CNAME {
+		for _, rrset := range rrsets {
+			if isCNAMECompatible(rrset.Type) == false {
+				return
+			}
+		}
+	} else if isCNAMECompatible(update.Type) == false && getRRset(rrsets, RR_CNAME) != nil {
+		return
+	}
+
+	old := getRRset(rrsets, update.Type)
+	if old == nil || update.Type == RR_CNAME {
+		ws.set(update.Name, &RRset{
+			Name:   update.Name,
+			Type:   update.Type,
+			Class:  u.Class,
+			Ttl:    update.Ttl,
+			Rdatas: []Rdata{rdata},
+		})
+		return
+	}
+
+	rrset := old.Clone()
+	if rrset.AddRdata(rdata) != nil && rrset.Ttl == update.Ttl {
+		return
+	}
+	rrset.Ttl = update.Ttl
+	ws.set(update.Name, rrset)
+}
+
+func (u *Update) removeName(ws *updateWorkspace, name *Name) {
+	for _, rrset := range ws.getAll(name) {
+		if u.isApex(name) && (rrset.Type == RR_SOA || rrset.Type == RR_NS) {
+			continue
+		}
+		ws.remove(name, rrset.Type)
+	}
+}
+
+func (u *Update) removeRRset(ws *updateWorkspace, name *Name, typ RRType) {
+	if u.isApex(name) && (typ == RR_SOA || typ == RR_NS) {
+		return
+	}
+	ws.remove(name, typ)
+}
+
+func (u *Update) removeRdata(ws *updateWorkspace, update *RRset, rdata Rdata) {
+	if update.Type == RR_SOA {
+		return
+	}
+
+	old := ws.get(update.Name, update.Type)
+	if old == nil {
+		return
+	}
+
+	if u.isApex(update.Name) && update.Type == RR_NS && old.RRCount() == 1 {
+		return
+	}
+
+	rrset := old.Clone()
+	if rrset.RemoveRdata(rdata) == false {
+		return
+	}
+
+	if rrset.RRCount() == 0 {
+		ws.remove(update.Name, update.Type)
+	} else {
+		ws.set(update.Name, rrset)
+	}
+}
+
+type rrsetKey struct {
+	name string
+	typ  RRType
+}
+
+// updateWorkspace holds the modified rrsets, nothing is written
+// into store until all the updates are applied
+type updateWorkspace struct {
+	store   UpdateStore
+	class   RRClass
+	names   map[string][]*RRset
+	touched []rrsetKey
+	owners  map[rrsetKey]*Name
+}
+
+func newUpdateWorkspace(store UpdateStore, class RRClass) *updateWorkspace {
+	return &updateWorkspace{
+		store:  store,
+		class:  class,
+		names:  make(map[string][]*RRset),
+		owners: make(map[rrsetKey]*Name),
+	}
+}
+
+func nameKey(name *Name) string {
+	return strings.ToLower(name.String(false))
+}
+
+func (ws *updateWorkspace) getAll(name *Name) []*RRset {
+	key := nameKey(name)
+	rrsets, ok := ws.names[key]
+	if ok == false {
+		rrsets = ws.store.GetRRsets(name)
+		ws.names[key] = rrsets
+	}
+	return rrsets
+}
+
+func (ws *updateWorkspace) get(name *Name, typ RRType) *RRset {
+	return getRRset(ws.getAll(name), typ)
+}
+
+func (ws *updateWorkspace) touch(name *Name, typ RRType) {
+	key := rrsetKey{nameKey(name), typ}
+	if _, ok := ws.owners[key]; ok == false {
+		ws.owners[key] = name
+		ws.touched = append(ws.touched, key)
+	}
+}
+
+func (ws *updateWorkspace) set(name *Name, rrset *RRset) {
+	ws.touch(name, rrset.Type)
+	var rrsets []*RRset
+	for _, old := range ws.getAll(name) {
+		if old.Type != rrset.Type {
+			rrsets = append(rrsets, old)
+		}
+	}
+	ws.names[nameKey(name)] = append(rrsets, rrset)
+}
+
+func (ws *updateWorkspace) remove(name *Name, typ RRType) {
+	if ws.get(name, typ) == nil {
+		return
+	}
+
+	ws.touch(name, typ)
+	var rrsets []*RRset
+	for _, old := range ws.getAll(name) {
+		if old.Type != typ {
+			rrsets = append(rrsets, old)
+		}
+	}
+	ws.names[nameKey(name)] = rrsets
+}
+
+func (ws *updateWorkspace) changed() bool {
+	return len(ws.touched) != 0
+}
+
+func (ws *updateWorkspace) changes() []*RRset {
+	changes := make([]*RRset, 0, len(ws.touched))
+	for _, key := range ws.touched {
+		if rrset := getRRset(ws.names[key.name], key.typ); rrset != nil {
+			changes = append(changes, rrset)
+		} else {
+			changes = append(changes, &RRset{
+				Name:  ws.owners[key],
+				Type:  key.typ,
+				Class: ws.class,
+			})
+		}
+	}
+	return changes
+}
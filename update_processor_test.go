@@ -0,0 +1,196 @@
+package g53
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+// store is accessed by update server goroutine and checked by test
+type memUpdateStore struct {
+	lock   sync.Mutex
+	rrsets map[string][]*RRset
+}
+
+func newMemUpdateStore(rrs []string) *memUpdateStore {
+	store := &memUpdateStore{rrsets: make(map[string][]*RRset)}
+	for _, rr := range rrs {
+		rrset, _ := RRsetFromString(rr)
+		key := nameKey(rrset.Name)
+		if old := getRRset(store.rrsets[key], rrset.Type); old != nil {
+			old.AddRdata(rrset.Rdatas[0])
+		} else {
+			store.rrsets[key] = append(store.rrsets[key], rrset)
+		}
+	}
+	return store
+}
+
+func (s *memUpdateStore) GetRRsets(name *Name) []*RRset {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.rrsets[nameKey(name)]
+}
+
+func (s *memUpdateStore) Commit(changes []*RRset) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, change := range changes {
+		key := nameKey(change.Name)
+		var rrsets []*RRset
+		for _, rrset := range s.rrsets[key] {
+			if rrset.Type != change.Type {
+				rrsets = append(rrsets, rrset)
+			}
+		}
+		if change.RRCount() != 0 {
+			rrsets = append(rrsets, change)
+		}
+		if len(rrsets) == 0 {
+			delete(s.rrsets, key)
+		} else {
+			s.rrsets[key] = rrsets
+		}
+	}
+	return nil
+}
+
+func (s *memUpdateStore) serial() uint32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return getRRset(s.rrsets["example.com."], RR_SOA).Rdatas[0].(*SOA).Serial
+}
+
+func newTestUpdateStore() *memUpdateStore {
+	return newMemUpdateStore([]string{
+		"example.com. 3600 IN SOA ns1.example.com. root.example.com. 100 3600 600 86400 300",
+		"example.com. 3600 IN NS ns1.example.com.",
+		"ns1.example.com. 3600 IN A 192.0.2.1",
+		"www.example.com. 3600 IN A 192.0.2.10",
+		"www.example.com. 3600 IN A 192.0.2.11",
+		"ftp.example.com. 3600 IN CNAME www.example.com.",
+	})
+}
+
+func updateThroughWire(t *testing.T, msg *Message) *Update {
+	msg.RecalculateSectionRRCount()
+	render := NewMsgRender()
+	msg.Rend(render)
+	msg, err := MessageFromWire(util.NewInputBuffer(render.Data()))
+	Assert(t, err == nil, "parse update failed:%v", err)
+	update, rcode := UpdateFromMessage(msg)
+	Equal(t, rcode, R_NOERROR)
+	return update
+}
+
+func TestUpdatePrerequisites(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	www, _ := RRsetFromString("www.example.com. 0 IN A 192.0.2.10")
+	www.AddRdata(&A{Host: []byte{192, 0, 2, 11}})
+	mail, _ := RRsetFromString("mail.example.com. 0 IN A 192.0.2.20")
+
+	cases := []struct {
+		build func(*Message)
+		rcode Rcode
+	}{
+		{func(m *Message) { m.UpdateNameExists([]*Name{www.Name}) }, R_NOERROR},
+		{func(m *Message) { m.UpdateNameExists([]*Name{mail.Name}) }, R_NXDOMAIN},
+		{func(m *Message) { m.UpdateNameNotExists([]*Name{www.Name}) }, R_YXDOMAIN},
+		{func(m *Message) { m.UpdateRRsetExists(mail) }, R_NXRRSET},
+		{func(m *Message) { m.UpdateRRsetNotExists(www) }, R_YXRRSET},
+		{func(m *Message) { m.UpdateRdataExsits(www) }, R_NOERROR},
+		{func(m *Message) { m.UpdateRdataExsits(mail) }, R_NXRRSET},
+		{func(m *Message) {
+			m.UpdateNameExists([]*Name{NameFromStringUnsafe("www.example.org.")})
+		}, R_NOTZONE},
+	}
+
+	store := newTestUpdateStore()
+	for _, c := range cases {
+		msg := MakeUpdate(zone)
+		c.build(msg)
+		update := updateThroughWire(t, msg)
+		Equal(t, update.CheckPrerequisites(store), c.rcode)
+	}
+}
+
+func TestUpdateApply(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	store := newTestUpdateStore()
+
+	msg := MakeUpdate(zone)
+	mail, _ := RRsetFromString("mail.example.com. 300 IN A 192.0.2.20")
+	msg.UpdateRRsetNotExists(mail)
+	msg.UpdateAddRRset(mail)
+	www, _ := RRsetFromString("www.example.com. 0 IN A 192.0.2.10")
+	msg.UpdateRemoveRdata(www)
+	Equal(t, updateThroughWire(t, msg).Apply(store), R_NOERROR)
+	Equal(t, store.serial(), uint32(101))
+	Equal(t, len(store.GetRRsets(mail.Name)), 1)
+	Equal(t, getRRset(store.GetRRsets(www.Name), RR_A).RRCount(), 1)
+
+	//prerequisite failed, nothing is changed
+	Equal(t, updateThroughWire(t, msg).Apply(store), R_YXRRSET)
+	Equal(t, store.serial(), uint32(101))
+
+	//cname can't coexist with other data
+	msg = MakeUpdate(zone)
+	ftpA, _ := RRsetFromString("ftp.example.com. 300 IN A 192.0.2.30")
+	msg.UpdateAddRRset(ftpA)
+	Equal(t, updateThroughWire(t, msg).Apply(store), R_NOERROR)
+	Equal(t, getRRset(store.GetRRsets(ftpA.Name), RR_A), (*RRset)(nil))
+	Equal(t, store.serial(), uint32(101))
+
+	//apex soa and ns can't be removed
+	msg = MakeUpdate(zone)
+	msg.UpdateRemoveName(zone)
+	ns, _ := RRsetFromString("example.com. 0 IN NS ns1.example.com.")
+	msg.UpdateRemoveRdata(ns)
+	msg.UpdateRemoveRRset(ns)
+	Equal(t, updateThroughWire(t, msg).Apply(store), R_NOERROR)
+	Equal(t, len(store.GetRRsets(zone)), 2)
+
+	//explicit soa update won't be increased again
+	msg = MakeUpdate(zone)
+	soa, _ := RRsetFromString("example.com. 3600 IN SOA ns1.example.com. root.example.com. 200 3600 600 86400 300")
+	msg.UpdateAddRRset(soa)
+	msg.UpdateRemoveName(NameFromStringUnsafe("ftp.example.com."))
+	Equal(t, updateThroughWire(t, msg).Apply(store), R_NOERROR)
+	Equal(t, store.serial(), uint32(200))
+	Equal(t, len(store.GetRRsets(NameFromStringUnsafe("ftp.example.com."))), 0)
+
+	//meta type can't be added
+	msg = MakeUpdate(zone)
+	msg.UpdateAddRRset(&RRset{Name: zone, Type: RR_ANY, Class: CLASS_IN, Rdatas: []Rdata{&A{Host: []byte{1, 1, 1, 1}}}})
+	msg.RecalculateSectionRRCount()
+	update, _ := UpdateFromMessage(msg)
+	Equal(t, update.Apply(store), R_FORMERR)
+}
+
+func TestUpdateFromMessage(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	msg := MakeUpdate(zone)
+	msg.RecalculateSectionRRCount()
+	_, rcode := UpdateFromMessage(msg)
+	Equal(t, rcode, R_NOERROR)
+
+	msg.Header.QDCount = 2
+	_, rcode = UpdateFromMessage(msg)
+	Equal(t, rcode, R_FORMERR)
+
+	msg.Question = nil
+	msg.RecalculateSectionRRCount()
+	_, rcode = UpdateFromMessage(msg)
+	Equal(t, rcode, R_FORMERR)
+
+	msg = MakeUpdate(zone)
+	msg.Question.Type = RR_A
+	msg.RecalculateSectionRRCount()
+	_, rcode = UpdateFromMessage(msg)
+	Equal(t, rcode, R_FORMERR)
+
+	msg = MakeQuery(zone, RR_SOA, 512, false)
+	_, rcode = UpdateFromMessage(msg)
+	Equal(t, rcode, R_FORMERR)
+}
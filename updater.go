@@ -0,0 +1,295 @@
+package g53
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrFormErr         = errors.New("server reply format error")
+	ErrServFail        = errors.New("server reply server failure")
+	ErrNotImp          = errors.New("server doesn't implement update")
+	ErrRefused         = errors.New("server refused update")
+	ErrPrereqYXDomain  = errors.New("prerequisite failed: name exists")
+	ErrPrereqYXRRset   = errors.New("prerequisite failed: rrset exists")
+	ErrPrereqNXDomain  = errors.New("prerequisite failed: name doesn't exist")
+	ErrPrereqNXRRset   = errors.New("prerequisite failed: rrset doesn't exist")
+	ErrNotAuth         = errors.New("server isn't authoritative for zone")
+	ErrNotZone         = errors.New("name isn't in zone")
+	ErrTsigBadSig      = errors.New("tsig signature is bad")
+	ErrTsigBadKey      = errors.New("tsig key isn't known by server")
+	ErrTsigBadTime     = errors.New("tsig time is out of window")
+	ErrTsigUnsigned    = errors.New("response to signed request isn't signed")
+	ErrZoneNotFound    = errors.New("no zone found for name")
+	ErrResponseInvalid = errors.New("response doesn't match the request")
+)
+
+// map rcode of the update response to error, nil for NOERROR
+func UpdateRcodeToError(rcode Rcode) error {
+	switch rcode {
+	case R_NOERROR:
+		return nil
+	case R_FORMERR:
+		return ErrFormErr
+	case R_SERVFAIL:
+		return ErrServFail
+	case R_NXDOMAIN:
+		return ErrPrereqNXDomain
+	case R_NOTIMP:
+		return ErrNotImp
+	case R_REFUSED:
+		return ErrRefused
+	case R_YXDOMAIN:
+		return ErrPrereqYXDomain
+	case R_YXRRSET:
+		return ErrPrereqYXRRset
+	case R_NXRRSET:
+		return ErrPrereqNXRRset
+	case R_NOTAUTH:
+		return ErrNotAuth
+	case R_NOTZONE:
+		return ErrNotZone
+	default:
+		return fmt.Errorf("unknown rcode %d", rcode)
+	}
+}
+
+// map error field of tsig in response to error, tsig errors don't fit
+// in the 4 bits rcode of header, nil for NOERROR
+func TsigErrorToError(code uint16) error {
+	switch Rcode(code) {
+	case R_NOERROR:
+		return nil
+	case R_BADSIG:
+		return ErrTsigBadSig
+	case R_BADKEY:
+		return ErrTsigBadKey
+	case R_BADTIME:
+		return ErrTsigBadTime
+	default:
+		return fmt.Errorf("unknown tsig error %d", code)
+	}
+}
+
+const maxUDPUpdateSize = 512
+
+// Updater send dynamic update to a primary server, the zone of each rrset
+// is discovered by soa query and cached
+type Updater struct {
+	Server  string //address in host:port format
+	Timeout time.Duration
+
+	tsigKey    string
+	tsigSecret string
+	tsigAlg    string
+
+	lock  sync.Mutex
+	zones map[string]*Name
+}
+
+func NewUpdater(server string) *Updater {
+	return &Updater{
+		Server:  server,
+		Timeout: 3 * time.Second,
+		zones:   make(map[string]*Name),
+	}
+}
+
+func (u *Updater) SetTSIG(key, secret, alg string) error {
+	if _, err := NewTSIG(key, secret, alg); err != nil {
+		return err
+	}
+	u.tsigKey, u.tsigSecret, u.tsigAlg = key, secret, alg
+	return nil
+}
+
+// FindZone return the zone which name belongs to, soa of name and its
+// ancestors is queried until soa is found in answer or authority section
+func (u *Updater) FindZone(name *Name) (*Name, error) {
+	u.lock.Lock()
+	zone, ok := u.zones[nameKey(name)]
+	u.lock.Unlock()
+	if ok {
+		return zone, nil
+	}
+
+	current := name
+	for {
+		zone, err := u.querySOAOwner(current)
+		if err != nil {
+			return nil, err
+		}
+
+		if zone != nil && name.IsSubDomain(zone) {
+			u.lock.Lock()
+			u.zones[nameKey(name)] = zone
+			u.lock.Unlock()
+			return zone, nil
+		}
+
+		if current.IsRoot() {
+			return nil, ErrZoneNotFound
+		}
+		current, _ = current.Parent(1)
+	}
+}
+
+func (u *Updater) querySOAOwner(name *Name) (*Name, error) {
+	query := MakeQuery(name, RR_SOA, 4096, false)
+	query.Header.SetFlag(FLAG_RD, false)
+	resp, err := u.exchange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Header.Rcode != R_NOERROR && resp.Header.Rcode != R_NXDOMAIN {
+		return nil, nil
+	}
+
+	for _, st := range []SectionType{AnswerSection, AuthSection} {
+		for _, rrset := range resp.Sections[st] {
+			if rrset.Type == RR_SOA {
+				return rrset.Name, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Update add and delete rrsets in one message per zone, rrset to delete
+// without rdata means the whole rrset will be removed. Messages are sent
+// in the order of the zones first appear, and it stops at the first error
+func (u *Updater) Update(adds []*RRset, deletes []*RRset) error {
+	var zones []*Name
+	msgs := make(map[string]*Message)
+	getMsg := func(name *Name) (*Message, error) {
+		zone, err := u.FindZone(name)
+		if err != nil {
+			return nil, err
+		}
+		key := nameKey(zone)
+		msg, ok := msgs[key]
+		if ok == false {
+			msg = MakeUpdate(zone)
+			msgs[key] = msg
+			zones = append(zones, zone)
+		}
+		return msg, nil
+	}
+
+	for _, rrset := range deletes {
+		msg, err := getMsg(rrset.Name)
+		if err != nil {
+			return err
+		}
+		if len(rrset.Rdatas) == 0 {
+			msg.UpdateRemoveRRset(rrset)
+		} else {
+			msg.UpdateRemoveRdata(rrset)
+		}
+	}
+
+	for _, rrset := range adds {
+		msg, err := getMsg(rrset.Name)
+		if err != nil {
+			return err
+		}
+		msg.UpdateAddRRset(rrset)
+	}
+
+	for _, zone := range zones {
+		if err := u.Send(msgs[nameKey(zone)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Send the update message which is built by caller and map the rcode in
+// response to error
+func (u *Updater) Send(msg *Message) error {
+	resp, err := u.exchange(msg)
+	if err != nil {
+		return err
+	}
+
+	if resp.Header.Opcode != OP_UPDATE {
+		return ErrResponseInvalid
+	}
+	return UpdateRcodeToError(resp.Header.Rcode)
+}
+
+func (u *Updater) exchange(msg *Message) (*Message, error) {
+	if u.tsigKey != "" {
+		tsig, err := NewTSIG(u.tsigKey, u.tsigSecret, u.tsigAlg)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetTSIG(tsig)
+	}
+
+	msg.RecalculateSectionRRCount()
+	render := NewMsgRender()
+	msg.Rend(render)
+
+	var resp *Message
+	var err error
+	if render.Len() <= maxUDPUpdateSize {
+		resp, err = u.exchangeUDP(render.Data())
+	}
+	if resp == nil || resp.Header.GetFlag(FLAG_TC) {
+		resp, err = u.exchangeTCP(render.Data())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.verifyResponse(msg, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// verifyResponse checks tsig of the response to a signed request, error
+// of tsig is checked first since BADSIG and BADKEY response has no mac,
+// then mac is verified with request mac and the key of updater
+func (u *Updater) verifyResponse(req, resp *Message) error {
+	if req.Tsig == nil {
+		return nil
+	}
+
+	tsig := resp.Tsig
+	if tsig == nil {
+		return ErrTsigUnsigned
+	}
+	if err := TsigErrorToError(tsig.Error); err != nil {
+		return err
+	}
+	if tsig.Header.Name.Equals(req.Tsig.Header.Name) == false {
+		return ErrSig
+	}
+
+	err := tsig.VerifyTsig(resp, u.tsigSecret, req.Tsig.MAC)
+	resp.Tsig = tsig
+	return err
+}
+
+func (u *Updater) exchangeUDP(query []byte) (*Message, error) {
+	data, err := util.UDPExchange(u.Server, query, u.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return MessageFromWire(util.NewInputBuffer(data))
+}
+
+func (u *Updater) exchangeTCP(query []byte) (*Message, error) {
+	data, err := util.TCPExchange(u.Server, query)
+	if err != nil {
+		return nil, err
+	}
+	return MessageFromWire(util.NewInputBuffer(data))
+}
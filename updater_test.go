@@ -0,0 +1,175 @@
+package g53
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+const testUpdaterSecret = "z08GzEnlCDGy/W3Zw/2NHg=="
+
+// signed request is verified with testUpdaterSecret, response to it is
+// signed with the same key, or carries BADSIG if verification fails
+func handleSignedUpdaterQuery(store *memUpdateStore, zone *Name, req *Message) *Message {
+	reqTsig := req.Tsig
+	if reqTsig == nil {
+		return handleUpdaterQuery(store, zone, req)
+	}
+
+	if reqTsig.VerifyTsig(req, testUpdaterSecret, nil) != nil {
+		resp := req.MakeResponse()
+		resp.Header.Rcode = R_NOTAUTH
+		badsig := *reqTsig
+		badsig.Error = uint16(R_BADSIG)
+		badsig.MAC, badsig.MACSize = nil, 0
+		resp.Tsig = &badsig
+		return resp
+	}
+
+	resp := handleUpdaterQuery(store, zone, req)
+	tsig, _ := NewTSIG(reqTsig.Header.Name.String(false), testUpdaterSecret, "hmac-md5")
+	//request mac is prepended to the digest of response
+	tsig.MAC = reqTsig.MAC
+	resp.SetTSIG(tsig)
+	return resp
+}
+
+func handleUpdaterQuery(store *memUpdateStore, zone *Name, req *Message) *Message {
+	resp := req.MakeResponse()
+	if req.Header.Opcode == OP_UPDATE {
+		update, rcode := UpdateFromMessage(req)
+		if rcode == R_NOERROR {
+			if update.Zone.Equals(zone) == false {
+				rcode = R_NOTAUTH
+			} else {
+				rcode = update.Apply(store)
+			}
+		}
+		resp.Header.Rcode = rcode
+		return resp
+	}
+
+	soa := getRRset(store.GetRRsets(zone), RR_SOA)
+	if req.Question.Name.IsSubDomain(zone) == false {
+		resp.Header.Rcode = R_REFUSED
+	} else if req.Question.Name.Equals(zone) {
+		resp.AddRRset(AnswerSection, soa)
+	} else {
+		if len(store.GetRRsets(req.Question.Name)) == 0 {
+			resp.Header.Rcode = R_NXDOMAIN
+		}
+		resp.AddRRset(AuthSection, soa)
+	}
+	resp.RecalculateSectionRRCount()
+	return resp
+}
+
+func runUpdateServer(t *testing.T, store *memUpdateStore, zone *Name) string {
+	addr, _ := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	conn, err := net.ListenUDP("udp", addr)
+	Assert(t, err == nil, "listen failed:%v", err)
+
+	go func() {
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		for {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, client, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			req, err := MessageFromWire(util.NewInputBuffer(buf[:n]))
+			if err != nil {
+				continue
+			}
+			render := NewMsgRender()
+			handleSignedUpdaterQuery(store, zone, req).Rend(render)
+			conn.WriteToUDP(render.Data(), client)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestUpdater(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	store := newTestUpdateStore()
+	updater := NewUpdater(runUpdateServer(t, store, zone))
+	err := updater.SetTSIG("key.", testUpdaterSecret, "hmac-md5")
+	Assert(t, err == nil, "set tsig failed:%v", err)
+
+	found, err := updater.FindZone(NameFromStringUnsafe("host1.lab.example.com."))
+	Assert(t, err == nil, "find zone failed:%v", err)
+	Assert(t, found.Equals(zone), "zone should be example.com")
+
+	host1, _ := RRsetFromString("host1.lab.example.com. 300 IN A 192.0.2.101")
+	host2, _ := RRsetFromString("host2.lab.example.com. 300 IN A 192.0.2.102")
+	www, _ := RRsetFromString("www.example.com. 0 IN A 192.0.2.10")
+	err = updater.Update([]*RRset{host1, host2}, []*RRset{www})
+	Assert(t, err == nil, "update failed:%v", err)
+	Equal(t, len(store.GetRRsets(host1.Name)), 1)
+	Equal(t, len(store.GetRRsets(host2.Name)), 1)
+	Equal(t, getRRset(store.GetRRsets(www.Name), RR_A).RRCount(), 1)
+
+	msg := MakeUpdate(zone)
+	msg.UpdateRRsetNotExists(host1)
+	Equal(t, updater.Send(msg), ErrPrereqYXRRset)
+
+	_, err = updater.FindZone(NameFromStringUnsafe("www.example.org."))
+	Equal(t, err, ErrZoneNotFound)
+	Equal(t, UpdateRcodeToError(R_NOTAUTH), ErrNotAuth)
+}
+
+func TestUpdaterTsigError(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	store := newTestUpdateStore()
+	updater := NewUpdater(runUpdateServer(t, store, zone))
+	updater.SetTSIG("key.", "AAAAAAAAAAAAAAAAAAAAAA==", "hmac-md5")
+
+	_, err := updater.FindZone(NameFromStringUnsafe("www.example.com."))
+	Equal(t, err, ErrTsigBadSig)
+
+	host, _ := RRsetFromString("host1.example.com. 300 IN A 192.0.2.101")
+	msg := MakeUpdate(zone)
+	msg.UpdateAddRRset(host)
+	Equal(t, updater.Send(msg), ErrTsigBadSig)
+	Equal(t, len(store.GetRRsets(host.Name)), 0)
+
+	Equal(t, TsigErrorToError(uint16(R_BADKEY)), ErrTsigBadKey)
+	Equal(t, TsigErrorToError(uint16(R_BADTIME)), ErrTsigBadTime)
+}
+
+func TestUpdaterVerifyResponse(t *testing.T) {
+	zone := NameFromStringUnsafe("example.com.")
+	store := newTestUpdateStore()
+	updater := NewUpdater("127.0.0.1:53")
+	updater.SetTSIG("key.", testUpdaterSecret, "hmac-md5")
+
+	req := MakeUpdate(zone)
+	tsig, _ := NewTSIG("key.", testUpdaterSecret, "hmac-md5")
+	req.SetTSIG(tsig)
+	req.RecalculateSectionRRCount()
+	render := NewMsgRender()
+	req.Rend(render)
+	req, _ = MessageFromWire(util.NewInputBuffer(render.Data()))
+
+	sign := func() *Message {
+		req, _ := MessageFromWire(util.NewInputBuffer(render.Data()))
+		render := NewMsgRender()
+		handleSignedUpdaterQuery(store, zone, req).Rend(render)
+		resp, _ := MessageFromWire(util.NewInputBuffer(render.Data()))
+		return resp
+	}
+
+	Equal(t, updater.verifyResponse(req, sign()), nil)
+
+	resp := sign()
+	resp.Tsig.MAC[0] ^= 0xff
+	Equal(t, updater.verifyResponse(req, resp), ErrSig)
+
+	resp = sign()
+	resp.Tsig = nil
+	Equal(t, updater.verifyResponse(req, resp), ErrTsigUnsigned)
+}
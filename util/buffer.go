@@ -26,6 +26,11 @@ func (buf *InputBuffer) Len() uint {
 	return buf.datalen
 }
 
+// Data returns the whole underlying data, it shouldn't be modified
+func (buf *InputBuffer) Data() []byte {
+	return buf.data
+}
+
 func (buf *InputBuffer) Position() uint {
 	return buf.pos
 }
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrResponseMismatch = errors.New("response doesn't match the query")
+
+const headerLen = 12
+
+// UDPExchange sends query to addr and waits for the response until
+// timeout, datagram which isn't a response to the query is ignored
+func UDPExchange(addr string, query []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := NewUDPConn(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := UDPWrite(query, conn); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		data, err := UDPRead(conn, deadline.Sub(time.Now()))
+		if err != nil {
+			return nil, err
+		}
+
+		if isResponse(query, data) {
+			return data, nil
+		}
+	}
+}
+
+// TCPExchange sends query to addr and reads one message back,
+// ErrResponseMismatch is returned if it isn't a response to the query
+func TCPExchange(addr string, query []byte) ([]byte, error) {
+	conn, err := NewTCPConn(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := TCPWrite(query, conn); err != nil {
+		return nil, err
+	}
+
+	data, err := TCPRead(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if isResponse(query, data) == false {
+		return nil, ErrResponseMismatch
+	}
+	return data, nil
+}
+
+// response has the same id as query and qr bit set
+func isResponse(query, data []byte) bool {
+	return len(query) >= headerLen && len(data) >= headerLen &&
+		data[0] == query[0] && data[1] == query[1] && data[2]&0x80 != 0
+}
@@ -0,0 +1,39 @@
+package util
+
+import (
+	"net"
+	"time"
+)
+
+const (
+	udpTimeout    = 3 * time.Second
+	maxUDPMsgSize = 65535
+)
+
+func NewUDPConn(address string) (*net.UDPConn, error) {
+	conn, err := net.DialTimeout("udp", address, udpTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(*net.UDPConn), nil
+}
+
+func UDPWrite(data []byte, conn *net.UDPConn) error {
+	conn.SetWriteDeadline(time.Now().Add(udpTimeout))
+	_, err := conn.Write(data)
+	return err
+}
+
+// timeout is the time left to wait, read fails with timeout error at
+// once if it's not positive
+func UDPRead(conn *net.UDPConn, timeout time.Duration) ([]byte, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, maxUDPMsgSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
@@ -0,0 +1,60 @@
+package view
+
+import (
+	"errors"
+)
+
+var ErrDuplicateView = errors.New("duplicate view name")
+
+// Selector holds views in order, the first matched view is selected
+type Selector[Z any] struct {
+	views []*View[Z]
+}
+
+func NewSelector[Z any]() *Selector[Z] {
+	return &Selector[Z]{}
+}
+
+// Add appends view to the end, so it has the lowest precedence
+func (s *Selector[Z]) Add(view *View[Z]) error {
+	if s.Get(view.Name) != nil {
+		return ErrDuplicateView
+	}
+
+	s.views = append(s.views, view)
+	return nil
+}
+
+func (s *Selector[Z]) Get(name string) *View[Z] {
+	for _, view := range s.views {
+		if view.Name == name {
+			return view
+		}
+	}
+	return nil
+}
+
+func (s *Selector[Z]) Views() []*View[Z] {
+	return s.views
+}
+
+// Select returns the first view which matches the query, nil if no view
+// matches
+func (s *Selector[Z]) Select(q *Query) *View[Z] {
+	for _, view := range s.views {
+		if view.Match(q) {
+			return view
+		}
+	}
+	return nil
+}
+
+// Zones returns the zone set of the selected view
+func (s *Selector[Z]) Zones(q *Query) (Z, bool) {
+	if view := s.Select(q); view != nil {
+		return view.Zones, true
+	}
+
+	var zero Z
+	return zero, false
+}
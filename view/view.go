@@ -0,0 +1,161 @@
+package view
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/zdnscloud/g53"
+)
+
+// View is matched if all of its non-empty conditions are met, for each
+// condition, matching any of its values is enough. View without any
+// condition matches all the queries
+type View[Z any] struct {
+	Name  string
+	Zones Z
+
+	// source address of the query
+	Sources []*net.IPNet
+	// address in edns client subnet option, source prefix of the option
+	// should be no shorter than the prefix
+	ECSPrefixes []*net.IPNet
+	// name of the tsig key which signs the query, the signature isn't
+	// verified here, it should be done before selection
+	TSIGKeys []*g53.Name
+	// value of the edns view option
+	ViewOpts []string
+	// address which the query is sent to
+	Destinations []*net.IPNet
+}
+
+// Query is what conditions of views are matched against
+type Query struct {
+	Message     *g53.Message
+	Source      net.IP
+	Destination net.IP
+}
+
+func (v *View[Z]) Match(q *Query) bool {
+	if len(v.Sources) > 0 && containsIP(v.Sources, q.Source) == false {
+		return false
+	}
+
+	if len(v.Destinations) > 0 && containsIP(v.Destinations, q.Destination) == false {
+		return false
+	}
+
+	if len(v.ECSPrefixes) > 0 && v.matchECS(q.Message) == false {
+		return false
+	}
+
+	if len(v.TSIGKeys) > 0 && v.matchTSIG(q.Message) == false {
+		return false
+	}
+
+	if len(v.ViewOpts) > 0 && v.matchViewOpt(q.Message) == false {
+		return false
+	}
+
+	return true
+}
+
+func (v *View[Z]) matchECS(msg *g53.Message) bool {
+	if msg == nil || msg.Edns == nil {
+		return false
+	}
+
+	subnet := msg.Edns.GetSubnetOpt()
+	if subnet == nil {
+		return false
+	}
+
+	for _, prefix := range v.ECSPrefixes {
+		ones, bits := prefix.Mask.Size()
+		sourcePrefix := int(subnet.SourcePrefix())
+		if (subnet.Family() == 1) != (bits == net.IPv4len*8) {
+			continue
+		}
+		if sourcePrefix >= ones && prefix.Contains(subnet.IP()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *View[Z]) matchTSIG(msg *g53.Message) bool {
+	if msg == nil || msg.Tsig == nil || msg.Tsig.Header == nil {
+		return false
+	}
+
+	for _, key := range v.TSIGKeys {
+		if key.Equals(msg.Tsig.Header.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *View[Z]) matchViewOpt(msg *g53.Message) bool {
+	if msg == nil || msg.Edns == nil {
+		return false
+	}
+
+	opt := msg.Edns.GetViewOpt()
+	if opt == nil {
+		return false
+	}
+
+	for _, name := range v.ViewOpts {
+		if name == opt.View {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(prefixes []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePrefixes parses addresses in cidr format, address without prefix
+// length is treated as a host prefix
+func ParsePrefixes(ss ...string) ([]*net.IPNet, error) {
+	var prefixes []*net.IPNet
+	for _, s := range ss {
+		prefix, err := parsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+func parsePrefix(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, prefix, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address:%s", s)
+		}
+		return prefix, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address:%s", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}, nil
+}
@@ -0,0 +1,137 @@
+package view
+
+import (
+	"net"
+	"testing"
+
+	ut "github.com/zdnscloud/cement/unittest"
+	"github.com/zdnscloud/g53"
+	"github.com/zdnscloud/g53/util"
+)
+
+func mustParsePrefixes(ss ...string) []*net.IPNet {
+	prefixes, err := ParsePrefixes(ss...)
+	if err != nil {
+		panic("invalid prefix:" + err.Error())
+	}
+	return prefixes
+}
+
+func newQuery(src, dst string) *Query {
+	return &Query{
+		Message:     g53.MakeQuery(g53.NameFromStringUnsafe("www.example.com"), g53.RR_A, 1232, false),
+		Source:      net.ParseIP(src),
+		Destination: net.ParseIP(dst),
+	}
+}
+
+func newTestSelector() *Selector[[]string] {
+	s := NewSelector[[]string]()
+	s.Add(&View[[]string]{
+		Name:     "transfer",
+		Zones:    []string{"example.com"},
+		TSIGKeys: []*g53.Name{g53.NameFromStringUnsafe("xfr-key")},
+	})
+	s.Add(&View[[]string]{
+		Name:     "tagged",
+		Zones:    []string{"tagged.example.com"},
+		ViewOpts: []string{"internal", "lab"},
+	})
+	s.Add(&View[[]string]{
+		Name:        "ecs",
+		Zones:       []string{"cdn.example.com"},
+		ECSPrefixes: mustParsePrefixes("198.51.100.0/24", "2001:db8::/32"),
+	})
+	s.Add(&View[[]string]{
+		Name:         "internal",
+		Zones:        []string{"corp.example.com", "example.com"},
+		Sources:      mustParsePrefixes("10.0.0.0/8", "192.168.1.1"),
+		Destinations: mustParsePrefixes("10.0.0.53"),
+	})
+	s.Add(&View[[]string]{
+		Name:  "default",
+		Zones: []string{"example.com"},
+	})
+	return s
+}
+
+func TestSelectView(t *testing.T) {
+	s := newTestSelector()
+	ut.Equal(t, len(s.Views()), 5)
+	ut.Equal(t, s.Add(&View[[]string]{Name: "default"}), ErrDuplicateView)
+
+	q := newQuery("10.1.1.1", "10.0.0.53")
+	ut.Equal(t, s.Select(q).Name, "internal")
+	zones, ok := s.Zones(q)
+	ut.Equal(t, ok, true)
+	ut.Equal(t, zones, []string{"corp.example.com", "example.com"})
+
+	//all the conditions should be met
+	ut.Equal(t, s.Select(newQuery("10.1.1.1", "203.0.113.53")).Name, "default")
+	ut.Equal(t, s.Select(newQuery("192.168.1.1", "10.0.0.53")).Name, "internal")
+	ut.Equal(t, s.Select(newQuery("192.168.1.2", "10.0.0.53")).Name, "default")
+	ut.Equal(t, s.Select(&Query{}).Name, "default")
+
+	q = newQuery("10.1.1.1", "10.0.0.53")
+	q.Message.Edns.AddSubnetView("lab")
+	ut.Equal(t, s.Select(q).Name, "tagged")
+
+	q = newQuery("10.1.1.1", "10.0.0.53")
+	q.Message.Edns.AddSubnetView("guest")
+	ut.Equal(t, s.Select(q).Name, "internal")
+
+	tsig, _ := g53.NewTSIG("xfr-key.", "z08GzEnlCDGy/W3Zw/2NHg==", "hmac-md5")
+	q.Message.SetTSIG(tsig)
+	ut.Equal(t, s.Select(q).Name, "transfer")
+}
+
+func TestSelectByECS(t *testing.T) {
+	s := newTestSelector()
+	for _, c := range []struct {
+		ip           string
+		sourcePrefix uint8
+		view         string
+	}{
+		{"198.51.100.0", 24, "ecs"},
+		{"198.51.100.128", 25, "ecs"},
+		{"198.51.0.0", 16, "default"},
+		{"203.0.113.0", 24, "default"},
+		{"2001:db8:1::", 48, "ecs"},
+		{"2001:db9::", 48, "default"},
+	} {
+		q := newQuery("203.0.113.1", "203.0.113.53")
+		q.Message.Edns.Options = append(q.Message.Edns.Options, g53.NewSubnetOpt(net.ParseIP(c.ip), c.sourcePrefix))
+
+		//option should survive wire format
+		render := g53.NewMsgRender()
+		q.Message.Rend(render)
+		msg, err := g53.MessageFromWire(util.NewInputBuffer(render.Data()))
+		ut.Equal(t, err, nil)
+		q.Message = msg
+		ut.Equal(t, s.Select(q).Name, c.view)
+	}
+}
+
+func TestNoViewMatch(t *testing.T) {
+	s := NewSelector[int]()
+	s.Add(&View[int]{Name: "internal", Zones: 1, Sources: mustParsePrefixes("10.0.0.0/8")})
+	q := newQuery("192.0.2.1", "192.0.2.53")
+	ut.Assert(t, s.Select(q) == nil, "no view should match")
+	_, ok := s.Zones(q)
+	ut.Equal(t, ok, false)
+	ut.Equal(t, s.Get("internal").Zones, 1)
+	ut.Assert(t, s.Get("external") == nil, "view doesn't exist")
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes("10.0.0.0/8", "192.0.2.1", "2001:db8::1")
+	ut.Equal(t, err, nil)
+	ut.Equal(t, prefixes[0].String(), "10.0.0.0/8")
+	ut.Equal(t, prefixes[1].String(), "192.0.2.1/32")
+	ut.Equal(t, prefixes[2].String(), "2001:db8::1/128")
+
+	_, err = ParsePrefixes("10.0.0.0/33")
+	ut.Assert(t, err != nil, "invalid prefix length")
+	_, err = ParsePrefixes("example.com")
+	ut.Assert(t, err != nil, "invalid address")
+}
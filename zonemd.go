@@ -0,0 +1,228 @@
+package g53
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"sort"
+
+	"github.com/zdnscloud/g53/util"
+)
+
+var (
+	ErrNoApexSOA                = errors.New("zone has no soa at apex")
+	ErrNoZONEMD                 = errors.New("zone has no zonemd at apex")
+	ErrUnsupportedZONEMD        = errors.New("no zonemd with supported scheme and hash algorithm")
+	ErrDuplicateZONEMD          = errors.New("more than one zonemd with same scheme and hash algorithm")
+	ErrZONEMDSerialMismatch     = errors.New("no zonemd serial matches soa serial")
+	ErrZONEMDDigestMismatch     = errors.New("zonemd digest doesn't match zone content")
+	ErrUnsupportedZONEMDScheme  = errors.New("unsupported zonemd scheme")
+	ErrUnsupportedZONEMDHashAlg = errors.New("unsupported zonemd hash algorithm")
+)
+
+// rr in canonical form, used to sort and hash rrs of a zone
+type canonicalRR struct {
+	name  *Name
+	typ   RRType
+	wire  []byte
+	rdata []byte
+}
+
+func zonemdHash(hashAlg uint8) (hash.Hash, error) {
+	switch hashAlg {
+	case ZONEMD_HASH_SHA384:
+		return sha512.New384(), nil
+	case ZONEMD_HASH_SHA512:
+		return sha512.New(), nil
+	default:
+		return nil, ErrUnsupportedZONEMDHashAlg
+	}
+}
+
+// names in rdata are expected to be downcased, which is the default
+// behavior of NameFromString and NameFromWire, owner name is downcased
+// here, since label length is always less than 'A', the whole wire
+// format of a name could be mapped to lower case
+func toCanonicalRR(rrset *RRset, rdata Rdata) canonicalRR {
+	buf := util.NewOutputBuffer(512)
+	rdata.ToWire(buf)
+	rdataWire := append([]byte(nil), buf.Data()...)
+
+	buf.Clear()
+	rrset.Name.ToWire(buf)
+	nameLen := int(buf.Len())
+	rrset.Type.ToWire(buf)
+	rrset.Class.ToWire(buf)
+	rrset.Ttl.ToWire(buf)
+	buf.WriteUint16(uint16(len(rdataWire)))
+	buf.WriteData(rdataWire)
+	wire := append([]byte(nil), buf.Data()...)
+	for i := 0; i < nameLen; i++ {
+		wire[i] = maptolower[wire[i]]
+	}
+
+	return canonicalRR{
+		name:  rrset.Name,
+		typ:   rrset.Type,
+		wire:  wire,
+		rdata: rdataWire,
+	}
+}
+
+func isApexZONEMD(origin *Name, rrset *RRset, rdata Rdata) bool {
+	if rrset.Name.Equals(origin) == false {
+		return false
+	}
+
+	if rrset.Type == RR_ZONEMD {
+		return true
+	}
+
+	if rrset.Type == RR_RRSIG {
+		if sig, ok := rdata.(*RRSig); ok && sig.Covered == RR_ZONEMD {
+			return true
+		}
+	}
+	return false
+}
+
+// rrs are sorted in dnssec canonical order and duplicate rrs are removed
+func zoneCanonicalRRs(origin *Name, rrsets []*RRset) []canonicalRR {
+	var rrs []canonicalRR
+	for _, rrset := range rrsets {
+		if relation := rrset.Name.Compare(origin, false).Relation; relation != SUBDOMAIN && relation != EQUAL {
+			continue
+		}
+
+		for _, rdata := range rrset.Rdatas {
+			if rdata == nil || isApexZONEMD(origin, rrset, rdata) {
+				continue
+			}
+			rrs = append(rrs, toCanonicalRR(rrset, rdata))
+		}
+	}
+
+	sort.Slice(rrs, func(i, j int) bool {
+		if order := rrs[i].name.Compare(rrs[j].name, false).Order; order != 0 {
+			return order < 0
+		}
+		if rrs[i].typ != rrs[j].typ {
+			return rrs[i].typ < rrs[j].typ
+		}
+		return bytes.Compare(rrs[i].rdata, rrs[j].rdata) < 0
+	})
+
+	uniq := rrs[:0]
+	for i, rr := range rrs {
+		if i > 0 {
+			last := uniq[len(uniq)-1]
+			if last.typ == rr.typ && last.name.Equals(rr.name) && bytes.Equal(last.rdata, rr.rdata) {
+				continue
+			}
+		}
+		uniq = append(uniq, rr)
+	}
+	return uniq
+}
+
+func apexSOA(origin *Name, rrsets []*RRset) (*SOA, error) {
+	for _, rrset := range rrsets {
+		if rrset.Type == RR_SOA && rrset.Name.Equals(origin) && len(rrset.Rdatas) > 0 {
+			if soa, ok := rrset.Rdatas[0].(*SOA); ok {
+				return soa, nil
+			}
+		}
+	}
+	return nil, ErrNoApexSOA
+}
+
+// ComputeZONEMD calculates the digest of the zone rooted at origin
+// according to RFC 8976, apex zonemd and its signatures are excluded,
+// the serial of the returned zonemd is copied from the apex soa
+func ComputeZONEMD(origin *Name, rrsets []*RRset, scheme, hashAlg uint8) (*ZONEMD, error) {
+	if scheme != ZONEMD_SCHEME_SIMPLE {
+		return nil, ErrUnsupportedZONEMDScheme
+	}
+
+	h, err := zonemdHash(hashAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	soa, err := apexSOA(origin, rrsets)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rr := range zoneCanonicalRRs(origin, rrsets) {
+		h.Write(rr.wire)
+	}
+
+	return &ZONEMD{
+		Serial:        soa.Serial,
+		Scheme:        scheme,
+		HashAlgorithm: hashAlg,
+		Digest:        h.Sum(nil),
+	}, nil
+}
+
+// VerifyZONEMD checks the zone content against the apex zonemd, it
+// succeeds if any zonemd with supported scheme and hash algorithm
+// matches, zonemd whose serial differs from soa is ignored
+func VerifyZONEMD(origin *Name, rrsets []*RRset) error {
+	soa, err := apexSOA(origin, rrsets)
+	if err != nil {
+		return err
+	}
+
+	var zonemds []*ZONEMD
+	for _, rrset := range rrsets {
+		if rrset.Type == RR_ZONEMD && rrset.Name.Equals(origin) {
+			for _, rdata := range rrset.Rdatas {
+				if zonemd, ok := rdata.(*ZONEMD); ok {
+					zonemds = append(zonemds, zonemd)
+				}
+			}
+		}
+	}
+	if len(zonemds) == 0 {
+		return ErrNoZONEMD
+	}
+
+	seen := make(map[[2]uint8]struct{})
+	var candidates []*ZONEMD
+	for _, zonemd := range zonemds {
+		if zonemd.Serial != soa.Serial {
+			continue
+		}
+
+		key := [2]uint8{zonemd.Scheme, zonemd.HashAlgorithm}
+		if _, ok := seen[key]; ok {
+			return ErrDuplicateZONEMD
+		}
+		seen[key] = struct{}{}
+
+		if zonemd.Scheme == ZONEMD_SCHEME_SIMPLE &&
+			(zonemd.HashAlgorithm == ZONEMD_HASH_SHA384 || zonemd.HashAlgorithm == ZONEMD_HASH_SHA512) {
+			candidates = append(candidates, zonemd)
+		}
+	}
+
+	if len(seen) == 0 {
+		return ErrZONEMDSerialMismatch
+	} else if len(candidates) == 0 {
+		return ErrUnsupportedZONEMD
+	}
+
+	for _, zonemd := range candidates {
+		computed, err := ComputeZONEMD(origin, rrsets, zonemd.Scheme, zonemd.HashAlgorithm)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(computed.Digest, zonemd.Digest) {
+			return nil
+		}
+	}
+	return ErrZONEMDDigestMismatch
+}
@@ -0,0 +1,85 @@
+package g53
+
+import (
+	"testing"
+)
+
+func zoneFromStrings(t *testing.T, rrs []string) []*RRset {
+	var rrsets []*RRset
+	for _, s := range rrs {
+		rrset, err := RRsetFromString(s)
+		Assert(t, err == nil, "parse %s failed:%v", s, err)
+		rrsets = append(rrsets, rrset)
+	}
+	return rrsets
+}
+
+// simple example zone from RFC 8976 appendix A.1
+var zonemdExampleZone = []string{
+	"example. 86400 IN SOA ns1.example. admin.example. 2018031900 1800 900 604800 86400",
+	"example. 86400 IN NS ns1.example.",
+	"example. 86400 IN NS ns2.example.",
+	"example. 86400 IN ZONEMD 2018031900 1 1 c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c",
+	"ns1.example. 3600 IN A 203.0.113.63",
+	"ns2.example. 3600 IN AAAA 2001:db8::63",
+}
+
+func TestComputeZONEMD(t *testing.T) {
+	origin := NameFromStringUnsafe("example.")
+	zone := zoneFromStrings(t, zonemdExampleZone)
+	zonemd, err := ComputeZONEMD(origin, zone, ZONEMD_SCHEME_SIMPLE, ZONEMD_HASH_SHA384)
+	Assert(t, err == nil, "compute zonemd failed:%v", err)
+	Equal(t, zonemd.String(), "2018031900 1 1 C68090D90A7AED716BC459F9340E3D7C1370D4D24B7E2FC3A1DDC0B9A87153B9A9713B3C9AE5CC27777F98B8E730044C")
+
+	// order, duplicate rr and out of zone data doesn't matter
+	shuffled := zoneFromStrings(t, []string{
+		"ns2.example. 3600 IN AAAA 2001:db8::63",
+		"example. 86400 IN NS ns2.example.",
+		"ns1.example. 3600 IN A 203.0.113.63",
+		"example. 86400 IN SOA ns1.example. admin.example. 2018031900 1800 900 604800 86400",
+		"example. 86400 IN NS ns1.example.",
+		"example. 86400 IN NS ns2.example.",
+		"other. 3600 IN A 192.0.2.1",
+		"aexample. 3600 IN A 192.0.2.1",
+	})
+	zonemd2, err := ComputeZONEMD(origin, shuffled, ZONEMD_SCHEME_SIMPLE, ZONEMD_HASH_SHA384)
+	Assert(t, err == nil, "compute zonemd failed:%v", err)
+	Equal(t, zonemd2.Compare(zonemd), 0)
+
+	_, err = ComputeZONEMD(origin, zone, 2, ZONEMD_HASH_SHA384)
+	Equal(t, err, ErrUnsupportedZONEMDScheme)
+	_, err = ComputeZONEMD(origin, zone, ZONEMD_SCHEME_SIMPLE, 3)
+	Equal(t, err, ErrUnsupportedZONEMDHashAlg)
+	_, err = ComputeZONEMD(origin, zone[1:], ZONEMD_SCHEME_SIMPLE, ZONEMD_HASH_SHA384)
+	Equal(t, err, ErrNoApexSOA)
+}
+
+func TestVerifyZONEMD(t *testing.T) {
+	origin := NameFromStringUnsafe("example.")
+	zone := zoneFromStrings(t, zonemdExampleZone)
+	Assert(t, VerifyZONEMD(origin, zone) == nil, "example zone should be verified")
+
+	sha512, err := ComputeZONEMD(origin, zone, ZONEMD_SCHEME_SIMPLE, ZONEMD_HASH_SHA512)
+	Assert(t, err == nil, "compute zonemd failed:%v", err)
+	zone[3].AddRdata(sha512)
+	zone[3].AddRdata(&ZONEMD{2018031900, 240, 1, make([]byte, 48)})
+	Assert(t, VerifyZONEMD(origin, zone) == nil, "multiple digests should be verified")
+
+	tampered := zoneFromStrings(t, zonemdExampleZone)
+	tampered[4].Ttl = 3601
+	Equal(t, VerifyZONEMD(origin, tampered), ErrZONEMDDigestMismatch)
+
+	tampered = zoneFromStrings(t, zonemdExampleZone)
+	tampered[0].Rdatas[0].(*SOA).Serial = 2018031901
+	Equal(t, VerifyZONEMD(origin, tampered), ErrZONEMDSerialMismatch)
+
+	Equal(t, VerifyZONEMD(origin, zoneFromStrings(t, zonemdExampleZone[:3])), ErrNoZONEMD)
+
+	unsupported := zoneFromStrings(t, zonemdExampleZone)
+	unsupported[3].Rdatas[0].(*ZONEMD).Scheme = 240
+	Equal(t, VerifyZONEMD(origin, unsupported), ErrUnsupportedZONEMD)
+
+	duplicate := zoneFromStrings(t, zonemdExampleZone)
+	duplicate[3].Rdatas = append(duplicate[3].Rdatas, &ZONEMD{2018031900, 1, 1, make([]byte, 48)})
+	Equal(t, VerifyZONEMD(origin, duplicate), ErrDuplicateZONEMD)
+}